@@ -3,18 +3,25 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/Artexxx/HR-Kafka-QA/internal/api"
+	"github.com/Artexxx/HR-Kafka-QA/internal/bugs"
 	"github.com/Artexxx/HR-Kafka-QA/internal/config"
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
 	"github.com/Artexxx/HR-Kafka-QA/internal/exchange/consumer"
+	"github.com/Artexxx/HR-Kafka-QA/internal/exchange/kafkaadmin"
+	"github.com/Artexxx/HR-Kafka-QA/internal/exchange/memkafka"
 	"github.com/Artexxx/HR-Kafka-QA/internal/exchange/producer"
 	"github.com/Artexxx/HR-Kafka-QA/internal/repository/events"
 	"github.com/Artexxx/HR-Kafka-QA/internal/repository/history"
 	"github.com/Artexxx/HR-Kafka-QA/internal/repository/profile"
+	"github.com/Artexxx/HR-Kafka-QA/internal/repository/session"
+	"github.com/Artexxx/HR-Kafka-QA/internal/scenario"
 	"github.com/Artexxx/HR-Kafka-QA/library/pg"
 	"github.com/Artexxx/HR-Kafka-QA/library/yamlreader"
 	"github.com/IBM/sarama"
@@ -35,7 +42,11 @@ func main() {
 		log.Fatal().Msg("ADMIN_RESET_PASSWORD is required")
 	}
 	log.Info().Msgf("pg=%+v", cfg.Postgres.Conn.Value)
-	log.Info().Msgf("kafka=%+v", cfg.Kafka.Bootstrap.Value)
+	if cfg.Kafka.InMemory.Value {
+		log.Warn().Msg("kafka=in-memory: сообщения теряются при остановке процесса")
+	} else {
+		log.Info().Msgf("kafka=%+v", cfg.Kafka.Bootstrap.Value)
+	}
 	zerolog.SetGlobalLevel(zerolog.InfoLevel)
 	zerolog.TimeFieldFormat = time.RFC3339
 	pgClient, err := pg.NewPG(rootCtx, cfg.Postgres.Conn.Value, log.Logger)
@@ -46,18 +57,62 @@ func main() {
 	eventsRepo := events.NewRepository(pgClient.Pool())
 	profileRepo := profile.NewRepository(pgClient.Pool())
 	historyRepo := history.NewRepository(pgClient.Pool())
-	hrProducer, err := initHRProducer(cfg.Kafka)
+	sessionRepo := session.NewRepository(pgClient.Pool())
+	syncProducer, kafkaAdmin, consumerOpts, err := initKafka(cfg.Kafka)
 	if err != nil {
-		log.Fatal().Err(err).Msg("kafka producer init failed")
+		log.Fatal().Err(err).Msg("kafka init failed")
+	}
+	switch cfg.Kafka.KeyMode.Value {
+	case producer.KeyModeMessageID, producer.KeyModeEmployeeID:
+	default:
+		log.Fatal().Str("key_mode", cfg.Kafka.KeyMode.Value).Msg("kafka key_mode must be message_id or employee_id")
 	}
+	hrProducer := initHRProducer(cfg.Kafka, syncProducer)
 	defer func() { _ = hrProducer.Close() }()
-	apiService := api.NewService(api.ServiceDeps{
-		Config:      cfg.UserAPI,
-		Producer:    hrProducer,
-		EventsRepo:  eventsRepo,
-		ProfileRepo: profileRepo,
-		HistoryRepo: historyRepo,
+	defer func() { _ = kafkaAdmin.Close() }()
+	topics := standTopics(cfg.Kafka)
+	ensured, err := kafkaAdmin.EnsureTopics(rootCtx, topics)
+	if err != nil {
+		log.Fatal().Err(err).Msg("kafka topics init failed")
+	}
+	for _, t := range ensured {
+		log.Info().Str("topic", t.Topic).Int32("partitions", t.Partitions).Bool("existed", t.Existed).Msg("kafka topic ready")
+	}
+	positionPolicy, err := consumer.NewPositionPolicy(cfg.Kafka.PositionPolicy.Value)
+	if err != nil {
+		log.Fatal().Err(err).Msg("kafka position_policy invalid")
+	}
+	bugCatalog, err := bugs.NewCatalog(bugs.ParseList(cfg.Bugs.Enabled.Value)...)
+	if err != nil {
+		log.Fatal().Err(err).Msg("bugs config invalid")
+	}
+	for _, b := range bugCatalog.List() {
+		if bugCatalog.Enabled(b.ID) {
+			log.Warn().Str("bug", b.ID).Msg("seeded bug enabled")
+		}
+	}
+	consumerChaos, err := consumer.NewChaos(dto.ConsumerChaos{
+		Latency:        cfg.Kafka.Chaos.Latency.Value,
+		CrashPercent:   cfg.Kafka.Chaos.CrashPercent.Value,
+		DBErrorPercent: cfg.Kafka.Chaos.DBErrorPercent.Value,
 	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("kafka chaos config invalid")
+	}
+	if c := consumerChaos.Get(); c != (dto.ConsumerChaos{}) {
+		log.Warn().Interface("chaos", c).Msg("consumer chaos enabled")
+	}
+	consumerOpts = append(consumerOpts, consumer.WithBugs(bugCatalog), consumer.WithChaos(consumerChaos))
+	if cfg.Kafka.DLQ.Enabled.Value {
+		consumerOpts = append(consumerOpts, consumer.WithDLQTopic(syncProducer, cfg.Kafka.DLQ.Suffix.Value))
+	}
+	if cfg.Kafka.Retry.Enabled.Value {
+		delays, err := consumer.ParseRetryDelays(cfg.Kafka.Retry.Delays.Value)
+		if err != nil {
+			log.Fatal().Err(err).Msg("kafka retry config invalid")
+		}
+		consumerOpts = append(consumerOpts, consumer.WithRetry(syncProducer, consumer.RetryPolicy{Delays: delays}))
+	}
 	consumerPersonal := consumer.NewPersonalRunner(
 		cfg.Kafka.Bootstrap.Value,
 		cfg.Kafka.Topics.Personal.Value,
@@ -65,6 +120,7 @@ func main() {
 		eventsRepo,
 		profileRepo,
 		log.Logger,
+		consumerOpts...,
 	)
 	consumerPositions := consumer.NewPositionsRunner(
 		cfg.Kafka.Bootstrap.Value,
@@ -73,6 +129,7 @@ func main() {
 		eventsRepo,
 		profileRepo,
 		log.Logger,
+		append(consumerOpts, consumer.WithPositionPolicy(positionPolicy))...,
 	)
 	consumerHistory := consumer.NewHistoryRunner(
 		cfg.Kafka.Bootstrap.Value,
@@ -82,7 +139,49 @@ func main() {
 		profileRepo,
 		historyRepo,
 		log.Logger,
+		consumerOpts...,
 	)
+	consumers := consumer.NewRegistry(consumerPersonal, consumerPositions, consumerHistory)
+	journalTopics := consumer.ProjectorTopics{
+		Personal:  cfg.Kafka.Topics.Personal.Value,
+		Positions: cfg.Kafka.Topics.Positions.Value,
+		History:   cfg.Kafka.Topics.History.Value,
+	}
+	projector := consumer.NewProjector(
+		eventsRepo,
+		profileRepo,
+		historyRepo,
+		journalTopics,
+		log.Logger,
+		consumer.WithPositionPolicy(positionPolicy),
+	)
+	checker := consumer.NewChecker(kafkaAdmin, eventsRepo, profileRepo, historyRepo, journalTopics, consumers)
+	scenarios := scenario.NewRunner(
+		hrProducer,
+		consumers,
+		sessionRepo,
+		eventsRepo,
+		profileRepo,
+		scenario.Topics(journalTopics),
+		log.Logger,
+	)
+	apiService := api.NewService(api.ServiceDeps{
+		Config:      cfg.UserAPI,
+		Producer:    hrProducer,
+		EventsRepo:  eventsRepo,
+		ProfileRepo: profileRepo,
+		HistoryRepo: historyRepo,
+		SessionRepo: sessionRepo,
+		Consumers:   consumers,
+		KafkaAdmin:  kafkaAdmin,
+		Topics:      topics,
+		Policy:      positionPolicy,
+		Chaos:       consumerChaos,
+		Projector:   projector,
+		Checker:     checker,
+		Bugs:        bugCatalog,
+		Scenarios:   scenarios,
+	})
 	group, gctx := errgroup.WithContext(ctx)
 	group.Go(func() error {
 		log.Info().Msg("запуск HTTP API")
@@ -138,30 +237,78 @@ func main() {
 		log.Info().Msg("all services stopped")
 	}
 }
-func initHRProducer(kafkaConfig config.KafkaConfig) (*producer.HRProducer, error) {
+
+// standKafka — служебный доступ к Kafka, нужный стенду: kafkaadmin.Admin или memkafka.Broker
+type standKafka interface {
+	api.KafkaAdmin
+	consumer.TopicReader
+	EnsureTopics(ctx context.Context, specs []dto.TopicSpec) ([]dto.TopicReset, error)
+	Close() error
+}
+
+// initKafka подключает стенд к Kafka по bootstrap или, при kafka.in_memory, к брокеру в памяти
+func initKafka(kafkaConfig config.KafkaConfig) (sarama.SyncProducer, standKafka, []consumer.Option, error) {
+	if kafkaConfig.InMemory.Value {
+		broker := memkafka.NewBroker(producer.NewPartitioner)
+		return broker.SyncProducer(), broker, []consumer.Option{consumer.WithGroupFactory(broker.NewConsumerGroup)}, nil
+	}
+	syncProducer, err := initSyncProducer(kafkaConfig)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("producer: %w", err)
+	}
+	kafkaAdmin, err := kafkaadmin.NewAdmin(kafkaConfig.Bootstrap.Value)
+	if err != nil {
+		_ = syncProducer.Close()
+		return nil, nil, nil, fmt.Errorf("admin: %w", err)
+	}
+	return syncProducer, kafkaAdmin, nil, nil
+}
+func initSyncProducer(kafkaConfig config.KafkaConfig) (sarama.SyncProducer, error) {
 	saramaCfg := sarama.NewConfig()
 	saramaCfg.Version = sarama.V3_3_2_0
 	saramaCfg.Producer.Return.Successes = true
 	saramaCfg.Producer.RequiredAcks = sarama.WaitForAll
+	saramaCfg.Producer.Partitioner = producer.NewPartitioner
 	saramaCfg.Producer.Idempotent = true
 	saramaCfg.Net.MaxOpenRequests = 1
 	saramaCfg.Producer.Retry.Max = 5
 	saramaCfg.Producer.Retry.Backoff = 200 * time.Millisecond
-	syncProducer, err := sarama.NewSyncProducer([]string{kafkaConfig.Bootstrap.Value}, saramaCfg)
-	if err != nil {
-		return nil, err
+	return sarama.NewSyncProducer([]string{kafkaConfig.Bootstrap.Value}, saramaCfg)
+}
+
+// standTopics — топики стенда с числом партиций: основные и, если включены, .dlq и retry-ярусы
+func standTopics(kafkaConfig config.KafkaConfig) []dto.TopicSpec {
+	var topics []dto.TopicSpec
+	for _, base := range []dto.TopicSpec{
+		{Name: kafkaConfig.Topics.Personal.Value, Partitions: int32(kafkaConfig.Partitions.Personal.Value)},
+		{Name: kafkaConfig.Topics.Positions.Value, Partitions: int32(kafkaConfig.Partitions.Positions.Value)},
+		{Name: kafkaConfig.Topics.History.Value, Partitions: int32(kafkaConfig.Partitions.History.Value)},
+	} {
+		topics = append(topics, base)
+		if kafkaConfig.DLQ.Enabled.Value {
+			topics = append(topics, dto.TopicSpec{Name: base.Name + kafkaConfig.DLQ.Suffix.Value, Partitions: base.Partitions})
+		}
+		if kafkaConfig.Retry.Enabled.Value {
+			delays, _ := consumer.ParseRetryDelays(kafkaConfig.Retry.Delays.Value)
+			for _, topic := range (consumer.RetryPolicy{Delays: delays}).Topics(base.Name) {
+				topics = append(topics, dto.TopicSpec{Name: topic, Partitions: base.Partitions})
+			}
+		}
 	}
-	hrProducer := producer.NewHRProducer(
+	return topics
+}
+func initHRProducer(kafkaConfig config.KafkaConfig, syncProducer sarama.SyncProducer) *producer.HRProducer {
+	return producer.NewHRProducer(
 		syncProducer,
 		producer.Config{
 			TopicPersonal:  kafkaConfig.Topics.Personal.Value,
 			TopicPositions: kafkaConfig.Topics.Positions.Value,
 			TopicHistory:   kafkaConfig.Topics.History.Value,
 			Source:         "qa-kafka-api",
+			KeyMode:        kafkaConfig.KeyMode.Value,
 		},
 		log.Logger,
 	)
-	return hrProducer, nil
 }
 func waitWithTimeout(done <-chan struct{}, timeout time.Duration) {
 	timer := time.NewTimer(timeout)
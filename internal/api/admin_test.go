@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/bugs"
+	"github.com/Artexxx/HR-Kafka-QA/internal/config"
+	"github.com/Artexxx/HR-Kafka-QA/library/yamlenv"
+	"github.com/valyala/fasthttp"
+)
+
+const testAdminPassword = "secret"
+
+// TestAdminMutationsRequirePassword — операции, меняющие стенд для всех стажёров,
+// без пароля администратора отклоняются до обращения к зависимостям (в сервисе их нет).
+func TestAdminMutationsRequirePassword(t *testing.T) {
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{fasthttp.MethodPost, "/admin/reset"},
+		{fasthttp.MethodPost, "/admin/rebuild"},
+		{fasthttp.MethodPut, "/admin/bugs"},
+		{fasthttp.MethodPut, "/admin/position-policy"},
+		{fasthttp.MethodPut, "/admin/consumer-chaos"},
+		{fasthttp.MethodPut, "/admin/producer-chaos"},
+		{fasthttp.MethodPost, "/admin/consumers/personal/offsets"},
+		{fasthttp.MethodPost, "/admin/consumers/personal/stop"},
+	}
+
+	bodies := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "no password", body: `{}`, want: fasthttp.StatusBadRequest},
+		{name: "wrong password", body: `{"password": "guess"}`, want: fasthttp.StatusUnauthorized},
+	}
+
+	s := newTestService()
+
+	for _, route := range routes {
+		for _, b := range bodies {
+			t.Run(route.method+" "+route.path+" "+b.name, func(t *testing.T) {
+				ctx := serve(s, route.method, route.path, b.body)
+
+				if status := ctx.Response.StatusCode(); status != b.want {
+					t.Fatalf("status = %d, want %d: %s", status, b.want, ctx.Response.Body())
+				}
+			})
+		}
+	}
+}
+
+func newTestService() *Service {
+	return NewService(ServiceDeps{
+		Config: config.ApiConfig{
+			Port:               &yamlenv.Env[int]{},
+			AdminResetPassword: &yamlenv.Env[string]{Value: testAdminPassword},
+		},
+	})
+}
+
+// serve выполняет запрос роутером сервиса, минуя middleware; headers — пары имя, значение
+func serve(s *Service, method, path, body string, headers ...string) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.Header.SetMethod(method)
+	for i := 0; i+1 < len(headers); i += 2 {
+		if headers[i+1] != "" {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+	}
+	req.SetRequestURI(path)
+	req.SetBodyString(body)
+
+	// Init привязывает запрос к серверу-заглушке: без него ctx нельзя использовать как context.Context
+	var ctx fasthttp.RequestCtx
+	ctx.Init(&req, nil, nil)
+
+	s.r.Handler(&ctx)
+
+	return &ctx
+}
+
+// TestListBugsHidesEnabled — какие дефекты включены, без пароля администратора не видно
+func TestListBugsHidesEnabled(t *testing.T) {
+	catalog, err := bugs.NewCatalog(bugs.SwapNames)
+	if err != nil {
+		t.Fatalf("NewCatalog: %v", err)
+	}
+
+	s := newTestService()
+	s.bugs = catalog
+
+	tests := []struct {
+		name        string
+		password    string
+		wantStatus  int
+		wantEnabled bool
+	}{
+		{name: "no password", wantStatus: fasthttp.StatusOK},
+		{name: "wrong password", password: "guess", wantStatus: fasthttp.StatusUnauthorized},
+		{name: "admin", password: testAdminPassword, wantStatus: fasthttp.StatusOK, wantEnabled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := serve(s, fasthttp.MethodGet, "/admin/bugs", "", AdminPasswordHeader, tt.password)
+			if status := ctx.Response.StatusCode(); status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", status, tt.wantStatus, ctx.Response.Body())
+			}
+			if tt.wantStatus != fasthttp.StatusOK {
+				return
+			}
+
+			var list []map[string]any
+			if err := json.Unmarshal(ctx.Response.Body(), &list); err != nil {
+				t.Fatalf("json.Unmarshal: %v", err)
+			}
+			if len(list) == 0 {
+				t.Fatal("empty catalog")
+			}
+
+			for _, bug := range list {
+				enabled, ok := bug["enabled"]
+				if ok != tt.wantEnabled {
+					t.Fatalf("bug %v: enabled present = %v, want %v", bug["id"], ok, tt.wantEnabled)
+				}
+				if ok && enabled != (bug["id"] == bugs.SwapNames) {
+					t.Errorf("bug %v: enabled = %v", bug["id"], enabled)
+				}
+			}
+		})
+	}
+}
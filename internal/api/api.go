@@ -26,12 +26,19 @@ import (
 // @produce   json
 
 type EventsRepository interface {
-	ExistsMessage(ctx context.Context, messageID uuid.UUID) (bool, error)
-	InsertEvent(ctx context.Context, event dto.KafkaEvent) error
 	InsertDLQ(ctx context.Context, dlq dto.KafkaDLQ) error
+	InsertReceipt(ctx context.Context, receipt dto.ProduceReceipt) error
 	ListEvents(ctx context.Context) ([]dto.KafkaEvent, error)
-	ListDLQ(ctx context.Context) ([]dto.KafkaDLQ, error)
+	ListEventsForOrdering(ctx context.Context, topic, employeeID string) ([]dto.KafkaEvent, error)
+	ListDLQ(ctx context.Context, filter dto.DLQFilter) ([]dto.KafkaDLQ, error)
+	GetDLQ(ctx context.Context, id int64) (*dto.KafkaDLQ, error)
+	MarkDLQReplay(ctx context.Context, id int64, status, replayErr string) error
+	GetEventByMessageID(ctx context.Context, messageID uuid.UUID) (*dto.KafkaEvent, error)
+	ListDLQByMessageID(ctx context.Context, messageID uuid.UUID) ([]dto.KafkaDLQ, error)
+	ListReceiptsByMessageID(ctx context.Context, messageID uuid.UUID) ([]dto.ProduceReceipt, error)
+	ListDecisionsByMessageID(ctx context.Context, messageID uuid.UUID) ([]dto.ConsumerDecision, error)
 	ResetAll(ctx context.Context) error
+	ResetSession(ctx context.Context) error
 }
 
 type ProfileRepository interface {
@@ -39,6 +46,8 @@ type ProfileRepository interface {
 	Update(ctx context.Context, profile dto.EmployeeProfile) error
 	Delete(ctx context.Context, employeeID string) error
 	GetProfile(ctx context.Context, employeeID string) (*dto.EmployeeProfile, error)
+	GetProfileAsOf(ctx context.Context, employeeID, asOf string) (*dto.EmployeeProfile, error)
+	ListPositions(ctx context.Context, employeeID string) ([]dto.PositionAssignment, error)
 	ListProfiles(ctx context.Context) ([]dto.EmployeeProfile, error)
 	UpsertPersonal(ctx context.Context, profile dto.EmployeeProfile) error
 	UpsertPosition(ctx context.Context, profile dto.EmployeeProfile) error
@@ -49,13 +58,80 @@ type HistoryRepository interface {
 	Update(ctx context.Context, h dto.EmploymentHistory) error
 	Delete(ctx context.Context, id int64) error
 	ListByEmployee(ctx context.Context, employeeID string) ([]dto.EmploymentHistory, error)
+	ListByMessageID(ctx context.Context, messageID uuid.UUID) ([]dto.EmploymentHistory, error)
 	GetByID(ctx context.Context, id int64) (*dto.EmploymentHistory, error)
 }
 
+// SessionRepository — сессии стажёров (заголовок X-Session-ID)
+type SessionRepository interface {
+	Create(ctx context.Context, session dto.Session) (dto.Session, error)
+	Get(ctx context.Context, id string) (*dto.Session, error)
+	List(ctx context.Context) ([]dto.Session, error)
+	Delete(ctx context.Context, id string) error
+}
+
 type Producer interface {
-	ProducePersonal(ctx context.Context, messageID uuid.UUID, in dto.EmployeeProfile) error
-	ProducePosition(ctx context.Context, messageID uuid.UUID, in dto.EmployeeProfile) error
-	ProduceHistory(ctx context.Context, messageID uuid.UUID, in dto.EmploymentHistory) error
+	ProducePersonal(ctx context.Context, messageID uuid.UUID, in dto.EmployeeProfile, chaos *dto.ProducerChaos) (dto.ProduceReceipt, error)
+	ProducePosition(ctx context.Context, messageID uuid.UUID, in dto.EmployeeProfile, chaos *dto.ProducerChaos) (dto.ProduceReceipt, error)
+	ProduceHistory(ctx context.Context, messageID uuid.UUID, in dto.EmploymentHistory, chaos *dto.ProducerChaos) (dto.ProduceReceipt, error)
+	ProduceRaw(ctx context.Context, raw dto.RawMessage) (dto.ProduceReceipt, error)
+	Chaos() dto.ProducerChaos
+	SetChaos(chaos dto.ProducerChaos)
+}
+
+// ConsumerControl — управление консьюмерами стенда во время работы
+type ConsumerControl interface {
+	ListConsumers() []dto.ConsumerState
+	PauseConsumer(name string) (dto.ConsumerState, error)
+	ResumeConsumer(name string) (dto.ConsumerState, error)
+	StopConsumer(ctx context.Context, name string) (dto.ConsumerState, error)
+	StartConsumer(name string) (dto.ConsumerState, error)
+	LastProcessed(name string) map[string]map[int32]time.Time
+	WithStopped(ctx context.Context, name string, fn func(st dto.ConsumerState) error) (dto.ConsumerState, error)
+	StopAll(ctx context.Context) ([]dto.ConsumerState, error)
+	RestoreAll(prev []dto.ConsumerState) error
+}
+
+// KafkaAdmin — служебное состояние кластера (offset групп, lag, участники)
+type KafkaAdmin interface {
+	DescribeGroup(ctx context.Context, groupID string, topics []string) (dto.ConsumerGroupLag, error)
+	ResetGroupOffsets(ctx context.Context, groupID string, topics []string, reset dto.OffsetReset) ([]dto.OffsetResetResult, error)
+	RecreateTopic(ctx context.Context, topic string, partitions int32) (dto.TopicReset, error)
+}
+
+// PositionPolicy — политика конфликтов событий должности, меняется во время работы
+type PositionPolicy interface {
+	Get() string
+	Set(policy string) error
+}
+
+// ConsumerChaos — хаос консьюмеров (задержка, падение до коммита, сбои БД), меняется во время работы
+type ConsumerChaos interface {
+	Get() dto.ConsumerChaos
+	Set(chaos dto.ConsumerChaos) error
+}
+
+// Projector — пересборка проекций из журнала kafka_events
+type Projector interface {
+	Rebuild(ctx context.Context, dryRun bool) (dto.RebuildReport, error)
+}
+
+// ConsistencyChecker — сверка топиков, журнала, DLQ и бизнес-таблиц
+type ConsistencyChecker interface {
+	Check(ctx context.Context) (dto.ConsistencyReport, error)
+}
+
+// BugCatalog — заложенные дефекты тренажёра (режим «найди баг»)
+type BugCatalog interface {
+	List() []dto.Bug
+	Set(ids []string) error
+}
+
+// ScenarioRunner — декларативные QA-сценарии (встроенные и из YAML)
+type ScenarioRunner interface {
+	List() []dto.ScenarioInfo
+	RunBuiltin(ctx context.Context, name string) (dto.ScenarioReport, error)
+	RunYAML(ctx context.Context, source []byte) (dto.ScenarioReport, error)
 }
 
 type ServiceDeps struct {
@@ -63,35 +139,65 @@ type ServiceDeps struct {
 	EventsRepo  EventsRepository
 	ProfileRepo ProfileRepository
 	HistoryRepo HistoryRepository
+	SessionRepo SessionRepository
 	Producer    Producer
+	Consumers   ConsumerControl
+	KafkaAdmin  KafkaAdmin
+	Topics      []dto.TopicSpec // Топики стенда, которые пересоздаёт /admin/reset
+	Policy      PositionPolicy
+	Chaos       ConsumerChaos
+	Projector   Projector
+	Checker     ConsistencyChecker
+	Bugs        BugCatalog
+	Scenarios   ScenarioRunner
 }
 
 type Service struct {
-	r        *router.Router
-	server   *fasthttp.Server
-	config   config.ApiConfig
-	events   EventsRepository
-	profiles ProfileRepository
-	history  HistoryRepository
-	producer Producer
+	r         *router.Router
+	server    *fasthttp.Server
+	config    config.ApiConfig
+	events    EventsRepository
+	profiles  ProfileRepository
+	history   HistoryRepository
+	sessions  SessionRepository
+	producer  Producer
+	consumers ConsumerControl
+	admin     KafkaAdmin
+	topics    []dto.TopicSpec
+	policy    PositionPolicy
+	chaos     ConsumerChaos
+	projector Projector
+	checker   ConsistencyChecker
+	bugs      BugCatalog
+	scenarios ScenarioRunner
 }
 
 func NewService(d ServiceDeps) *Service {
 	rt := router.New()
 
 	s := &Service{
-		r:        rt,
-		config:   d.Config,
-		events:   d.EventsRepo,
-		profiles: d.ProfileRepo,
-		history:  d.HistoryRepo,
-		producer: d.Producer,
+		r:         rt,
+		config:    d.Config,
+		events:    d.EventsRepo,
+		profiles:  d.ProfileRepo,
+		history:   d.HistoryRepo,
+		sessions:  d.SessionRepo,
+		producer:  d.Producer,
+		consumers: d.Consumers,
+		admin:     d.KafkaAdmin,
+		topics:    d.Topics,
+		policy:    d.Policy,
+		chaos:     d.Chaos,
+		projector: d.Projector,
+		checker:   d.Checker,
+		bugs:      d.Bugs,
+		scenarios: d.Scenarios,
 	}
 
 	s.mountRoutes()
 
 	s.server = &fasthttp.Server{
-		Handler:            RecoveryMiddleware(LoggingMiddleware(CORS(s.r.Handler))),
+		Handler:            RecoveryMiddleware(LoggingMiddleware(CORS(s.SessionScope(s.r.Handler)))),
 		Name:               "qa-kafka-api",
 		ReadTimeout:        10 * time.Second,
 		WriteTimeout:       15 * time.Second,
@@ -101,7 +207,7 @@ func NewService(d ServiceDeps) *Service {
 	return s
 }
 func (s *Service) Start(ctx context.Context) error {
-	mainHandler := RecoveryMiddleware(LoggingMiddleware(CORS(s.r.Handler)))
+	mainHandler := RecoveryMiddleware(LoggingMiddleware(CORS(s.SessionScope(s.r.Handler))))
 
 	server := fasthttp.Server{
 		Handler: mainHandler,
@@ -128,6 +234,8 @@ func (s *Service) mountRoutes() {
 	s.r.POST("/producer/personal", s.producerPersonal)
 	s.r.POST("/producer/position", s.producerPosition)
 	s.r.POST("/producer/history", s.producerHistory)
+	s.r.POST("/producer/raw", s.producerRaw)
+	s.r.POST("/producer/batch", s.producerBatch)
 
 	// Profiles
 	s.r.POST("/profiles", s.createProfile)
@@ -135,6 +243,7 @@ func (s *Service) mountRoutes() {
 	s.r.DELETE("/profiles/{employee_id}", s.deleteProfile)
 	s.r.GET("/profiles", s.listProfiles)
 	s.r.GET("/profiles/{employee_id}", s.getProfile)
+	s.r.GET("/profiles/{employee_id}/positions", s.listProfilePositions)
 
 	// History
 	s.r.POST("/history", s.createHistory)
@@ -144,9 +253,39 @@ func (s *Service) mountRoutes() {
 
 	// Events/DLQ
 	s.r.GET("/events", s.listEvents)
+	s.r.GET("/events/ordering", s.checkEventOrdering)
 	s.r.GET("/dlq", s.listDLQ)
+	s.r.POST("/dlq/replay", s.replayDLQBatch)
+	s.r.POST("/dlq/{id}/replay", s.replayDLQ)
+	s.r.GET("/messages/{message_id}", s.getMessageLifecycle)
+
+	// Consumers
+	s.r.GET("/consumers", s.listConsumerLag)
+	s.r.GET("/consistency", s.checkConsistency)
+
+	// Sessions
+	s.r.POST("/sessions", s.createSession)
+	s.r.GET("/sessions", s.listSessions)
+	s.r.DELETE("/sessions/{id}", s.deleteSession)
+
+	// Scenarios
+	s.r.GET("/scenarios", s.listScenarios)
+	s.r.POST("/scenarios/run", s.runScenarioYAML)
+	s.r.POST("/scenarios/{name}/run", s.runScenario)
 
 	// Admin & Health
 	s.r.GET("/health", s.healthHandler)
 	s.r.POST("/admin/reset", s.resetHandler)
+	s.r.POST("/admin/rebuild", s.rebuildHandler)
+	s.r.GET("/admin/consumers", s.listConsumers)
+	s.r.GET("/admin/position-policy", s.getPositionPolicy)
+	s.r.PUT("/admin/position-policy", s.setPositionPolicy)
+	s.r.GET("/admin/consumer-chaos", s.getConsumerChaos)
+	s.r.PUT("/admin/consumer-chaos", s.setConsumerChaos)
+	s.r.GET("/admin/producer-chaos", s.getProducerChaos)
+	s.r.PUT("/admin/producer-chaos", s.setProducerChaos)
+	s.r.GET("/admin/bugs", s.listBugs)
+	s.r.PUT("/admin/bugs", s.setBugs)
+	s.r.POST("/admin/consumers/{name}/offsets", s.resetConsumerOffsets)
+	s.r.POST("/admin/consumers/{name}/{action}", s.controlConsumer)
 }
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+)
+
+type setBugsRequest struct {
+	Password string   `json:"password"`                                      // пароль
+	Enabled  []string `json:"enabled" example:"swap-names,drop-nth-history"` // Включаемые дефекты; остальные выключаются, пустой список — выключить все
+}
+
+// AdminPasswordHeader — пароль администратора для GET-запросов, у которых нет тела
+const AdminPasswordHeader = "X-Admin-Password"
+
+// @Summary Каталог заложенных дефектов
+// @Tags    Admin
+// @Produce json
+// @Param   X-Admin-Password header string false "Пароль администратора: без него поле enabled не возвращается"
+// @Success 200 {array} dto.Bug
+// @description Какие дефекты включены, видит только администратор: иначе стажёр узнал бы ответ упражнения «найди баг».
+// @Failure 401 {object} errorResponse "invalid admin password"
+// @Router  /admin/bugs [get]
+func (s *Service) listBugs(ctx *fasthttp.RequestCtx) {
+	list := s.bugs.List()
+
+	password := string(ctx.Request.Header.Peek(AdminPasswordHeader))
+	if password == "" {
+		for i := range list {
+			list[i].Enabled = nil
+		}
+
+		writeJSON(ctx, fasthttp.StatusOK, list)
+		return
+	}
+
+	if status, err := s.checkAdminPassword(password); err != nil {
+		writeError(ctx, status, err)
+		return
+	}
+
+	writeJSON(ctx, fasthttp.StatusOK, list)
+}
+
+// @Summary Включение заложенных дефектов
+// @Tags    Admin
+// @Accept  json
+// @Produce json
+// @Param   request body setBugsRequest true "Пароль и включаемые дефекты"
+// @Success 200 {array} dto.Bug
+// @description Режим «найди баг»: набор включённых дефектов заменяется целиком и действует сразу, без перезапуска консьюмеров.
+// @description Пересборка проекций (POST /admin/rebuild) дефектов не видит — dry_run показывает, что они испортили.
+// @Failure 400 {object} errorResponse "unknown bug"
+// @Failure 401 {object} errorResponse "invalid admin password"
+// @Router  /admin/bugs [put]
+func (s *Service) setBugs(ctx *fasthttp.RequestCtx) {
+	var req setBugsRequest
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("json.Unmarshal: %w", err))
+		return
+	}
+
+	if status, err := s.checkAdminPassword(req.Password); err != nil {
+		writeError(ctx, status, err)
+		return
+	}
+
+	if err := s.bugs.Set(req.Enabled); err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(ctx, fasthttp.StatusOK, s.bugs.List())
+}
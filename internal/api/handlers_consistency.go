@@ -0,0 +1,35 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// consistencyTimeout — предел на сверку: чтение трёх топиков целиком и журнала
+const consistencyTimeout = time.Minute
+
+// @Summary Сверка Kafka, журнала и бизнес-таблиц
+// @Tags    Consumers
+// @Produce json
+// @Success 200 {object} dto.ConsistencyReport
+// @description Топики hr.* читаются с начала: каждое сообщение должно быть в kafka_events, kafka_dlq, быть дублем уже применённого
+// @description message_id, ждать в retry-топике или лежать за committed offset группы (иначе lost). Записи журнала и DLQ без сообщения
+// @description в топике — unexplained_*. События журнала сверяются с employee_profile, position_assignment и employment_history
+// @description (not_reflected, mismatch). consistent=true — проблем нет.
+// @Failure 500 {object} errorResponse "Внутренняя ошибка"
+// @Router  /consistency [get]
+func (s *Service) checkConsistency(ctx *fasthttp.RequestCtx) {
+	checkCtx, cancel := context.WithTimeout(ctx, consistencyTimeout)
+	defer cancel()
+
+	report, err := s.checker.Check(checkCtx)
+	if err != nil {
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("checker.Check: %w", err))
+		return
+	}
+
+	writeJSON(ctx, fasthttp.StatusOK, report)
+}
@@ -0,0 +1,312 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/valyala/fasthttp"
+)
+
+// consumerStopTimeout — сколько ждать выхода консьюмера из группы при stop
+const consumerStopTimeout = 30 * time.Second
+
+// consumerControlRequest — пароль для управления консьюмером
+type consumerControlRequest struct {
+	Password string `json:"password"` // пароль
+}
+
+// offsetResetRequest — куда переставить offset группы консьюмера
+type offsetResetRequest struct {
+	Password   string  `json:"password"`                                                       // пароль
+	To         string  `json:"to" example:"earliest" enums:"earliest,latest,offset,timestamp"` // Цель перестановки
+	Topic      string  `json:"topic,omitempty" example:"hr.personal"`                          // Топик (не передан — все топики консьюмера)
+	Partitions []int32 `json:"partitions,omitempty"`                                           // Партиции (не переданы — все)
+	Offset     *int64  `json:"offset,omitempty" example:"42"`                                  // Для to=offset
+	Timestamp  string  `json:"timestamp,omitempty" example:"2025-10-01T10:00:00+03:00"`        // Для to=timestamp (RFC3339)
+}
+
+// positionPolicyBody — политика конфликтов событий должности
+type positionPolicyBody struct {
+	Policy string `json:"policy" example:"effective_from" enums:"arrival,effective_from,reject_stale"` // Политика
+}
+
+type positionPolicyRequest struct {
+	Password string `json:"password"` // пароль
+	positionPolicyBody
+}
+
+// consumerChaosRequest — пароль и хаос консьюмеров ({} без полей хаоса — выключить)
+type consumerChaosRequest struct {
+	Password string `json:"password"` // пароль
+	dto.ConsumerChaos
+}
+
+// offsetResetResponse — состояние консьюмера после перестановки и новые offset
+type offsetResetResponse struct {
+	Consumer   dto.ConsumerState       `json:"consumer"`
+	Partitions []dto.OffsetResetResult `json:"partitions"`
+}
+
+// @Summary Состояние консьюмеров
+// @Tags    Consumers
+// @Produce json
+// @Success 200 {array} dto.ConsumerState
+// @Router  /admin/consumers [get]
+func (s *Service) listConsumers(ctx *fasthttp.RequestCtx) {
+	writeJSON(ctx, fasthttp.StatusOK, s.consumers.ListConsumers())
+}
+
+// @Summary Управление консьюмером: pause, resume, stop, start
+// @Tags    Consumers
+// @Produce json
+// @Param   name   path string true "Имя консьюмера" Enums(personal, positions, history)
+// @Param   action path string true "Действие" Enums(pause, resume, stop, start)
+// @Param   request body consumerControlRequest true "Пароль"
+// @Success 200 {object} dto.ConsumerState
+// @description pause/resume приостанавливают fetch партиций без выхода из группы (PauseAll/ResumeAll).
+// @description stop выводит консьюмер из группы (offset сохраняются), start — возвращает; HTTP API при этом продолжает работать.
+// @Failure 400 {object} errorResponse "unknown action"
+// @Failure 401 {object} errorResponse "invalid admin password"
+// @Failure 404 {object} errorResponse "consumer not found"
+// @Failure 409 {object} errorResponse "Недопустимый переход, например pause остановленного консьюмера"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка"
+// @Router  /admin/consumers/{name}/{action} [post]
+func (s *Service) controlConsumer(ctx *fasthttp.RequestCtx) {
+	name := ctx.UserValue("name").(string)
+	action := ctx.UserValue("action").(string)
+
+	var req consumerControlRequest
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("json.Unmarshal: %w", err))
+		return
+	}
+
+	if status, err := s.checkAdminPassword(req.Password); err != nil {
+		writeError(ctx, status, err)
+		return
+	}
+
+	var (
+		state dto.ConsumerState
+		err   error
+	)
+
+	switch action {
+	case "pause":
+		state, err = s.consumers.PauseConsumer(name)
+	case "resume":
+		state, err = s.consumers.ResumeConsumer(name)
+	case "stop":
+		stopCtx, cancel := context.WithTimeout(ctx, consumerStopTimeout)
+		state, err = s.consumers.StopConsumer(stopCtx, name)
+		cancel()
+	case "start":
+		state, err = s.consumers.StartConsumer(name)
+	default:
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("unknown action '%s'", action))
+		return
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, dto.ErrNotFound):
+			writeError(ctx, fasthttp.StatusNotFound, ErrConsumerNotFound)
+		case errors.Is(err, dto.ErrInvalidState):
+			writeError(ctx, fasthttp.StatusConflict, err)
+		default:
+			writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("consumers.%s: %w", action, err))
+		}
+		return
+	}
+
+	writeJSON(ctx, fasthttp.StatusOK, state)
+}
+
+// @Summary Отставание консьюмеров по партициям
+// @Tags    Consumers
+// @Produce json
+// @Success 200 {array} dto.ConsumerGroupLag
+// @description По каждой группе (consumer_personal, consumer_positions, consumer_history) и партиции:
+// @description закоммиченный offset, high-water mark, lag, назначенный участник и время последней обработки.
+// @Failure 500 {object} errorResponse "Внутренняя ошибка"
+// @Router  /consumers [get]
+func (s *Service) listConsumerLag(ctx *fasthttp.RequestCtx) {
+	states := s.consumers.ListConsumers()
+	out := make([]dto.ConsumerGroupLag, 0, len(states))
+
+	for _, st := range states {
+		lag, err := s.admin.DescribeGroup(ctx, st.GroupID, st.Topics)
+		if err != nil {
+			writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("admin.DescribeGroup %s: %w", st.GroupID, err))
+			return
+		}
+
+		lag.Name = st.Name
+		lag.State = st.State
+
+		processed := s.consumers.LastProcessed(st.Name)
+		for i, p := range lag.Partitions {
+			if at, ok := processed[p.Topic][p.Partition]; ok {
+				ts := at.UTC().Format(time.RFC3339)
+				lag.Partitions[i].LastProcessedAt = &ts
+			}
+		}
+
+		out = append(out, lag)
+	}
+
+	writeJSON(ctx, fasthttp.StatusOK, out)
+}
+
+// @Summary Перестановка offset группы консьюмера
+// @Tags    Consumers
+// @Accept  json
+// @Produce json
+// @Param   name    path string             true "Имя консьюмера" Enums(personal, positions, history)
+// @Param   request body offsetResetRequest true "Пароль и цель перестановки"
+// @Success 200 {object} offsetResetResponse
+// @description Консьюмер останавливается (группа пустеет), offset коммитятся заново, затем консьюмер возвращается в прежнее состояние.
+// @description earliest — перечитать всё, latest — пропустить накопленное, offset — конкретная позиция, timestamp — первое сообщение не раньше времени.
+// @Failure 400 {object} errorResponse "Некорректные параметры"
+// @Failure 401 {object} errorResponse "invalid admin password"
+// @Failure 404 {object} errorResponse "consumer not found"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка"
+// @Router  /admin/consumers/{name}/offsets [post]
+func (s *Service) resetConsumerOffsets(ctx *fasthttp.RequestCtx) {
+	name := ctx.UserValue("name").(string)
+
+	var req offsetResetRequest
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("json.Unmarshal: %w", err))
+		return
+	}
+
+	if status, err := s.checkAdminPassword(req.Password); err != nil {
+		writeError(ctx, status, err)
+		return
+	}
+
+	reset, msg := validateOffsetReset(req)
+	if msg != "" {
+		writeError(ctx, fasthttp.StatusBadRequest, errors.New(msg))
+		return
+	}
+
+	var results []dto.OffsetResetResult
+
+	stopCtx, cancel := context.WithTimeout(ctx, consumerStopTimeout)
+	defer cancel()
+
+	state, err := s.consumers.WithStopped(stopCtx, name, func(st dto.ConsumerState) error {
+		if reset.Topic != "" && !slices.Contains(st.Topics, reset.Topic) {
+			return fmt.Errorf("consumer %s does not read topic '%s': %w", st.Name, reset.Topic, ErrInvalidTopic)
+		}
+
+		var err error
+		results, err = s.admin.ResetGroupOffsets(ctx, st.GroupID, st.Topics, reset)
+
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidTopic):
+			writeError(ctx, fasthttp.StatusBadRequest, err)
+		case errors.Is(err, dto.ErrNotFound) && state.Name == "":
+			// консьюмер не найден — состояние пустое
+			writeError(ctx, fasthttp.StatusNotFound, ErrConsumerNotFound)
+		case errors.Is(err, dto.ErrNotFound):
+			// несуществующая партиция
+			writeError(ctx, fasthttp.StatusBadRequest, err)
+		default:
+			writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("admin.ResetGroupOffsets: %w", err))
+		}
+		return
+	}
+
+	writeJSON(ctx, fasthttp.StatusOK, offsetResetResponse{Consumer: state, Partitions: results})
+}
+
+// @Summary Текущая политика конфликтов событий должности
+// @Tags    Consumers
+// @Produce json
+// @Success 200 {object} positionPolicyBody
+// @Router  /admin/position-policy [get]
+func (s *Service) getPositionPolicy(ctx *fasthttp.RequestCtx) {
+	writeJSON(ctx, fasthttp.StatusOK, positionPolicyBody{Policy: s.policy.Get()})
+}
+
+// @Summary Смена политики конфликтов событий должности
+// @Tags    Consumers
+// @Accept  json
+// @Produce json
+// @Param   request body positionPolicyRequest true "Пароль и политика"
+// @Success 200 {object} positionPolicyBody
+// @description Событие с effective_from старше текущего считается устаревшим (stale в kafka_events):
+// @description arrival — применяется (последнее по приходу побеждает), effective_from — не применяется (решение stale), reject_stale — уходит в DLQ.
+// @Failure 400 {object} errorResponse "unknown position policy"
+// @Failure 401 {object} errorResponse "invalid admin password"
+// @Router  /admin/position-policy [put]
+func (s *Service) setPositionPolicy(ctx *fasthttp.RequestCtx) {
+	var req positionPolicyRequest
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("json.Unmarshal: %w", err))
+		return
+	}
+
+	if status, err := s.checkAdminPassword(req.Password); err != nil {
+		writeError(ctx, status, err)
+		return
+	}
+
+	if err := s.policy.Set(req.Policy); err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(ctx, fasthttp.StatusOK, positionPolicyBody{Policy: s.policy.Get()})
+}
+
+// @Summary Текущий хаос консьюмеров
+// @Tags    Consumers
+// @Produce json
+// @Success 200 {object} dto.ConsumerChaos
+// @Router  /admin/consumer-chaos [get]
+func (s *Service) getConsumerChaos(ctx *fasthttp.RequestCtx) {
+	writeJSON(ctx, fasthttp.StatusOK, s.chaos.Get())
+}
+
+// @Summary Смена хаоса консьюмеров
+// @Tags    Consumers
+// @Accept  json
+// @Produce json
+// @Param   request body consumerChaosRequest true "Пароль и хаос (без полей хаоса — выключить)"
+// @Success 200 {object} dto.ConsumerChaos
+// @description latency — задержка перед каждым сообщением; crash_percent — падение после записи в БД, но до коммита offset
+// @description (сессия перезапускается, сообщение приходит повторно); db_error_percent — временная ошибка вызова репозитория (retry, без retry — DLQ).
+// @Failure 400 {object} errorResponse "invalid value in field"
+// @Failure 401 {object} errorResponse "invalid admin password"
+// @Router  /admin/consumer-chaos [put]
+func (s *Service) setConsumerChaos(ctx *fasthttp.RequestCtx) {
+	var req consumerChaosRequest
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("json.Unmarshal: %w", err))
+		return
+	}
+
+	if status, err := s.checkAdminPassword(req.Password); err != nil {
+		writeError(ctx, status, err)
+		return
+	}
+
+	if err := s.chaos.Set(req.ConsumerChaos); err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(ctx, fasthttp.StatusOK, s.chaos.Get())
+}
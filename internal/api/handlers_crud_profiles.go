@@ -43,7 +43,11 @@ func (s *Service) listProfiles(ctx *fasthttp.RequestCtx) {
 // @Tags CRUD-Profiles
 // @Produce json
 // @Param employee_id path string true "Идентификатор сотрудника"
+// @Param as_of query string false "Должность на дату (YYYY-MM-DD), по умолчанию — сегодня"
 // @Success 200 {object} dto.EmployeeProfile
+// @description Должность берётся из истории назначений: действует последнее с effective_from не позже as_of,
+// @description назначения с будущей датой становятся текущими, когда дата наступит.
+// @Failure 400 {object} errorResponse "invalid value in field 'as_of'"
 // @Failure 404 {object} errorResponse "employee not found"
 // @Failure 500 {object} errorResponse "Внутренняя ошибка"
 // @Router /profiles/{employee_id} [get]
@@ -54,7 +58,15 @@ func (s *Service) getProfile(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	row, err := s.profiles.GetProfile(ctx, employeeID)
+	asOf := string(ctx.QueryArgs().Peek("as_of"))
+	if asOf != "" {
+		if msg := checkDate("as_of", asOf); msg != "" {
+			writeError(ctx, fasthttp.StatusBadRequest, errors.New(msg))
+			return
+		}
+	}
+
+	row, err := s.profiles.GetProfileAsOf(ctx, employeeID, asOf)
 
 	if err != nil {
 		if errors.Is(err, dto.ErrNotFound) {
@@ -69,6 +81,41 @@ func (s *Service) getProfile(ctx *fasthttp.RequestCtx) {
 	writeJSON(ctx, fasthttp.StatusOK, row)
 }
 
+// @Summary История должностей сотрудника
+// @Tags CRUD-Profiles
+// @Produce json
+// @Param employee_id path string true "Идентификатор сотрудника"
+// @Success 200 {array} dto.PositionAssignment
+// @description Назначения из hr.positions по effective_from; status — past, current (действует сегодня) или future.
+// @Failure 404 {object} errorResponse "employee not found"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка"
+// @Router /profiles/{employee_id}/positions [get]
+func (s *Service) listProfilePositions(ctx *fasthttp.RequestCtx) {
+	employeeID := ctx.UserValue("employee_id").(string)
+	if strings.TrimSpace(employeeID) == "" {
+		writeError(ctx, fasthttp.StatusBadRequest, ErrEmployeeIDRequired)
+		return
+	}
+
+	if _, err := s.profiles.GetProfile(ctx, employeeID); err != nil {
+		if errors.Is(err, dto.ErrNotFound) {
+			writeError(ctx, fasthttp.StatusNotFound, ErrProfileNotFound)
+			return
+		}
+
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("profileRepository.GetProfile: %w", err))
+		return
+	}
+
+	rows, err := s.profiles.ListPositions(ctx, employeeID)
+	if err != nil {
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("profileRepository.ListPositions: %w", err))
+		return
+	}
+
+	writeJSON(ctx, fasthttp.StatusOK, rows)
+}
+
 // @Summary Создать профиль
 // @Tags    CRUD-Profiles
 // @Accept  json
@@ -124,7 +171,10 @@ func (s *Service) createProfile(ctx *fasthttp.RequestCtx) {
 // @description - required (если присутствует): title, department, grade, effective_from
 // @description - invalid value: email, birth_date, effective_from (если присутствует)
 // @description - invalid enum (если присутствует): grade in {Junior, Middle, Senior, Lead, Head}
+// @description Если у сотрудника есть назначения из hr.positions, должность берётся из них (см. GET /profiles/{employee_id}):
+// @description title, department, grade и effective_from через CRUD не меняются — 409, остальные поля обновляются как обычно.
 // @Failure 404 {object} errorResponse "employee not found"
+// @Failure 409 {object} errorResponse "position is set by hr.positions events"
 // @Failure 500 {object} errorResponse "Внутренняя ошибка"
 // @Router  /profiles/{employee_id} [put]
 func (s *Service) updateProfile(ctx *fasthttp.RequestCtx) {
@@ -167,6 +217,12 @@ func (s *Service) updateProfile(ctx *fasthttp.RequestCtx) {
 			return
 		}
 
+		if errors.Is(err, dto.ErrInvalidState) {
+			writeError(ctx, fasthttp.StatusConflict, ErrPositionFromEvents)
+
+			return
+		}
+
 		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("profileRepository.Update: %w", err))
 		return
 	}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	dlqReplayDefaultLimit = 100
+	dlqReplayMaxLimit     = 1000
+)
+
+// dlqReplayRequest — replay одной записи DLQ
+type dlqReplayRequest struct {
+	Payload json.RawMessage `json:"payload,omitempty" swaggertype:"object"` // Исправленный payload (не передан — исходное тело сообщения)
+}
+
+// dlqBatchReplayRequest — replay записей DLQ по фильтру
+type dlqBatchReplayRequest struct {
+	Topic           string `json:"topic,omitempty" example:"hr.personal"`              // Исходный топик
+	ErrorContains   string `json:"error_contains,omitempty" example:"birth_date"`      // Подстрока причины
+	From            string `json:"from,omitempty" example:"2025-10-01T00:00:00+03:00"` // received_at не раньше (RFC3339)
+	To              string `json:"to,omitempty" example:"2025-10-02T00:00:00+03:00"`   // received_at не позже (RFC3339)
+	IncludeReplayed bool   `json:"include_replayed,omitempty" example:"false"`         // Повторить и уже успешно переотправленные
+	Limit           int    `json:"limit,omitempty" example:"100"`                      // Максимум записей (по умолчанию 100, не более 1000)
+}
+
+// dlqReplayResponse — результат replay одной записи DLQ
+type dlqReplayResponse struct {
+	DLQID   int64               `json:"dlq_id" example:"7"`                                // Идентификатор записи DLQ
+	Status  string              `json:"status" example:"replayed" enums:"replayed,failed"` // Результат replay
+	Receipt *dto.ProduceReceipt `json:"receipt,omitempty"`                                 // Квитанция повторной отправки
+	Error   string              `json:"error,omitempty"`                                   // Ошибка отправки
+}
+
+// @Summary Повторная отправка записи DLQ в исходный топик
+// @Tags    DLQ
+// @Accept  json
+// @Produce json
+// @Param   id path int true "ID записи DLQ"
+// @Param   request body dlqReplayRequest false "Исправленный payload"
+// @Success 200 {object} dlqReplayResponse
+// @description Сообщение уходит в исходный топик с исходным ключом и заголовками x-dlq-id, x-replay-attempt.
+// @description Без payload отправляется исходное тело байт в байт. Попытка фиксируется в записи DLQ (replay_status, replay_attempts).
+// @Failure 400 {object} errorResponse "invalid value in field 'id'"
+// @Failure 404 {object} errorResponse "dlq record not found"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка"
+// @Router  /dlq/{id}/replay [post]
+func (s *Service) replayDLQ(ctx *fasthttp.RequestCtx) {
+	idStr := ctx.UserValue("id").(string)
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("invalid value in field 'id'=%s", idStr))
+		return
+	}
+
+	var req dlqReplayRequest
+	if len(ctx.PostBody()) > 0 {
+		if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+			writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("json.Unmarshal: %w", err))
+			return
+		}
+	}
+
+	row, err := s.events.GetDLQ(ctx, id)
+	if err != nil {
+		if errors.Is(err, dto.ErrNotFound) {
+			writeError(ctx, fasthttp.StatusNotFound, ErrDLQNotFound)
+			return
+		}
+
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("events.GetDLQ: %w", err))
+		return
+	}
+
+	res, err := s.replayDLQRow(ctx, *row, req.Payload)
+	if err != nil {
+		writeError(ctx, fasthttp.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(ctx, fasthttp.StatusOK, res)
+}
+
+// @Summary Повторная отправка записей DLQ по фильтру (redrive)
+// @Tags    DLQ
+// @Accept  json
+// @Produce json
+// @Param   request body dlqBatchReplayRequest true "Фильтр"
+// @Success 200 {array} dlqReplayResponse
+// @description По умолчанию пропускаются записи, уже успешно переотправленные (include_replayed=false).
+// @Failure 400 {object} errorResponse "invalid value in field 'from'/'to'/'limit'"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка"
+// @Router  /dlq/replay [post]
+func (s *Service) replayDLQBatch(ctx *fasthttp.RequestCtx) {
+	var req dlqBatchReplayRequest
+
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("json.Unmarshal: %w", err))
+		return
+	}
+
+	filter := dto.DLQFilter{
+		Topic:         req.Topic,
+		ErrorContains: req.ErrorContains,
+		From:          req.From,
+		To:            req.To,
+		OnlyPending:   !req.IncludeReplayed,
+		Limit:         req.Limit,
+	}
+
+	if msg := validateDLQFilter(filter); msg != "" {
+		writeError(ctx, fasthttp.StatusBadRequest, errors.New(msg))
+		return
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = dlqReplayDefaultLimit
+	}
+	if filter.Limit > dlqReplayMaxLimit {
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("invalid value in field 'limit'=%d: max %d", filter.Limit, dlqReplayMaxLimit))
+		return
+	}
+
+	rows, err := s.events.ListDLQ(ctx, filter)
+	if err != nil {
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("events.ListDLQ: %w", err))
+		return
+	}
+
+	// в DLQ новые записи сверху, отправляем в порядке поступления
+	out := make([]dlqReplayResponse, 0, len(rows))
+	for i := len(rows) - 1; i >= 0; i-- {
+		res, err := s.replayDLQRow(ctx, rows[i], nil)
+		if err != nil {
+			writeError(ctx, fasthttp.StatusInternalServerError, err)
+			return
+		}
+
+		out = append(out, res)
+	}
+
+	writeJSON(ctx, fasthttp.StatusOK, out)
+}
+
+// replayDLQRow отправляет запись DLQ в исходный топик и фиксирует попытку.
+// Ошибка Kafka — часть результата; error возвращается только при сбое БД.
+func (s *Service) replayDLQRow(ctx context.Context, row dto.KafkaDLQ, payload json.RawMessage) (dlqReplayResponse, error) {
+	body := []byte(payload)
+	if len(body) == 0 {
+		body = row.RawPayload
+	}
+	if body == nil {
+		body = row.Payload
+	}
+
+	raw := dto.RawMessage{
+		Topic: row.Topic,
+		Headers: map[string]string{
+			"x-dlq-id":         strconv.FormatInt(row.ID, 10),
+			"x-replay-attempt": strconv.Itoa(row.ReplayAttempts + 1),
+		},
+		Body: body,
+	}
+	if row.Key != "" {
+		raw.Key = &row.Key
+	}
+	// при ключе employee_id message_id есть только в заголовке и теле
+	if row.MessageID != nil {
+		raw.Headers[dto.HeaderMessageID] = row.MessageID.String()
+	}
+
+	res := dlqReplayResponse{DLQID: row.ID, Status: dto.ReplayStatusReplayed}
+
+	receipt, err := s.producer.ProduceRaw(ctx, raw)
+	if err != nil {
+		res.Status = dto.ReplayStatusFailed
+		res.Error = err.Error()
+	} else {
+		res.Receipt = &receipt
+		s.saveReceipt(ctx, receipt)
+	}
+
+	if err := s.events.MarkDLQReplay(ctx, row.ID, res.Status, res.Error); err != nil {
+		return res, fmt.Errorf("events.MarkDLQReplay id=%d: %w", row.ID, err)
+	}
+
+	return res, nil
+}
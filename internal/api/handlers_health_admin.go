@@ -1,15 +1,29 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/valyala/fasthttp"
 	"strings"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/valyala/fasthttp"
 )
 
+// resetTimeout — предел на весь сброс: остановка консьюмеров, пересоздание топиков, offset, БД
+const resetTimeout = 2 * time.Minute
+
+type rebuildRequest struct {
+	Password string `json:"password"`                          // пароль
+	DryRun   bool   `json:"dry_run,omitempty" example:"false"` // Только отчёт о расхождениях, изменения откатываются
+}
+
 type resetRequest struct {
-	Password string `json:"password"` // пароль
+	Password       string `json:"password"`                                                         // пароль
+	RecreateTopics bool   `json:"recreate_topics,omitempty" example:"true"`                         // Удалить и создать заново топики hr.* (со служебными .dlq/.retry.N)
+	ResetOffsets   string `json:"reset_offsets,omitempty" example:"latest" enums:"earliest,latest"` // Куда переставить offset групп; при recreate_topics по умолчанию earliest
 }
 
 // @Summary Проверка здоровья сервиса
@@ -20,10 +34,17 @@ func (s *Service) healthHandler(ctx *fasthttp.RequestCtx) {
 	ok(ctx, "OK")
 }
 
-// @Summary Полная очистка данных тренажёра (truncate tables.*)
+// @Summary Сброс окружения тренажёра
 // @Tags    Admin
-// @Param   request body resetRequest true "Пароль"
-// @Success 200 {object} okResponse
+// @Param   request body resetRequest true "Пароль и что сбрасывать"
+// @Success 200 {object} dto.ResetReport
+// @description Консьюмеры останавливаются, при recreate_topics топики пересоздаются с числом партиций из kafka.partitions,
+// @description при reset_offsets (или recreate_topics) offset групп переставляются, таблицы очищаются (truncate), затем консьюмеры возвращаются в прежнее состояние.
+// @description С заголовком X-Session-ID удаляются только данные этой сессии; топики и offset общие для всех сессий,
+// @description поэтому recreate_topics и reset_offsets в сессии недоступны.
+// @Param   X-Session-ID header string false "Сессия стажёра"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse "invalid admin password"
 // @Failure 500 {object} errorResponse
 // @Router  /admin/reset [post]
 func (s *Service) resetHandler(ctx *fasthttp.RequestCtx) {
@@ -33,20 +54,187 @@ func (s *Service) resetHandler(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	if strings.TrimSpace(req.Password) == "" {
-		writeError(ctx, fasthttp.StatusBadRequest, errors.New("required field 'admin_password'"))
+	if status, err := s.checkAdminPassword(req.Password); err != nil {
+		writeError(ctx, status, err)
+		return
+	}
+
+	switch req.ResetOffsets {
+	case "", dto.OffsetResetEarliest, dto.OffsetResetLatest:
+	default:
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("invalid value in field 'reset_offsets'=%s", req.ResetOffsets))
+		return
+	}
+
+	if session := dto.SessionFrom(ctx); session != "" {
+		if req.RecreateTopics || req.ResetOffsets != "" {
+			writeError(ctx, fasthttp.StatusBadRequest, errors.New("recreate_topics and reset_offsets are not available in a session: topics and offsets are shared"))
+			return
+		}
+
+		report, err := s.resetSession(ctx, session)
+		if err != nil {
+			writeError(ctx, fasthttp.StatusInternalServerError, err)
+			return
+		}
+
+		writeJSON(ctx, fasthttp.StatusOK, report)
 		return
 	}
 
-	if req.Password != s.config.AdminResetPassword.Value {
-		writeError(ctx, fasthttp.StatusUnauthorized, errors.New("invalid admin password"))
+	resetCtx, cancel := context.WithTimeout(ctx, resetTimeout)
+	defer cancel()
+
+	report, err := s.resetEnvironment(resetCtx, req)
+	if err != nil {
+		writeError(ctx, fasthttp.StatusInternalServerError, err)
 		return
 	}
 
+	writeJSON(ctx, fasthttp.StatusOK, report)
+}
+
+// checkAdminPassword проверяет пароль административных операций; при ошибке — HTTP-статус ответа
+func (s *Service) checkAdminPassword(password string) (int, error) {
+	if strings.TrimSpace(password) == "" {
+		return fasthttp.StatusBadRequest, errors.New("required field 'admin_password'")
+	}
+
+	if password != s.config.AdminResetPassword.Value {
+		return fasthttp.StatusUnauthorized, errors.New("invalid admin password")
+	}
+
+	return fasthttp.StatusOK, nil
+}
+
+// resetEnvironment сбрасывает окружение при остановленных консьюмерах, чтобы
+// ни одно сообщение не применилось между очисткой БД и перестановкой offset.
+func (s *Service) resetEnvironment(ctx context.Context, req resetRequest) (report dto.ResetReport, err error) {
+	started := time.Now()
+	report.Topics = []dto.TopicReset{}
+	report.Offsets = []dto.ConsumerOffsetReset{}
+
+	prev, err := s.consumers.StopAll(ctx)
+	if err != nil {
+		return report, fmt.Errorf("consumers.StopAll: %w", err)
+	}
+
+	defer func() {
+		if restoreErr := s.consumers.RestoreAll(prev); restoreErr != nil && err == nil {
+			err = fmt.Errorf("consumers.RestoreAll: %w", restoreErr)
+		}
+		report.Consumers = s.consumers.ListConsumers()
+		report.Duration = time.Since(started).Round(10 * time.Millisecond).String()
+	}()
+
+	if req.RecreateTopics {
+		for _, topic := range s.topics {
+			recreated, err := s.admin.RecreateTopic(ctx, topic.Name, topic.Partitions)
+			if err != nil {
+				return report, fmt.Errorf("admin.RecreateTopic: %w", err)
+			}
+			report.Topics = append(report.Topics, recreated)
+		}
+	}
+
+	// старые offset на пересозданных топиках указывали бы за конец партиции
+	offsets := req.ResetOffsets
+	if offsets == "" && req.RecreateTopics {
+		offsets = dto.OffsetResetEarliest
+	}
+
+	if offsets != "" {
+		for _, st := range prev {
+			results, err := s.admin.ResetGroupOffsets(ctx, st.GroupID, st.Topics, dto.OffsetReset{To: offsets})
+			if err != nil {
+				return report, fmt.Errorf("admin.ResetGroupOffsets %s: %w", st.GroupID, err)
+			}
+			report.Offsets = append(report.Offsets, dto.ConsumerOffsetReset{Consumer: st.Name, GroupID: st.GroupID, Partitions: results})
+		}
+	}
+
 	if err := s.events.ResetAll(ctx); err != nil {
-		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("events.ResetAll: %w", err))
+		return report, fmt.Errorf("events.ResetAll: %w", err)
+	}
+	report.Database = true
+
+	return report, nil
+}
+
+// resetSession удаляет данные одной сессии. Консьюмеры не останавливаются: они общие
+// для всех сессий, а сообщение сессии, применённое во время удаления, — её новые данные.
+func (s *Service) resetSession(ctx context.Context, session string) (dto.ResetReport, error) {
+	started := time.Now()
+
+	if err := s.events.ResetSession(ctx); err != nil {
+		return dto.ResetReport{}, fmt.Errorf("events.ResetSession: %w", err)
+	}
+
+	return dto.ResetReport{
+		Topics:    []dto.TopicReset{},
+		Offsets:   []dto.ConsumerOffsetReset{},
+		Database:  true,
+		Session:   session,
+		Consumers: s.consumers.ListConsumers(),
+		Duration:  time.Since(started).Round(10 * time.Millisecond).String(),
+	}, nil
+}
+
+// @Summary Пересборка проекций из журнала kafka_events
+// @Tags    Admin
+// @Param   request body rebuildRequest true "Пароль и режим"
+// @Success 200 {object} dto.RebuildReport
+// @description Консьюмеры останавливаются, employee_profile, employment_history и position_assignment очищаются
+// @description и заполняются заново из журнала (hr.personal, hr.positions, hr.history; внутри топика — partition, offset)
+// @description той же логикой, что и в консьюмерах. В отчёте — счётчики и расхождения с состоянием до пересборки
+// @description (например, правки через CRUD, которых нет в журнале). dry_run откатывает изменения.
+// @description С заголовком X-Session-ID пересобираются только проекции этой сессии.
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse "invalid admin password"
+// @Failure 500 {object} errorResponse
+// @Router  /admin/rebuild [post]
+func (s *Service) rebuildHandler(ctx *fasthttp.RequestCtx) {
+	var req rebuildRequest
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("json.Unmarshal: %w", err))
+		return
+	}
+
+	if status, err := s.checkAdminPassword(req.Password); err != nil {
+		writeError(ctx, status, err)
+		return
+	}
+
+	rebuildCtx, cancel := context.WithTimeout(ctx, resetTimeout)
+	defer cancel()
+
+	report, err := s.rebuildProjections(rebuildCtx, req.DryRun)
+	if err != nil {
+		writeError(ctx, fasthttp.StatusInternalServerError, err)
 		return
 	}
 
-	ok(ctx, "Все данные очищены")
+	writeJSON(ctx, fasthttp.StatusOK, report)
+}
+
+// rebuildProjections пересобирает проекции при остановленных консьюмерах:
+// иначе новые сообщения смешались бы с переигрываемым журналом.
+func (s *Service) rebuildProjections(ctx context.Context, dryRun bool) (report dto.RebuildReport, err error) {
+	prev, err := s.consumers.StopAll(ctx)
+	if err != nil {
+		return report, fmt.Errorf("consumers.StopAll: %w", err)
+	}
+
+	defer func() {
+		if restoreErr := s.consumers.RestoreAll(prev); restoreErr != nil && err == nil {
+			err = fmt.Errorf("consumers.RestoreAll: %w", restoreErr)
+		}
+	}()
+
+	report, err = s.projector.Rebuild(ctx, dryRun)
+	if err != nil {
+		return report, fmt.Errorf("projector.Rebuild: %w", err)
+	}
+
+	return report, nil
 }
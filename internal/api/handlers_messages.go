@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/google/uuid"
+	"github.com/valyala/fasthttp"
+)
+
+// @Summary Жизненный цикл сообщения по message_id
+// @Tags    Events
+// @Produce json
+// @Param   message_id path string true "Идентификатор события (UUID)"
+// @Success 200 {object} dto.MessageLifecycle
+// @description Собирает в одном ответе: квитанции продюсера, запись kafka_events, записи kafka_dlq,
+// @description решения консьюмера (applied, duplicate, dlq), текущий профиль и записи истории, созданные сообщением.
+// @Failure 400 {object} errorResponse "invalid value in field 'message_id'"
+// @Failure 404 {object} errorResponse "message not found"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка"
+// @Router  /messages/{message_id} [get]
+func (s *Service) getMessageLifecycle(ctx *fasthttp.RequestCtx) {
+	idStr := ctx.UserValue("message_id").(string)
+	if strings.TrimSpace(idStr) == "" {
+		writeError(ctx, fasthttp.StatusBadRequest, ErrMessageIDRequired)
+		return
+	}
+
+	messageID, err := uuid.Parse(idStr)
+	if err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("invalid value in field 'message_id'=%s", idStr))
+		return
+	}
+
+	out := dto.MessageLifecycle{MessageID: messageID}
+
+	out.Receipts, err = s.events.ListReceiptsByMessageID(ctx, messageID)
+	if err != nil {
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("events.ListReceiptsByMessageID: %w", err))
+		return
+	}
+
+	out.Event, err = s.events.GetEventByMessageID(ctx, messageID)
+	if err != nil && !errors.Is(err, dto.ErrNotFound) {
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("events.GetEventByMessageID: %w", err))
+		return
+	}
+
+	out.DLQ, err = s.events.ListDLQByMessageID(ctx, messageID)
+	if err != nil {
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("events.ListDLQByMessageID: %w", err))
+		return
+	}
+
+	out.Decisions, err = s.events.ListDecisionsByMessageID(ctx, messageID)
+	if err != nil {
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("events.ListDecisionsByMessageID: %w", err))
+		return
+	}
+
+	if len(out.Receipts) == 0 && out.Event == nil && len(out.DLQ) == 0 && len(out.Decisions) == 0 {
+		writeError(ctx, fasthttp.StatusNotFound, ErrMessageNotFound)
+		return
+	}
+
+	out.History, err = s.history.ListByMessageID(ctx, messageID)
+	if err != nil {
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("history.ListByMessageID: %w", err))
+		return
+	}
+
+	out.Status = dto.MessageStatusPending
+	if n := len(out.Decisions); n > 0 {
+		out.Status = out.Decisions[n-1].Decision
+	}
+
+	out.EmployeeID = lifecycleEmployeeID(out)
+	if out.EmployeeID != "" {
+		out.Profile, err = s.profiles.GetProfile(ctx, out.EmployeeID)
+		if err != nil && !errors.Is(err, dto.ErrNotFound) {
+			writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("profiles.GetProfile: %w", err))
+			return
+		}
+	}
+
+	writeJSON(ctx, fasthttp.StatusOK, out)
+}
+
+// lifecycleEmployeeID достаёт employee_id из payload журнала, а если события там нет — из DLQ.
+func lifecycleEmployeeID(l dto.MessageLifecycle) string {
+	payloads := make([]json.RawMessage, 0, len(l.DLQ)+1)
+	if l.Event != nil {
+		payloads = append(payloads, l.Event.Payload)
+	}
+	for _, row := range l.DLQ {
+		payloads = append(payloads, row.Payload)
+	}
+
+	for _, payload := range payloads {
+		var body struct {
+			EmployeeID string `json:"employee_id"`
+		}
+
+		if json.Unmarshal(payload, &body) == nil && strings.TrimSpace(body.EmployeeID) != "" {
+			return body.EmployeeID
+		}
+	}
+
+	return ""
+}
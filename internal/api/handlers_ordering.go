@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/valyala/fasthttp"
+)
+
+// @Summary Проверка порядка событий по сотрудникам
+// @Tags    Producer
+// @Produce json
+// @Param   topic       query string false "Топик"
+// @Param   employee_id query string false "Сотрудник"
+// @Success 200 {object} dto.OrderingReport
+// @description По kafka_events в порядке применения: события сотрудника в топике должны лежать в одной партиции (partition_split),
+// @description а offset внутри партиции — расти (offset_regression, например после retry или replay из DLQ).
+// @Failure 500 {object} errorResponse "Внутренняя ошибка"
+// @Router  /events/ordering [get]
+func (s *Service) checkEventOrdering(ctx *fasthttp.RequestCtx) {
+	topic := string(ctx.QueryArgs().Peek("topic"))
+	employeeID := string(ctx.QueryArgs().Peek("employee_id"))
+
+	events, err := s.events.ListEventsForOrdering(ctx, topic, employeeID)
+	if err != nil {
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("events.ListEventsForOrdering: %w", err))
+		return
+	}
+
+	writeJSON(ctx, fasthttp.StatusOK, checkOrdering(events))
+}
+
+// checkOrdering ищет нарушения порядка; events отсортированы по топику и порядку применения
+func checkOrdering(events []dto.KafkaEvent) dto.OrderingReport {
+	type key struct {
+		topic      string
+		employeeID string
+	}
+
+	report := dto.OrderingReport{Events: len(events), Violations: []dto.OrderingViolation{}}
+
+	partitions := make(map[key][]int)
+	last := make(map[key]map[int]dto.KafkaEvent)
+	var order []key
+
+	for _, ev := range events {
+		var payload struct {
+			EmployeeID string `json:"employee_id"`
+		}
+		_ = json.Unmarshal(ev.Payload, &payload)
+
+		k := key{topic: ev.Topic, employeeID: payload.EmployeeID}
+		if _, seen := last[k]; !seen {
+			last[k] = make(map[int]dto.KafkaEvent)
+			order = append(order, k)
+		}
+
+		if !slices.Contains(partitions[k], ev.Partition) {
+			partitions[k] = append(partitions[k], ev.Partition)
+		}
+
+		if prev, ok := last[k][ev.Partition]; ok && ev.Offset < prev.Offset {
+			report.Violations = append(report.Violations, dto.OrderingViolation{
+				Kind:          dto.OrderingOffsetRegression,
+				Topic:         ev.Topic,
+				EmployeeID:    payload.EmployeeID,
+				MessageID:     &ev.MessageID,
+				Partition:     &ev.Partition,
+				Offset:        &ev.Offset,
+				PrevMessageID: &prev.MessageID,
+				PrevOffset:    &prev.Offset,
+			})
+			continue
+		}
+
+		last[k][ev.Partition] = ev
+	}
+
+	for _, k := range order {
+		if len(partitions[k]) > 1 {
+			slices.Sort(partitions[k])
+			report.Violations = append(report.Violations, dto.OrderingViolation{
+				Kind:       dto.OrderingPartitionSplit,
+				Topic:      k.topic,
+				EmployeeID: k.employeeID,
+				Partitions: partitions[k],
+			})
+		}
+	}
+	report.Employees = len(order)
+
+	return report
+}
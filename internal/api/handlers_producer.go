@@ -1,45 +1,84 @@
 package api
 
 import (
+	"cmp"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"strings"
 
 	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 	"github.com/valyala/fasthttp"
 )
 
 // personalProduceRequest — payload для топика hr.personal
 type personalProduceRequest struct {
-	MessageID  uuid.UUID `json:"message_id" example:"6b6f9c38-3e2a-4b3d-9a9a-9f1c0f8b2a10"` // Идентификатор события (UUIDv4)
-	EmployeeID string    `json:"employee_id" example:"e-1024"`                              // Идентификатор сотрудника
-	FirstName  string    `json:"first_name" example:"Анна"`                                 // Имя
-	LastName   string    `json:"last_name" example:"Иванова"`                               // Фамилия
-	BirthDate  string    `json:"birth_date" example:"1994-06-12"`                           // Дата рождения (YYYY-MM-DD)
-	Email      string    `json:"email" example:"anna@mail.ru"`                              // Email
-	Phone      string    `json:"phone" example:"+7 916 123-45-67"`                          // Телефон
+	MessageID  uuid.UUID          `json:"message_id" example:"6b6f9c38-3e2a-4b3d-9a9a-9f1c0f8b2a10"` // Идентификатор события (UUIDv4)
+	EmployeeID string             `json:"employee_id" example:"e-1024"`                              // Идентификатор сотрудника
+	FirstName  string             `json:"first_name" example:"Анна"`                                 // Имя
+	LastName   string             `json:"last_name" example:"Иванова"`                               // Фамилия
+	BirthDate  string             `json:"birth_date" example:"1994-06-12"`                           // Дата рождения (YYYY-MM-DD)
+	Email      string             `json:"email" example:"anna@mail.ru"`                              // Email
+	Phone      string             `json:"phone" example:"+7 916 123-45-67"`                          // Телефон
+	Chaos      *dto.ProducerChaos `json:"chaos,omitempty"`                                           // Хаос для этого события (не передан — глобальный, см. /admin/producer-chaos)
 }
 
 // positionProduceRequest — payload для топика hr.positions
 type positionProduceRequest struct {
-	MessageID     uuid.UUID `json:"message_id" example:"a1d2f3c4-5678-4abc-9def-0123456789ab"` // Идентификатор события (UUIDv4)
-	EmployeeID    string    `json:"employee_id" example:"e-1024"`                              // Идентификатор сотрудника
-	Title         *string   `json:"title,omitempty" example:"Инженер по тестированию"`         // Должность
-	Department    *string   `json:"department,omitempty" example:"Отдел качества"`             // Подразделение
-	Grade         *string   `json:"grade,omitempty" example:"Middle"`                          // Грейд
-	EffectiveFrom *string   `json:"effective_from,omitempty" example:"2025-10-01"`             // Дата вступления в силу (YYYY-MM-DD)
+	MessageID     uuid.UUID          `json:"message_id" example:"a1d2f3c4-5678-4abc-9def-0123456789ab"` // Идентификатор события (UUIDv4)
+	EmployeeID    string             `json:"employee_id" example:"e-1024"`                              // Идентификатор сотрудника
+	Title         *string            `json:"title,omitempty" example:"Инженер по тестированию"`         // Должность
+	Department    *string            `json:"department,omitempty" example:"Отдел качества"`             // Подразделение
+	Grade         *string            `json:"grade,omitempty" example:"Middle"`                          // Грейд
+	EffectiveFrom *string            `json:"effective_from,omitempty" example:"2025-10-01"`             // Дата вступления в силу (YYYY-MM-DD)
+	Chaos         *dto.ProducerChaos `json:"chaos,omitempty"`                                           // Хаос для этого события (не передан — глобальный, см. /admin/producer-chaos)
 }
 
 // historyProduceRequest — payload для топика hr.history
 type historyProduceRequest struct {
-	MessageID  uuid.UUID `json:"message_id" example:"0f2eb2b1-6a25-4d2a-8a7e-2c642e00e5ed"` // Идентификатор события (UUIDv4)
-	EmployeeID string    `json:"employee_id" example:"e-1024"`                              // Идентификатор сотрудника
-	Company    string    `json:"company" example:"ООО Ромашка"`                             // Компания
-	Position   string    `json:"position,omitempty"  example:"Инженер QA"`                  // Должность (опционально)
-	PeriodFrom string    `json:"period_from" example:"2022-07-01"`                          // Начало периода (YYYY-MM-DD)
-	PeriodTo   string    `json:"period_to" example:"2025-09-30"`                            // Окончание периода (YYYY-MM-DD)
-	Stack      []string  `json:"stack" example:"Python,Pytest,PostgreSQL"`                  // Стек (список строк)
+	MessageID  uuid.UUID          `json:"message_id" example:"0f2eb2b1-6a25-4d2a-8a7e-2c642e00e5ed"` // Идентификатор события (UUIDv4)
+	EmployeeID string             `json:"employee_id" example:"e-1024"`                              // Идентификатор сотрудника
+	Company    string             `json:"company" example:"ООО Ромашка"`                             // Компания
+	Position   string             `json:"position,omitempty"  example:"Инженер QA"`                  // Должность (опционально)
+	PeriodFrom string             `json:"period_from" example:"2022-07-01"`                          // Начало периода (YYYY-MM-DD)
+	PeriodTo   string             `json:"period_to" example:"2025-09-30"`                            // Окончание периода (YYYY-MM-DD)
+	Stack      []string           `json:"stack" example:"Python,Pytest,PostgreSQL"`                  // Стек (список строк)
+	Chaos      *dto.ProducerChaos `json:"chaos,omitempty"`                                           // Хаос для этого события (не передан — глобальный, см. /admin/producer-chaos)
+}
+
+// batchProduceRequest — серия событий для /producer/batch
+type batchProduceRequest struct {
+	Shuffle  bool               `json:"shuffle,omitempty" example:"true"` // Перемешать сообщения перед отправкой
+	Chaos    *dto.ProducerChaos `json:"chaos,omitempty"`                  // Хаос для сообщений без собственного chaos
+	Messages []batchProduceItem `json:"messages"`                         // Сообщения в порядке отправки (без shuffle)
+}
+
+// batchProduceItem — одно событие серии: заполняется ровно одно поле
+type batchProduceItem struct {
+	Personal *personalProduceRequest `json:"personal,omitempty"` // Событие hr.personal
+	Position *positionProduceRequest `json:"position,omitempty"` // Событие hr.positions
+	History  *historyProduceRequest  `json:"history,omitempty"`  // Событие hr.history
+}
+
+// producerChaosRequest — пароль и глобальный хаос продюсера
+type producerChaosRequest struct {
+	Password string `json:"password"` // пароль
+	dto.ProducerChaos
+}
+
+// rawProduceRequest — произвольное сообщение, публикуемое без валидации
+type rawProduceRequest struct {
+	Topic        string            `json:"topic" example:"hr.personal"`                                // Топик назначения
+	Key          *string           `json:"key,omitempty" example:"not-a-uuid"`                         // Ключ (не передан — сообщение без ключа)
+	Partition    *int32            `json:"partition,omitempty" example:"0"`                            // Явная партиция (не передана — по ключу)
+	Headers      map[string]string `json:"headers,omitempty"`                                          // Заголовки сообщения
+	Body         string            `json:"body" example:"{\"employee_id\":\"e-1024\"}"`                // Тело сообщения
+	BodyEncoding string            `json:"body_encoding,omitempty" example:"text" enums:"text,base64"` // Кодировка body: text (по умолчанию) или base64
 }
 
 // @Summary Публикация события в hr.personal
@@ -47,7 +86,7 @@ type historyProduceRequest struct {
 // @Accept  json
 // @Produce json
 // @Param   request body personalProduceRequest true "payload"
-// @Success 200 {object} okResponse
+// @Success 200 {object} dto.ProduceReceipt
 // @Failure 400 {object} errorResponse "Отсутствует message_id/employee_id"
 // @description Ошибки валидации консьюмера:
 // @description - required: employee_id, first_name, birth_date, email, phone
@@ -63,31 +102,20 @@ func (s *Service) producerPersonal(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	if req.MessageID == uuid.Nil {
-		writeError(ctx, fasthttp.StatusBadRequest, ErrMessageIDRequired)
+	if err := checkProduceRequest(req.MessageID, req.EmployeeID, req.Chaos); err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, err)
 		return
 	}
 
-	if strings.TrimSpace(req.EmployeeID) == "" {
-		writeError(ctx, fasthttp.StatusBadRequest, ErrEmployeeIDRequired)
-		return
-	}
-
-	employee := dto.EmployeeProfile{
-		EmployeeID: req.EmployeeID,
-		FirstName:  req.FirstName,
-		LastName:   req.LastName,
-		BirthDate:  req.BirthDate,
-		Email:      req.Email,
-		Phone:      req.Phone,
-	}
-
-	if err := s.producer.ProducePersonal(ctx, req.MessageID, employee); err != nil {
+	receipt, err := s.producer.ProducePersonal(ctx, req.MessageID, req.profile(), req.Chaos)
+	if err != nil {
 		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("producer.ProducePersonal: %w", err))
 		return
 	}
 
-	ok(ctx, "Событие отправлено в hr.personal")
+	s.saveReceipt(ctx, receipt)
+
+	writeJSON(ctx, fasthttp.StatusOK, receipt)
 }
 
 // @Summary Публикация события в hr.positions
@@ -95,7 +123,7 @@ func (s *Service) producerPersonal(ctx *fasthttp.RequestCtx) {
 // @Accept  json
 // @Produce json
 // @Param   request body positionProduceRequest true "payload"
-// @Success 200 {object} okResponse
+// @Success 200 {object} dto.ProduceReceipt
 // @Failure 400 {object} errorResponse "Отсутствует message_id/employee_id
 // @description Ошибки валидации консьюмера:
 // @description - required: title, department, grade, effective_from
@@ -112,30 +140,20 @@ func (s *Service) producerPosition(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	if req.MessageID == uuid.Nil {
-		writeError(ctx, fasthttp.StatusBadRequest, ErrMessageIDRequired)
-		return
-	}
-
-	if strings.TrimSpace(req.EmployeeID) == "" {
-		writeError(ctx, fasthttp.StatusBadRequest, ErrEmployeeIDRequired)
+	if err := checkProduceRequest(req.MessageID, req.EmployeeID, req.Chaos); err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, err)
 		return
 	}
 
-	employee := dto.EmployeeProfile{
-		EmployeeID:    req.EmployeeID,
-		Title:         req.Title,
-		Department:    req.Department,
-		Grade:         req.Grade,
-		EffectiveFrom: req.EffectiveFrom,
-	}
-
-	if err := s.producer.ProducePosition(ctx, req.MessageID, employee); err != nil {
+	receipt, err := s.producer.ProducePosition(ctx, req.MessageID, req.profile(), req.Chaos)
+	if err != nil {
 		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("producer.ProducePosition: %w", err))
 		return
 	}
 
-	ok(ctx, "Событие отправлено в hr.positions")
+	s.saveReceipt(ctx, receipt)
+
+	writeJSON(ctx, fasthttp.StatusOK, receipt)
 }
 
 // @Summary Публикация события в hr.history
@@ -143,6 +161,7 @@ func (s *Service) producerPosition(ctx *fasthttp.RequestCtx) {
 // @Accept  json
 // @Produce json
 // @Param   request body historyProduceRequest true "payload"
+// @Success 200 {object} dto.ProduceReceipt
 // @Failure 400 {object} errorResponse "Отсутствует message_id/employee_id"
 // @description Ошибки валидации консьюмера:
 // @description - required: employee_id, company, period_from, period_to
@@ -159,35 +178,303 @@ func (s *Service) producerHistory(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	if req.MessageID == uuid.Nil {
-		writeError(ctx, fasthttp.StatusBadRequest, ErrMessageIDRequired)
+	if err := checkProduceRequest(req.MessageID, req.EmployeeID, req.Chaos); err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, err)
 		return
 	}
 
-	if strings.TrimSpace(req.EmployeeID) == "" {
-		writeError(ctx, fasthttp.StatusBadRequest, ErrEmployeeIDRequired)
+	receipt, err := s.producer.ProduceHistory(ctx, req.MessageID, req.history(), req.Chaos)
+	if err != nil {
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("producer.ProduceHistory: %w", err))
 		return
 	}
 
-	if req.Stack == nil {
-		req.Stack = []string{}
+	s.saveReceipt(ctx, receipt)
+
+	writeJSON(ctx, fasthttp.StatusOK, receipt)
+}
+
+// maxBatchMessages — ограничение размера серии /producer/batch
+const maxBatchMessages = 1000
+
+// @Summary Публикация серии событий
+// @Tags    Producer
+// @Accept  json
+// @Produce json
+// @Param   request body batchProduceRequest true "payload"
+// @Success 200 {array} dto.ProduceReceipt
+// @description События отправляются последовательно; shuffle перемешивает серию (в chaos квитанции — "shuffle i->j").
+// @description chaos события важнее chaos серии, а тот — глобального (/admin/producer-chaos).
+// @Failure 400 {object} errorResponse "messages[i]: ошибка валидации"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка (уже отправленные квитанции сохранены)"
+// @Router  /producer/batch [post]
+func (s *Service) producerBatch(ctx *fasthttp.RequestCtx) {
+	var req batchProduceRequest
+
+	err := json.Unmarshal(ctx.PostBody(), &req)
+	if err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("json.Unmarshal: %w", err))
+		return
+	}
+
+	if len(req.Messages) == 0 || len(req.Messages) > maxBatchMessages {
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("invalid value in field 'messages': expected 1..%d items", maxBatchMessages))
+		return
+	}
+
+	if req.Chaos != nil {
+		if msg := validateChaos(*req.Chaos); msg != "" {
+			writeError(ctx, fasthttp.StatusBadRequest, errors.New(msg))
+			return
+		}
+	}
+
+	for i, item := range req.Messages {
+		if err := item.check(); err != nil {
+			writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("messages[%d]: %w", i, err))
+			return
+		}
+	}
+
+	order := make([]int, len(req.Messages))
+	for i := range order {
+		order[i] = i
+	}
+	if req.Shuffle {
+		rand.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+	}
+
+	receipts := make([]dto.ProduceReceipt, 0, len(order))
+	for pos, i := range order {
+		receipt, err := s.produceItem(ctx, req.Messages[i], req.Chaos)
+		if err != nil {
+			writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("messages[%d]: %w", i, err))
+			return
+		}
+
+		if req.Shuffle {
+			receipt.Chaos = append(receipt.Chaos, fmt.Sprintf("shuffle %d->%d", i, pos))
+		}
+
+		s.saveReceipt(ctx, receipt)
+		receipts = append(receipts, receipt)
+	}
+
+	writeJSON(ctx, fasthttp.StatusOK, receipts)
+}
+
+// check — проверка события серии: ровно одно событие и его обязательные поля
+func (item batchProduceItem) check() error {
+	switch {
+	case item.Personal != nil && item.Position == nil && item.History == nil:
+		return checkProduceRequest(item.Personal.MessageID, item.Personal.EmployeeID, item.Personal.Chaos)
+	case item.Personal == nil && item.Position != nil && item.History == nil:
+		return checkProduceRequest(item.Position.MessageID, item.Position.EmployeeID, item.Position.Chaos)
+	case item.Personal == nil && item.Position == nil && item.History != nil:
+		return checkProduceRequest(item.History.MessageID, item.History.EmployeeID, item.History.Chaos)
+	default:
+		return errors.New("expected exactly one of 'personal', 'position', 'history'")
+	}
+}
+
+// produceItem отправляет событие серии; chaos серии действует, если у события нет своего
+func (s *Service) produceItem(ctx context.Context, item batchProduceItem, chaos *dto.ProducerChaos) (dto.ProduceReceipt, error) {
+	switch {
+	case item.Personal != nil:
+		receipt, err := s.producer.ProducePersonal(ctx, item.Personal.MessageID, item.Personal.profile(), cmp.Or(item.Personal.Chaos, chaos))
+		if err != nil {
+			return dto.ProduceReceipt{}, fmt.Errorf("producer.ProducePersonal: %w", err)
+		}
+
+		return receipt, nil
+	case item.Position != nil:
+		receipt, err := s.producer.ProducePosition(ctx, item.Position.MessageID, item.Position.profile(), cmp.Or(item.Position.Chaos, chaos))
+		if err != nil {
+			return dto.ProduceReceipt{}, fmt.Errorf("producer.ProducePosition: %w", err)
+		}
+
+		return receipt, nil
+	default:
+		receipt, err := s.producer.ProduceHistory(ctx, item.History.MessageID, item.History.history(), cmp.Or(item.History.Chaos, chaos))
+		if err != nil {
+			return dto.ProduceReceipt{}, fmt.Errorf("producer.ProduceHistory: %w", err)
+		}
+
+		return receipt, nil
+	}
+}
+
+// checkProduceRequest — общие проверки событий продюсера
+func checkProduceRequest(messageID uuid.UUID, employeeID string, chaos *dto.ProducerChaos) error {
+	if messageID == uuid.Nil {
+		return ErrMessageIDRequired
+	}
+
+	if strings.TrimSpace(employeeID) == "" {
+		return ErrEmployeeIDRequired
 	}
 
-	history := dto.EmploymentHistory{
+	if chaos != nil {
+		if msg := validateChaos(*chaos); msg != "" {
+			return errors.New(msg)
+		}
+	}
+
+	return nil
+}
+
+func (req personalProduceRequest) profile() dto.EmployeeProfile {
+	return dto.EmployeeProfile{
+		EmployeeID: req.EmployeeID,
+		FirstName:  req.FirstName,
+		LastName:   req.LastName,
+		BirthDate:  req.BirthDate,
+		Email:      req.Email,
+		Phone:      req.Phone,
+	}
+}
+
+func (req positionProduceRequest) profile() dto.EmployeeProfile {
+	return dto.EmployeeProfile{
+		EmployeeID:    req.EmployeeID,
+		Title:         req.Title,
+		Department:    req.Department,
+		Grade:         req.Grade,
+		EffectiveFrom: req.EffectiveFrom,
+	}
+}
+
+func (req historyProduceRequest) history() dto.EmploymentHistory {
+	stack := req.Stack
+	if stack == nil {
+		stack = []string{}
+	}
+
+	return dto.EmploymentHistory{
 		EmployeeID: req.EmployeeID,
 		Company:    req.Company,
 		Position:   req.Position,
 		PeriodFrom: req.PeriodFrom,
 		PeriodTo:   req.PeriodTo,
-		Stack:      req.Stack,
+		Stack:      stack,
 	}
+}
 
-	if err := s.producer.ProduceHistory(ctx, req.MessageID, history); err != nil {
-		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("producer.ProducePosition: %w", err))
+// @Summary Текущий глобальный хаос продюсера
+// @Tags    Producer
+// @Produce json
+// @Success 200 {object} dto.ProducerChaos
+// @Router  /admin/producer-chaos [get]
+func (s *Service) getProducerChaos(ctx *fasthttp.RequestCtx) {
+	writeJSON(ctx, fasthttp.StatusOK, s.producer.Chaos())
+}
+
+// @Summary Смена глобального хаоса продюсера
+// @Tags    Producer
+// @Accept  json
+// @Produce json
+// @Param   request body producerChaosRequest true "Пароль и хаос (без полей хаоса — выключить)"
+// @Success 200 {object} dto.ProducerChaos
+// @description Действует на /producer/personal, /producer/position, /producer/history и /producer/batch,
+// @description если в запросе не передан собственный chaos. /producer/raw и повтор DLQ отправляются без хаоса.
+// @Failure 400 {object} errorResponse "invalid value in field 'chaos.*'"
+// @Failure 401 {object} errorResponse "invalid admin password"
+// @Router  /admin/producer-chaos [put]
+func (s *Service) setProducerChaos(ctx *fasthttp.RequestCtx) {
+	var req producerChaosRequest
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("json.Unmarshal: %w", err))
 		return
 	}
 
-	ok(ctx, "Событие отправлено в hr.history")
+	if status, err := s.checkAdminPassword(req.Password); err != nil {
+		writeError(ctx, status, err)
+		return
+	}
+
+	if msg := validateChaos(req.ProducerChaos); msg != "" {
+		writeError(ctx, fasthttp.StatusBadRequest, errors.New(msg))
+		return
+	}
+
+	s.producer.SetChaos(req.ProducerChaos)
+
+	writeJSON(ctx, fasthttp.StatusOK, s.producer.Chaos())
+}
+
+// @Summary Публикация произвольного сообщения (битый JSON, любой ключ, заголовки)
+// @Tags    Producer
+// @Accept  json
+// @Produce json
+// @Param   request body rawProduceRequest true "payload"
+// @Success 200 {object} dto.ProduceReceipt
+// @Failure 400 {object} errorResponse "Отсутствует topic / неверная кодировка body / отрицательная партиция"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка"
+// @Router  /producer/raw [post]
+func (s *Service) producerRaw(ctx *fasthttp.RequestCtx) {
+	var req rawProduceRequest
+
+	err := json.Unmarshal(ctx.PostBody(), &req)
+	if err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("json.Unmarshal: %w", err))
+		return
+	}
+
+	if strings.TrimSpace(req.Topic) == "" {
+		writeError(ctx, fasthttp.StatusBadRequest, ErrTopicRequired)
+		return
+	}
+
+	if req.Partition != nil && *req.Partition < 0 {
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("invalid value in field 'partition'=%d", *req.Partition))
+		return
+	}
+
+	var body []byte
+	switch req.BodyEncoding {
+	case "", "text":
+		body = []byte(req.Body)
+	case "base64":
+		body, err = base64.StdEncoding.DecodeString(req.Body)
+		if err != nil {
+			writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("base64.DecodeString: %w", err))
+			return
+		}
+	default:
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("invalid value in field 'body_encoding'=%s", req.BodyEncoding))
+		return
+	}
+
+	receipt, err := s.producer.ProduceRaw(ctx, dto.RawMessage{
+		Topic:     req.Topic,
+		Key:       req.Key,
+		Partition: req.Partition,
+		Headers:   req.Headers,
+		Body:      body,
+	})
+	if err != nil {
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("producer.ProduceRaw: %w", err))
+		return
+	}
+
+	s.saveReceipt(ctx, receipt)
+
+	writeJSON(ctx, fasthttp.StatusOK, receipt)
+}
+
+// saveReceipt запоминает квитанцию (и повторные отправки chaos.duplicate) для
+// GET /messages/{message_id}. Сообщение уже в Kafka, поэтому ошибка записи только логируется.
+func (s *Service) saveReceipt(ctx context.Context, receipt dto.ProduceReceipt) {
+	for _, r := range append([]dto.ProduceReceipt{receipt}, receipt.Copies...) {
+		if err := s.events.InsertReceipt(ctx, r); err != nil {
+			log.Warn().
+				Err(err).
+				Str("topic", r.Topic).
+				Int32("partition", r.Partition).
+				Int64("offset", r.Offset).
+				Msg("failed to save produce receipt")
+		}
+	}
 }
 
 // @Summary Сырые события (эмуляция kafka_events)
@@ -209,11 +496,29 @@ func (s *Service) listEvents(ctx *fasthttp.RequestCtx) {
 // @Summary Сообщения DLQ
 // @Tags    Producer
 // @Produce json
+// @Param   topic query string false "Исходный топик"
+// @Param   error query string false "Подстрока причины"
+// @Param   from  query string false "received_at не раньше (RFC3339)"
+// @Param   to    query string false "received_at не позже (RFC3339)"
 // @Success 200 {array} dto.KafkaDLQ
+// @Failure 400 {object} errorResponse "invalid value in field 'from'/'to'"
 // @Failure 500 {string} string "Внутренняя ошибка"
 // @Router  /dlq [get]
 func (s *Service) listDLQ(ctx *fasthttp.RequestCtx) {
-	rows, err := s.events.ListDLQ(ctx)
+	args := ctx.QueryArgs()
+	filter := dto.DLQFilter{
+		Topic:         string(args.Peek("topic")),
+		ErrorContains: string(args.Peek("error")),
+		From:          string(args.Peek("from")),
+		To:            string(args.Peek("to")),
+	}
+
+	if msg := validateDLQFilter(filter); msg != "" {
+		writeError(ctx, fasthttp.StatusBadRequest, errors.New(msg))
+		return
+	}
+
+	rows, err := s.events.ListDLQ(ctx, filter)
 	if err != nil {
 		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("events.ListDLQ: %w", err))
 		return
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/valyala/fasthttp"
+)
+
+// @Summary Встроенные QA-сценарии
+// @Tags    Scenarios
+// @Produce json
+// @Success 200 {array} dto.ScenarioInfo
+// @Router  /scenarios [get]
+func (s *Service) listScenarios(ctx *fasthttp.RequestCtx) {
+	writeJSON(ctx, fasthttp.StatusOK, s.scenarios.List())
+}
+
+// @Summary Прогон встроенного сценария
+// @Tags    Scenarios
+// @Produce json
+// @Param   name path string true "Имя сценария (basic-flow, ordering, idempotency, errors, lag)"
+// @Success 200 {object} dto.ScenarioReport
+// @description Шаги выполняются по порядку до первой ошибки, остальные — skipped. Консьюмеры после прогона
+// @description возвращаются в прежнее состояние. Упавший сценарий — тоже 200, см. passed.
+// @description Консьюмеры общие для всего стенда, поэтому сценарий с шагами pause/resume/stop/start_consumer (lag)
+// @description отклоняется, пока есть сессии стажёров.
+// @Failure 404 {object} errorResponse "scenario not found"
+// @Failure 409 {object} errorResponse "another scenario is running / consumer steps are not available while trainee sessions exist"
+// @Router  /scenarios/{name}/run [post]
+func (s *Service) runScenario(ctx *fasthttp.RequestCtx) {
+	name, _ := ctx.UserValue("name").(string)
+
+	report, err := s.scenarios.RunBuiltin(ctx, name)
+	switch {
+	case errors.Is(err, dto.ErrNotFound):
+		writeError(ctx, fasthttp.StatusNotFound, err)
+	case errors.Is(err, dto.ErrInvalidState):
+		writeError(ctx, fasthttp.StatusConflict, err)
+	case err != nil:
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("scenarios.RunBuiltin: %w", err))
+	default:
+		writeJSON(ctx, fasthttp.StatusOK, report)
+	}
+}
+
+// @Summary Прогон сценария из YAML
+// @Tags    Scenarios
+// @Accept  plain
+// @Produce json
+// @Param   request body string true "YAML сценария (формат — GET /scenarios, поле source)"
+// @Success 200 {object} dto.ScenarioReport
+// @description Шаги: produce (personal / position / history / raw), sleep, pause_consumer, resume_consumer, stop_consumer,
+// @description start_consumer, wait_until (timeout + одна проверка), assert_profile, assert_dlq_reason, assert_event_count, assert_decision.
+// @description ${run} — идентификатор прогона, ${uuid.<имя>} — UUID, общий для имени в пределах прогона.
+// @description Шаги управления консьюмерами действуют на весь стенд и отклоняются, пока есть сессии стажёров.
+// @Failure 400 {object} errorResponse "Сценарий не разобран"
+// @Failure 409 {object} errorResponse "another scenario is running / consumer steps are not available while trainee sessions exist"
+// @Router  /scenarios/run [post]
+func (s *Service) runScenarioYAML(ctx *fasthttp.RequestCtx) {
+	report, err := s.scenarios.RunYAML(ctx, ctx.PostBody())
+	switch {
+	case errors.Is(err, dto.ErrInvalidState):
+		writeError(ctx, fasthttp.StatusConflict, err)
+	case err != nil:
+		writeError(ctx, fasthttp.StatusBadRequest, err)
+	default:
+		writeJSON(ctx, fasthttp.StatusOK, report)
+	}
+}
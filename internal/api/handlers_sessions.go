@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/google/uuid"
+	"github.com/valyala/fasthttp"
+)
+
+// maxSessionName — ограничение длины имени сессии
+const maxSessionName = 100
+
+type createSessionRequest struct {
+	Name string `json:"name" example:"anna"` // Имя сессии (например, имя стажёра)
+}
+
+// @Summary Создать сессию стажёра
+// @Tags    Sessions
+// @Param   request body createSessionRequest true "Имя сессии"
+// @Success 201 {object} dto.Session
+// @description Сессия — отдельное пространство стенда: запросы с заголовком X-Session-ID: <id> видят и меняют только
+// @description свои профили, историю, журнал, DLQ и квитанции, события продюсера уходят в Kafka с заголовком session-id,
+// @description и консьюмеры применяют их в пространство той же сессии. Один employee_id в разных сессиях — разные сотрудники.
+// @Failure 400 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Router  /sessions [post]
+func (s *Service) createSession(ctx *fasthttp.RequestCtx) {
+	var req createSessionRequest
+	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("json.Unmarshal: %w", err))
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		writeError(ctx, fasthttp.StatusBadRequest, errors.New("required field 'name'"))
+		return
+	}
+	if utf8.RuneCountInString(req.Name) > maxSessionName {
+		writeError(ctx, fasthttp.StatusBadRequest, fmt.Errorf("field 'name' is longer than %d characters", maxSessionName))
+		return
+	}
+
+	session, err := s.sessions.Create(ctx, dto.Session{ID: uuid.NewString(), Name: req.Name})
+	if err != nil {
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("sessionRepository.Create: %w", err))
+		return
+	}
+
+	writeJSON(ctx, fasthttp.StatusCreated, session)
+}
+
+// @Summary Список сессий стажёров
+// @Tags    Sessions
+// @Produce json
+// @Success 200 {array} dto.Session
+// @Failure 500 {object} errorResponse
+// @Router  /sessions [get]
+func (s *Service) listSessions(ctx *fasthttp.RequestCtx) {
+	sessions, err := s.sessions.List(ctx)
+	if err != nil {
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("sessionRepository.List: %w", err))
+		return
+	}
+
+	writeJSON(ctx, fasthttp.StatusOK, sessions)
+}
+
+// @Summary Удалить сессию стажёра
+// @Tags    Sessions
+// @Param   id path string true "Идентификатор сессии"
+// @Success 200 {object} okResponse
+// @description Сессия удаляется вместе с её данными: профилями, историей, журналом, DLQ, квитанциями и решениями
+// @description консьюмеров. Сообщения сессии, ещё не прочитанные из Kafka, после удаления не видны ни в одной сессии.
+// @Failure 404 {object} errorResponse "session not found"
+// @Failure 500 {object} errorResponse
+// @Router  /sessions/{id} [delete]
+func (s *Service) deleteSession(ctx *fasthttp.RequestCtx) {
+	id, _ := ctx.UserValue("id").(string)
+
+	if err := s.sessions.Delete(ctx, id); err != nil {
+		if errors.Is(err, dto.ErrNotFound) {
+			writeError(ctx, fasthttp.StatusNotFound, ErrSessionNotFound)
+			return
+		}
+
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("sessionRepository.Delete: %w", err))
+		return
+	}
+
+	if err := s.events.ResetSession(dto.WithSession(ctx, id)); err != nil {
+		writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("events.ResetSession: %w", err))
+		return
+	}
+
+	ok(ctx, "Сессия удалена")
+}
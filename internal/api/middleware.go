@@ -2,10 +2,14 @@ package api
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"runtime"
 	"runtime/debug"
+	"strings"
 	"time"
 
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"github.com/valyala/fasthttp"
@@ -76,7 +80,7 @@ func CORS(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 	return func(ctx *fasthttp.RequestCtx) {
 		ctx.Response.Header.Set("Access-Control-Allow-Origin", "*")
 		ctx.Response.Header.Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
-		ctx.Response.Header.Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		ctx.Response.Header.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+SessionHeader+", "+AdminPasswordHeader)
 
 		if string(ctx.Method()) == "OPTIONS" {
 			ctx.SetStatusCode(fasthttp.StatusNoContent)
@@ -86,3 +90,33 @@ func CORS(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 		next(ctx)
 	}
 }
+
+// SessionHeader — заголовок запроса с идентификатором сессии стажёра
+const SessionHeader = "X-Session-ID"
+
+// SessionScope кладёт сессию из X-Session-ID в контекст запроса: репозитории и продюсер
+// работают в её пространстве. Без заголовка запрос выполняется в общей сессии;
+// неизвестная сессия — 404, чтобы опечатка не писала данные в чужое пространство.
+func (s *Service) SessionScope(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		id := strings.TrimSpace(string(ctx.Request.Header.Peek(SessionHeader)))
+		if id == "" {
+			next(ctx)
+			return
+		}
+
+		if _, err := s.sessions.Get(ctx, id); err != nil {
+			if errors.Is(err, dto.ErrNotFound) {
+				writeError(ctx, fasthttp.StatusNotFound, fmt.Errorf("%w: '%s'", ErrSessionNotFound, id))
+				return
+			}
+
+			writeError(ctx, fasthttp.StatusInternalServerError, fmt.Errorf("sessionRepository.Get: %w", err))
+			return
+		}
+
+		ctx.SetUserValue(dto.SessionKey, id)
+
+		next(ctx)
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/Artexxx/HR-Kafka-QA/internal/repository/memory"
+	"github.com/valyala/fasthttp"
+)
+
+const testProfile = `{"employee_id": "e-1", "first_name": "Анна", "last_name": "Иванова", "birth_date": "1994-06-12",
+	"email": "anna@mail.ru", "phone": "+79160000000", "title": "QA", "department": "Отдел качества", "grade": "Junior", "effective_from": "2024-01-01"}`
+
+// TestUpdateProfilePosition — правка должности через CRUD видна в GET, пока у сотрудника
+// нет назначений из hr.positions; после первого назначения она отклоняется с 409.
+func TestUpdateProfilePosition(t *testing.T) {
+	store := memory.NewStore()
+	profiles := memory.NewProfileRepository(store)
+
+	s := newTestService()
+	s.profiles = profiles
+
+	if ctx := serve(s, fasthttp.MethodPost, "/profiles", testProfile); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("create status = %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	update := `{"first_name": "Анна", "last_name": "Петрова", "birth_date": "1994-06-12", "email": "anna@mail.ru", "phone": "+79160000000", "title": "Senior QA"}`
+	if ctx := serve(s, fasthttp.MethodPut, "/profiles/e-1", update); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("update status = %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	assertProfile(t, s, "Петрова", "Senior QA")
+
+	tx, err := store.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	title, department, grade := "Lead QA", "Отдел качества", "Lead"
+	assignment := dto.PositionAssignment{EmployeeID: "e-1", Title: &title, Department: &department, Grade: &grade, EffectiveFrom: "2024-06-01"}
+	if err := profiles.InsertAssignmentTx(context.Background(), tx, assignment); err != nil {
+		t.Fatalf("InsertAssignmentTx: %v", err)
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if ctx := serve(s, fasthttp.MethodPut, "/profiles/e-1", update); ctx.Response.StatusCode() != fasthttp.StatusConflict {
+		t.Fatalf("update position status = %d, want %d: %s", ctx.Response.StatusCode(), fasthttp.StatusConflict, ctx.Response.Body())
+	}
+	assertProfile(t, s, "Петрова", "Lead QA")
+
+	personal := `{"first_name": "Анна", "last_name": "Смирнова", "birth_date": "1994-06-12", "email": "anna@mail.ru", "phone": "+79160000000"}`
+	if ctx := serve(s, fasthttp.MethodPut, "/profiles/e-1", personal); ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("update personal status = %d: %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+	assertProfile(t, s, "Смирнова", "Lead QA")
+}
+
+func assertProfile(t *testing.T, s *Service, lastName, title string) {
+	t.Helper()
+
+	ctx := serve(s, fasthttp.MethodGet, "/profiles/e-1", "")
+	if status := ctx.Response.StatusCode(); status != fasthttp.StatusOK {
+		t.Fatalf("get status = %d: %s", status, ctx.Response.Body())
+	}
+
+	var got dto.EmployeeProfile
+	if err := json.Unmarshal(ctx.Response.Body(), &got); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+
+	if got.LastName != lastName || got.Title == nil || *got.Title != title {
+		t.Errorf("profile = last_name %q title %v, want %q %q", got.LastName, got.Title, lastName, title)
+	}
+}
@@ -0,0 +1,254 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/Artexxx/HR-Kafka-QA/internal/exchange/consumer"
+	"github.com/Artexxx/HR-Kafka-QA/internal/exchange/memkafka"
+	"github.com/Artexxx/HR-Kafka-QA/internal/exchange/producer"
+	"github.com/Artexxx/HR-Kafka-QA/internal/repository/memory"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	testTopic   = "hr.personal"
+	testGroupID = "consumer_personal"
+	waitTimeout = 5 * time.Second
+)
+
+// resetStand — стенд на брокере в памяти: консьюмер personal, продюсер и сервис
+type resetStand struct {
+	service   *Service
+	broker    *memkafka.Broker
+	producer  *producer.HRProducer
+	consumers *consumer.Registry
+	profiles  *memory.ProfileRepository
+}
+
+// TestResetRecreateTopics — после пересоздания топиков offset группы стоят в начале
+// новых партиций, консьюмер возвращается и читает сообщения, отправленные после сброса.
+func TestResetRecreateTopics(t *testing.T) {
+	st := newResetStand(t)
+
+	st.produce(t, "E-0001", "E-0002", "E-0003")
+	st.waitApplied(t, "E-0001", "E-0002", "E-0003")
+	st.waitLag(t, 0)
+
+	report := st.reset(t, `{"password": "`+testAdminPassword+`", "recreate_topics": true}`)
+
+	if len(report.Topics) != 1 || !report.Topics[0].Existed || report.Topics[0].Partitions != 2 {
+		t.Errorf("topics = %+v, want %s recreated with 2 partitions", report.Topics, testTopic)
+	}
+	if !report.Database {
+		t.Error("database was not reset")
+	}
+	assertResetOffsets(t, report, map[int32]int64{0: 0, 1: 0})
+	assertConsumerState(t, report, dto.ConsumerStateRunning)
+
+	if _, err := st.profiles.GetProfile(context.Background(), "E-0001"); !errors.Is(err, dto.ErrNotFound) {
+		t.Errorf("GetProfile after reset: err = %v, want %v", err, dto.ErrNotFound)
+	}
+
+	st.produce(t, "E-0004")
+	st.waitApplied(t, "E-0004")
+	st.waitLag(t, 0)
+}
+
+// TestResetOffsetsLatest — reset_offsets=latest пропускает непрочитанные сообщения:
+// offset группы переставляются вперёд, в том числе для партиций без коммитов.
+func TestResetOffsetsLatest(t *testing.T) {
+	st := newResetStand(t)
+
+	if _, err := st.consumers.StopConsumer(context.Background(), "personal"); err != nil {
+		t.Fatalf("StopConsumer: %v", err)
+	}
+
+	st.produce(t, "E-0001", "E-0002", "E-0003", "E-0004")
+
+	report := st.reset(t, `{"password": "`+testAdminPassword+`", "reset_offsets": "latest"}`)
+
+	if len(report.Topics) != 0 {
+		t.Errorf("topics = %+v, want none recreated", report.Topics)
+	}
+	assertResetOffsets(t, report, st.highWaterMarks(t))
+	assertConsumerState(t, report, dto.ConsumerStateStopped)
+
+	if _, err := st.consumers.StartConsumer("personal"); err != nil {
+		t.Fatalf("StartConsumer: %v", err)
+	}
+
+	st.produce(t, "E-0005")
+	st.waitApplied(t, "E-0005")
+	st.waitLag(t, 0)
+
+	for _, id := range []string{"E-0001", "E-0002", "E-0003", "E-0004"} {
+		if _, err := st.profiles.GetProfile(context.Background(), id); !errors.Is(err, dto.ErrNotFound) {
+			t.Errorf("GetProfile %s: err = %v, want %v: skipped message was applied", id, err, dto.ErrNotFound)
+		}
+	}
+}
+
+func newResetStand(t *testing.T) *resetStand {
+	t.Helper()
+
+	topics := []dto.TopicSpec{{Name: testTopic, Partitions: 2}}
+
+	broker := memkafka.NewBroker(producer.NewPartitioner)
+	if _, err := broker.EnsureTopics(context.Background(), topics); err != nil {
+		t.Fatalf("EnsureTopics: %v", err)
+	}
+
+	store := memory.NewStore()
+	events := memory.NewEventsRepository(store)
+	profiles := memory.NewProfileRepository(store)
+
+	runner := consumer.NewPersonalRunner("", testTopic, testGroupID, events, profiles, zerolog.Nop(),
+		consumer.WithGroupFactory(broker.NewConsumerGroup))
+	consumers := consumer.NewRegistry(runner)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- runner.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	service := newTestService()
+	service.events = events
+	service.profiles = profiles
+	service.consumers = consumers
+	service.admin = broker
+	service.topics = topics
+
+	return &resetStand{
+		service:   service,
+		broker:    broker,
+		producer:  producer.NewHRProducer(broker.SyncProducer(), producer.Config{TopicPersonal: testTopic, KeyMode: producer.KeyModeEmployeeID}, zerolog.Nop()),
+		consumers: consumers,
+		profiles:  profiles,
+	}
+}
+
+func (st *resetStand) produce(t *testing.T, employeeIDs ...string) {
+	t.Helper()
+
+	for _, id := range employeeIDs {
+		profile := dto.EmployeeProfile{
+			EmployeeID: id,
+			FirstName:  "Иван",
+			LastName:   "Петров",
+			BirthDate:  "1990-01-01",
+			Email:      "ivan@example.com",
+			Phone:      "+79990000000",
+		}
+		if _, err := st.producer.ProducePersonal(context.Background(), uuid.New(), profile, nil); err != nil {
+			t.Fatalf("ProducePersonal %s: %v", id, err)
+		}
+	}
+}
+
+func (st *resetStand) reset(t *testing.T, body string) dto.ResetReport {
+	t.Helper()
+
+	ctx := serve(st.service, fasthttp.MethodPost, "/admin/reset", body)
+	if status := ctx.Response.StatusCode(); status != fasthttp.StatusOK {
+		t.Fatalf("reset status = %d: %s", status, ctx.Response.Body())
+	}
+
+	var report dto.ResetReport
+	if err := json.Unmarshal(ctx.Response.Body(), &report); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+
+	return report
+}
+
+func (st *resetStand) waitApplied(t *testing.T, employeeIDs ...string) {
+	t.Helper()
+
+	for _, id := range employeeIDs {
+		waitFor(t, "profile "+id, func() error {
+			_, err := st.profiles.GetProfile(context.Background(), id)
+			return err
+		})
+	}
+}
+
+// waitLag ждёт, пока группа закоммитит offset и её отставание станет lag
+func (st *resetStand) waitLag(t *testing.T, lag int64) {
+	t.Helper()
+
+	waitFor(t, fmt.Sprintf("group lag %d", lag), func() error {
+		group, err := st.broker.DescribeGroup(context.Background(), testGroupID, []string{testTopic})
+		if err != nil {
+			return err
+		}
+		if group.TotalLag != lag {
+			return fmt.Errorf("total lag = %d", group.TotalLag)
+		}
+		return nil
+	})
+}
+
+func (st *resetStand) highWaterMarks(t *testing.T) map[int32]int64 {
+	t.Helper()
+
+	group, err := st.broker.DescribeGroup(context.Background(), testGroupID, []string{testTopic})
+	if err != nil {
+		t.Fatalf("DescribeGroup: %v", err)
+	}
+
+	out := make(map[int32]int64, len(group.Partitions))
+	for _, p := range group.Partitions {
+		out[p.Partition] = p.HighWaterMark
+	}
+
+	return out
+}
+
+func waitFor(t *testing.T, what string, check func() error) {
+	t.Helper()
+
+	var err error
+	for deadline := time.Now().Add(waitTimeout); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if err = check(); err == nil {
+			return
+		}
+	}
+
+	t.Fatalf("timeout waiting for %s: %v", what, err)
+}
+
+func assertResetOffsets(t *testing.T, report dto.ResetReport, want map[int32]int64) {
+	t.Helper()
+
+	if len(report.Offsets) != 1 || report.Offsets[0].GroupID != testGroupID {
+		t.Fatalf("offsets = %+v, want group %s", report.Offsets, testGroupID)
+	}
+
+	got := make(map[int32]int64)
+	for _, p := range report.Offsets[0].Partitions {
+		got[p.Partition] = p.Offset
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("reset offsets = %v, want %v", got, want)
+	}
+}
+
+func assertConsumerState(t *testing.T, report dto.ResetReport, want string) {
+	t.Helper()
+
+	if len(report.Consumers) != 1 || report.Consumers[0].State != want {
+		t.Errorf("consumers = %+v, want personal %s", report.Consumers, want)
+	}
+}
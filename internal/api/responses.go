@@ -9,6 +9,11 @@ import (
 
 var (
 	ErrMessageIDRequired = errors.New("required field 'message_id'")
+	ErrTopicRequired     = errors.New("required field 'topic'")
+	ErrMessageNotFound   = errors.New("message not found")
+	ErrDLQNotFound       = errors.New("dlq record not found")
+	ErrConsumerNotFound  = errors.New("consumer not found")
+	ErrInvalidTopic      = errors.New("invalid topic")
 
 	ErrHistoryIDRequired = errors.New("required field 'history_id'")
 	ErrHistoryNotFound   = errors.New("history not found")
@@ -16,6 +21,9 @@ var (
 	ErrEmployeeIDRequired   = errors.New("required field 'employee_id'")
 	ErrProfileNotFound      = errors.New("employee not found")
 	ErrProfileAlreadyExists = errors.New("employee already exists")
+	ErrPositionFromEvents   = errors.New("position is set by hr.positions events: title, department, grade and effective_from cannot be changed")
+
+	ErrSessionNotFound = errors.New("session not found")
 )
 
 type okResponse struct {
@@ -136,3 +136,93 @@ func validateEmploymentHistory(history dto.EmploymentHistory) string {
 
 	return ""
 }
+
+func checkTimestamp(field, value string) string {
+	if _, err := time.Parse(time.RFC3339, value); err != nil {
+		return fmt.Sprintf("invalid value in field '%s'=%s", field, value)
+	}
+
+	return ""
+}
+
+func validateDLQFilter(filter dto.DLQFilter) string {
+	if filter.From != "" {
+		if msg := checkTimestamp("from", filter.From); msg != "" {
+			return msg
+		}
+	}
+
+	if filter.To != "" {
+		if msg := checkTimestamp("to", filter.To); msg != "" {
+			return msg
+		}
+	}
+
+	if filter.Limit < 0 {
+		return fmt.Sprintf("invalid value in field 'limit'=%d", filter.Limit)
+	}
+
+	return ""
+}
+
+func validateOffsetReset(req offsetResetRequest) (dto.OffsetReset, string) {
+	reset := dto.OffsetReset{To: req.To, Topic: req.Topic, Partitions: req.Partitions}
+
+	switch req.To {
+	case dto.OffsetResetEarliest, dto.OffsetResetLatest:
+	case dto.OffsetResetOffset:
+		if req.Offset == nil {
+			return reset, "required field 'offset'"
+		}
+		if *req.Offset < 0 {
+			return reset, fmt.Sprintf("invalid value in field 'offset'=%d", *req.Offset)
+		}
+		reset.Offset = *req.Offset
+	case dto.OffsetResetTimestamp:
+		if req.Timestamp == "" {
+			return reset, "required field 'timestamp'"
+		}
+		ts, err := time.Parse(time.RFC3339, req.Timestamp)
+		if err != nil {
+			return reset, fmt.Sprintf("invalid value in field 'timestamp'=%s", req.Timestamp)
+		}
+		reset.Timestamp = &ts
+	case "":
+		return reset, "required field 'to'"
+	default:
+		return reset, fmt.Sprintf("invalid value in field 'to'=%s", req.To)
+	}
+
+	for _, p := range req.Partitions {
+		if p < 0 {
+			return reset, fmt.Sprintf("invalid value in field 'partitions'=%d", p)
+		}
+	}
+
+	return reset, ""
+}
+
+// Пределы хаоса продюсера: один запрос не должен занять стенд надолго
+const (
+	maxChaosDuplicate = 100
+	maxChaosDelay     = time.Minute
+)
+
+func validateChaos(c dto.ProducerChaos) string {
+	if c.Duplicate < 0 || c.Duplicate > maxChaosDuplicate {
+		return fmt.Sprintf("invalid value in field 'chaos.duplicate'=%d, allowed 0..%d", c.Duplicate, maxChaosDuplicate)
+	}
+
+	if c.Delay != "" {
+		d, err := time.ParseDuration(c.Delay)
+		if err != nil || d < 0 || d > maxChaosDelay {
+			return fmt.Sprintf("invalid value in field 'chaos.delay'=%s, allowed 0..%s", c.Delay, maxChaosDelay)
+		}
+	}
+
+	if c.CorruptBytes < 0 {
+		return fmt.Sprintf("invalid value in field 'chaos.corrupt_bytes'=%d", c.CorruptBytes)
+	}
+
+	return ""
+}
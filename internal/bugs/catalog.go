@@ -0,0 +1,155 @@
+// Package bugs — каталог заложенных дефектов (режим «найди баг»). Дефекты включаются
+// конфигурацией (bugs.enabled) и admin API во время работы; код стенда проверяет
+// их в точках внедрения через Catalog.Enabled. Пересборка проекций дефектов не видит,
+// поэтому POST /admin/rebuild с dry_run показывает, что они испортили.
+package bugs
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+)
+
+// Идентификаторы дефектов
+const (
+	SkipIdempotency    = "skip-idempotency"
+	SwapNames          = "swap-names"
+	AcceptInvalidGrade = "accept-invalid-grade"
+	CommitBeforeDLQ    = "commit-before-dlq"
+	DropHistory        = "drop-nth-history"
+)
+
+// DropHistoryEvery — при DropHistory теряется каждое N-е событие hr.history
+const DropHistoryEvery = 3
+
+var catalog = []dto.Bug{
+	{
+		ID:          SkipIdempotency,
+		Area:        "consumer_personal",
+		Description: "повтор message_id в hr.personal применяется заново: старое событие затирает более новый профиль",
+	},
+	{
+		ID:          SwapNames,
+		Area:        "consumer_personal",
+		Description: "при записи профиля из hr.personal first_name и last_name меняются местами",
+	},
+	{
+		ID:          AcceptInvalidGrade,
+		Area:        "consumer_positions",
+		Description: "грейд вне списка допустимых не отклоняется, событие применяется вместо DLQ",
+	},
+	{
+		ID:          CommitBeforeDLQ,
+		Area:        "consumer_*",
+		Description: "offset ошибочного сообщения коммитится до записи в DLQ, а запись теряется: сообщения нет ни в журнале, ни в DLQ",
+	},
+	{
+		ID:          DropHistory,
+		Area:        "consumer_history",
+		Description: fmt.Sprintf("каждое %d-е событие hr.history журналируется как applied, но не попадает в employment_history", DropHistoryEvery),
+	},
+}
+
+// Catalog — включённые дефекты; безопасен для конкурентного использования.
+// Нулевой указатель — все дефекты выключены.
+type Catalog struct {
+	mu       sync.Mutex
+	enabled  map[string]bool
+	counters map[string]int
+}
+
+func NewCatalog(ids ...string) (*Catalog, error) {
+	c := &Catalog{}
+	if err := c.Set(ids); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// ParseList разбирает список идентификаторов вида "swap-names,drop-nth-history"
+func ParseList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+
+	return out
+}
+
+// Set заменяет набор включённых дефектов (пустой — выключить все) и обнуляет счётчики
+func (c *Catalog) Set(ids []string) error {
+	enabled := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if !known(id) {
+			return fmt.Errorf("unknown bug '%s'", id)
+		}
+
+		enabled[id] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.enabled = enabled
+	c.counters = make(map[string]int)
+
+	return nil
+}
+
+func known(id string) bool {
+	for _, b := range catalog {
+		if b.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Enabled — включён ли дефект id
+func (c *Catalog) Enabled(id string) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.enabled[id]
+}
+
+// Every — включён ли дефект id и приходится ли текущий вызов на каждый n-й
+func (c *Catalog) Every(id string, n int) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.enabled[id] {
+		return false
+	}
+
+	c.counters[id]++
+
+	return c.counters[id]%n == 0
+}
+
+// List — каталог дефектов с отметкой, какие включены
+func (c *Catalog) List() []dto.Bug {
+	out := make([]dto.Bug, len(catalog))
+	copy(out, catalog)
+
+	for i := range out {
+		enabled := c.Enabled(out[i].ID)
+		out[i].Enabled = &enabled
+	}
+
+	return out
+}
@@ -9,19 +9,54 @@ type Config struct {
 	Postgres pg.PostgresConfig `yaml:"postgres"`
 	Kafka    KafkaConfig       `yaml:"kafka"`
 	UserAPI  ApiConfig         `yaml:"userAPI"`
+	Bugs     BugsConfig        `yaml:"bugs"`
 }
 
 type KafkaConfig struct {
 	Bootstrap        *yamlenv.Env[string] `yaml:"bootstrap"`
 	ProducerClientID *yamlenv.Env[string] `yaml:"producer_client_id"`
-	Topics           struct {
+	// KeyMode — ключ сообщения: message_id или employee_id (message_id всегда в заголовке message-id)
+	KeyMode *yamlenv.Env[string] `yaml:"key_mode"`
+	Topics  struct {
 		Personal  *yamlenv.Env[string] `yaml:"personal"`
 		Positions *yamlenv.Env[string] `yaml:"positions"`
 		History   *yamlenv.Env[string] `yaml:"history"`
 	} `yaml:"topics"`
+	// Partitions — число партиций топиков; служебные .dlq/.retry.N создаются с тем же числом, что и основной
+	Partitions struct {
+		Personal  *yamlenv.Env[int] `yaml:"personal"`
+		Positions *yamlenv.Env[int] `yaml:"positions"`
+		History   *yamlenv.Env[int] `yaml:"history"`
+	} `yaml:"partitions"`
+	// DLQ — дублирование ошибочных сообщений в Kafka-топики <topic><suffix>
+	DLQ struct {
+		Enabled *yamlenv.Env[bool]   `yaml:"enabled"`
+		Suffix  *yamlenv.Env[string] `yaml:"suffix"`
+	} `yaml:"dlq"`
+	// PositionPolicy — конфликт событий должности по effective_from: arrival, effective_from или reject_stale
+	PositionPolicy *yamlenv.Env[string] `yaml:"position_policy"`
+	// Retry — ярусы <topic>.retry.N для временных ошибок; delays — задержки ярусов через запятую
+	Retry struct {
+		Enabled *yamlenv.Env[bool]   `yaml:"enabled"`
+		Delays  *yamlenv.Env[string] `yaml:"delays"`
+	} `yaml:"retry"`
+	// InMemory — брокер в памяти процесса вместо Kafka по bootstrap; данные живут до остановки процесса
+	InMemory *yamlenv.Env[bool] `yaml:"in_memory"`
+	// Chaos — хаос консьюмеров при старте (меняется через /admin/consumer-chaos); пусто и 0 — выключен
+	Chaos struct {
+		Latency        *yamlenv.Env[string] `yaml:"latency"`
+		CrashPercent   *yamlenv.Env[int]    `yaml:"crash_percent"`
+		DBErrorPercent *yamlenv.Env[int]    `yaml:"db_error_percent"`
+	} `yaml:"chaos"`
 }
 
 type ApiConfig struct {
 	Port               *yamlenv.Env[int]    `yaml:"port"`
 	AdminResetPassword *yamlenv.Env[string] `yaml:"admin_reset_password"`
 }
+
+// BugsConfig — заложенные дефекты (режим «найди баг»), включённые при старте
+type BugsConfig struct {
+	// Enabled — идентификаторы через запятую (см. GET /admin/bugs); пусто — все выключены
+	Enabled *yamlenv.Env[string] `yaml:"enabled"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+// Bug — заложенный дефект тренажёра для упражнений «найди баг»
+type Bug struct {
+	ID          string `json:"id" example:"swap-names"`                                       // Идентификатор дефекта
+	Area        string `json:"area" example:"consumer_personal"`                              // Где проявляется
+	Description string `json:"description" example:"first_name и last_name меняются местами"` // Что ломается
+	Enabled     *bool  `json:"enabled,omitempty" example:"false"`                             // Дефект включён; только для администратора
+}
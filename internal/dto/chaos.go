@@ -0,0 +1,16 @@
+package dto
+
+// ProducerChaos — искажения при публикации событий (хаос продюсера); пустое значение — без хаоса
+type ProducerChaos struct {
+	Duplicate    int    `json:"duplicate,omitempty" example:"3"`            // Отправить одно и то же сообщение N раз
+	Delay        string `json:"delay,omitempty" example:"2s"`               // Задержка перед отправкой (1s, 500ms, ...)
+	CorruptBytes int    `json:"corrupt_bytes,omitempty" example:"2"`        // Сколько случайных байт тела инвертировать
+	DropHeader   string `json:"drop_header,omitempty" example:"message-id"` // Заголовок, который не отправляется
+}
+
+// ConsumerChaos — сбои консьюмеров (хаос консьюмера); пустое значение — без хаоса
+type ConsumerChaos struct {
+	Latency        string `json:"latency,omitempty" example:"500ms"`      // Задержка перед обработкой каждого сообщения
+	CrashPercent   int    `json:"crash_percent,omitempty" example:"20"`   // Вероятность (0..100) падения после записи, но до коммита offset
+	DBErrorPercent int    `json:"db_error_percent,omitempty" example:"5"` // Вероятность (0..100) ошибки каждого вызова репозитория
+}
@@ -0,0 +1,45 @@
+package dto
+
+import "github.com/google/uuid"
+
+// Виды проблем в ConsistencyReport
+const (
+	ConsistencyLost               = "lost"                // сообщение прочитано группой (offset закоммичен), но его нет ни в журнале, ни в DLQ
+	ConsistencyUnexplainedJournal = "unexplained_journal" // событие журнала не соответствует ни одному сообщению в топике
+	ConsistencyUnexplainedDLQ     = "unexplained_dlq"     // запись DLQ не соответствует ни одному сообщению в топике
+	ConsistencyNotReflected       = "not_reflected"       // событие журнала не отражено в employee_profile / employment_history
+	ConsistencyMismatch           = "mismatch"            // отражено, но поля отличаются от payload
+)
+
+// ConsistencyReport — сверка топиков Kafka, журнала kafka_events, kafka_dlq и бизнес-таблиц
+type ConsistencyReport struct {
+	Consistent bool               `json:"consistent" example:"false"` // Проблем не найдено
+	Topics     []TopicConsistency `json:"topics"`                     // Сводка по топикам
+	Issues     []ConsistencyIssue `json:"issues"`                     // Найденные проблемы
+	Duration   string             `json:"duration" example:"1.2s"`    // Длительность проверки
+}
+
+// TopicConsistency — чем объясняется каждое сообщение топика
+type TopicConsistency struct {
+	Topic      string `json:"topic" example:"hr.personal"`          // Топик
+	GroupID    string `json:"group_id" example:"consumer_personal"` // Consumer group, чьи offset считаются прочитанными
+	Messages   int    `json:"messages" example:"42"`                // Сообщений в топике
+	Journaled  int    `json:"journaled" example:"38"`               // Есть в kafka_events с теми же partition/offset
+	Duplicates int    `json:"duplicates" example:"1"`               // message_id уже в журнале с другими координатами
+	DLQ        int    `json:"dlq" example:"2"`                      // Есть в kafka_dlq
+	Retrying   int    `json:"retrying" example:"0"`                 // Отправлены в retry-топик, итог ещё не известен
+	Pending    int    `json:"pending" example:"1"`                  // offset не закоммичен — консьюмер ещё не дочитал
+	Lost       int    `json:"lost" example:"0"`                     // Прочитаны, но не объяснены
+}
+
+// ConsistencyIssue — одна найденная проблема
+type ConsistencyIssue struct {
+	Kind       string     `json:"kind" example:"lost" enums:"lost,unexplained_journal,unexplained_dlq,not_reflected,mismatch"` // Вид проблемы
+	Topic      string     `json:"topic" example:"hr.positions"`                                                                // Топик
+	Partition  *int       `json:"partition,omitempty" example:"0"`                                                             // Партиция
+	Offset     *int64     `json:"offset,omitempty" example:"17"`                                                               // Offset
+	MessageID  *uuid.UUID `json:"message_id,omitempty"`                                                                        // Идентификатор сообщения
+	EmployeeID string     `json:"employee_id,omitempty" example:"e-1024"`                                                      // Сотрудник
+	Fields     []string   `json:"fields,omitempty" example:"title"`                                                            // mismatch: отличающиеся поля
+	Detail     string     `json:"detail" example:"committed offset 20, message is neither in kafka_events nor in kafka_dlq"`   // Пояснение
+}
@@ -0,0 +1,75 @@
+package dto
+
+import "time"
+
+// Состояния консьюмера, управляемые через admin API
+const (
+	ConsumerStateRunning = "running" // читает топик и обрабатывает сообщения
+	ConsumerStatePaused  = "paused"  // остаётся в группе, но fetch приостановлен (PauseAll)
+	ConsumerStateStopped = "stopped" // вышел из группы, партиции переданы другим участникам
+)
+
+// ConsumerState — текущее состояние одного консьюмера стенда
+type ConsumerState struct {
+	Name      string   `json:"name" example:"personal"`                                                  // Имя консьюмера: personal, positions, history
+	GroupID   string   `json:"group_id" example:"consumer_personal"`                                     // Consumer group
+	Topics    []string `json:"topics"`                                                                   // Читаемые топики (основной и retry-ярусы)
+	State     string   `json:"state" example:"running"`                                                  // running | paused | stopped
+	ChangedAt string   `json:"changed_at" example:"2025-10-01T10:00:00Z"`                                // Время последней смены состояния
+	LastError string   `json:"last_error,omitempty" example:"create consumer group: broker unreachable"` // Ошибка входа в группу; консьюмер повторяет вход, пока она не исчезнет
+}
+
+// ConsumerGroupLag — отставание consumer group по партициям
+type ConsumerGroupLag struct {
+	Name       string         `json:"name" example:"personal"`              // Имя консьюмера стенда
+	GroupID    string         `json:"group_id" example:"consumer_personal"` // Consumer group
+	State      string         `json:"state" example:"running"`              // Состояние консьюмера стенда: running | paused | stopped
+	GroupState string         `json:"group_state" example:"Stable"`         // Состояние группы в Kafka: Stable, Empty, PreparingRebalance, …
+	TotalLag   int64          `json:"total_lag" example:"3"`                // Суммарное отставание по всем партициям
+	Members    []GroupMember  `json:"members"`                              // Участники группы
+	Partitions []PartitionLag `json:"partitions"`                           // Отставание по партициям
+}
+
+// GroupMember — участник consumer group и его назначение
+type GroupMember struct {
+	MemberID   string             `json:"member_id"`                       // Идентификатор, выданный координатором
+	ClientID   string             `json:"client_id" example:"sarama"`      // client.id консьюмера
+	ClientHost string             `json:"client_host" example:"/10.0.0.5"` // Хост консьюмера
+	Assignment map[string][]int32 `json:"assignment"`                      // Назначенные партиции по топикам
+}
+
+// PartitionLag — положение группы в одной партиции
+type PartitionLag struct {
+	Topic           string  `json:"topic" example:"hr.personal"`                                // Топик
+	Partition       int32   `json:"partition" example:"0"`                                      // Партиция
+	CommittedOffset int64   `json:"committed_offset" example:"40"`                              // Закоммиченный offset (-1 — коммитов ещё не было)
+	HighWaterMark   int64   `json:"high_water_mark" example:"43"`                               // Offset следующего сообщения в партиции
+	Lag             int64   `json:"lag" example:"3"`                                            // Непрочитанных сообщений
+	Member          string  `json:"member,omitempty"`                                           // member_id, которому назначена партиция
+	LastProcessedAt *string `json:"last_processed_at,omitempty" example:"2025-10-01T10:00:00Z"` // Когда консьюмер стенда обработал последнее сообщение
+}
+
+// Куда переставить offset группы
+const (
+	OffsetResetEarliest  = "earliest"  // самое раннее доступное сообщение
+	OffsetResetLatest    = "latest"    // high-water mark: старые сообщения пропускаются
+	OffsetResetOffset    = "offset"    // конкретный offset
+	OffsetResetTimestamp = "timestamp" // первое сообщение с временем не раньше заданного
+)
+
+// OffsetReset — параметры перестановки offset группы
+type OffsetReset struct {
+	To         string     // earliest | latest | offset | timestamp
+	Topic      string     // пусто — все топики консьюмера
+	Partitions []int32    // пусто — все партиции
+	Offset     int64      // для To=offset
+	Timestamp  *time.Time // для To=timestamp
+}
+
+// OffsetResetResult — итог перестановки offset одной партиции
+type OffsetResetResult struct {
+	Topic     string `json:"topic" example:"hr.personal"` // Топик
+	Partition int32  `json:"partition" example:"0"`       // Партиция
+	Previous  int64  `json:"previous" example:"43"`       // Offset до перестановки (-1 — коммитов не было)
+	Offset    int64  `json:"offset" example:"0"`          // Новый закоммиченный offset
+}
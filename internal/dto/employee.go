@@ -1,5 +1,7 @@
 package dto
 
+import "github.com/google/uuid"
+
 // EmployeeProfile — данные профиля сотрудника
 type EmployeeProfile struct {
 	EmployeeID    string  `json:"employee_id" example:"e-1024"`                      // Идентификатор сотрудника
@@ -13,3 +15,24 @@ type EmployeeProfile struct {
 	Grade         *string `json:"grade,omitempty" example:"Middle"`                  // Грейд (Junior, Middle, Senior или другой)
 	EffectiveFrom *string `json:"effective_from,omitempty" example:"2025-10-01"`     // Дата вступления изменений в силу (YYYY-MM-DD)
 }
+
+// Положение назначения относительно текущей даты
+const (
+	AssignmentPast       = "past"       // сменено более поздним назначением
+	AssignmentCurrent    = "current"    // действует сегодня
+	AssignmentFuture     = "future"     // effective_from ещё не наступил
+	AssignmentSuperseded = "superseded" // отменено событием с более ранним effective_from, пришедшим позже (политика arrival)
+)
+
+// PositionAssignment — назначение на должность, действующее с effective_from до следующего назначения
+type PositionAssignment struct {
+	ID            int64      `json:"id" example:"12"`                                                 // Идентификатор записи
+	EmployeeID    string     `json:"employee_id" example:"e-1024"`                                    // Идентификатор сотрудника
+	Title         *string    `json:"title,omitempty" example:"Инженер по тестированию"`               // Должность
+	Department    *string    `json:"department,omitempty" example:"Отдел качества"`                   // Подразделение/отдел
+	Grade         *string    `json:"grade,omitempty" example:"Middle"`                                // Грейд
+	EffectiveFrom string     `json:"effective_from" example:"2025-10-01"`                             // Дата вступления в силу (YYYY-MM-DD)
+	MessageID     *uuid.UUID `json:"message_id,omitempty"`                                            // Событие hr.positions, создавшее запись
+	Status        string     `json:"status" example:"current" enums:"past,current,future,superseded"` // Положение относительно сегодняшней даты
+	CreatedAt     string     `json:"created_at" example:"2025-10-01T10:00:00Z"`                       // Время записи
+}
@@ -7,4 +7,5 @@ import (
 var (
 	ErrNotFound      = errors.New("errRecordNotFound")
 	ErrAlreadyExists = errors.New("errAlreadyExists")
+	ErrInvalidState  = errors.New("errInvalidState")
 )
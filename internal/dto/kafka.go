@@ -5,6 +5,23 @@ import (
 	"github.com/google/uuid"
 )
 
+// HeaderMessageID — заголовок с message_id: при ключе employee_id идентификатор
+// сообщения передаётся только в нём (и в теле)
+const HeaderMessageID = "message-id"
+
+// Политики конфликтов событий должности
+const (
+	PositionPolicyArrival       = "arrival"        // последнее по приходу побеждает (устаревшее событие помечается stale, но применяется)
+	PositionPolicyEffectiveFrom = "effective_from" // побеждает более поздний effective_from, устаревшее событие не применяется
+	PositionPolicyRejectStale   = "reject_stale"   // устаревшее событие уходит в DLQ
+)
+
+// TopicSpec — топик стенда и требуемое число партиций
+type TopicSpec struct {
+	Name       string
+	Partitions int32
+}
+
 // KafkaEvent — сырое событие
 type KafkaEvent struct {
 	ID         int64           `json:"id"`
@@ -14,14 +31,110 @@ type KafkaEvent struct {
 	Offset     int64           `json:"offset"`
 	Payload    json.RawMessage `json:"payload"`
 	ReceivedAt string          `json:"received_at"`
+	Stale      bool            `json:"stale"` // Событие должности старше текущего effective_from
 }
 
 // KafkaDLQ — сообщение в DLQ
 type KafkaDLQ struct {
 	ID         int64           `json:"id"`
+	MessageID  *uuid.UUID      `json:"message_id,omitempty"`
 	Topic      string          `json:"topic"`
+	Partition  *int            `json:"partition,omitempty"`
+	Offset     *int64          `json:"offset,omitempty"`
 	Key        string          `json:"key"`
 	Payload    json.RawMessage `json:"payload"`
 	Error      string          `json:"error"`
 	ReceivedAt string          `json:"received_at"`
+
+	ReplayStatus    string  `json:"replay_status,omitempty" enums:"replayed,failed"` // Результат последнего replay
+	ReplayAttempts  int     `json:"replay_attempts"`                                 // Сколько раз запись отправлялась повторно
+	LastReplayedAt  *string `json:"last_replayed_at,omitempty"`                      // Время последнего replay
+	LastReplayError string  `json:"last_replay_error,omitempty"`                     // Ошибка последнего replay
+
+	RawPayload []byte `json:"-"` // Исходное тело сообщения байт в байт
+}
+
+// Статусы replay записи DLQ
+const (
+	ReplayStatusReplayed = "replayed" // сообщение повторно отправлено в исходный топик
+	ReplayStatusFailed   = "failed"   // повторная отправка не удалась
+)
+
+// DLQFilter — отбор записей DLQ; пустые поля не ограничивают выборку
+type DLQFilter struct {
+	Topic         string // Исходный топик
+	ErrorContains string // Подстрока причины (без учёта регистра)
+	From          string // received_at >= From (RFC3339)
+	To            string // received_at <= To (RFC3339)
+	OnlyPending   bool   // Только записи без успешного replay
+	Limit         int    // Максимум записей (0 — без ограничения)
+}
+
+// Решения консьюмера по прочитанному сообщению
+const (
+	DecisionApplied   = "applied"   // событие записано в журнал и применено
+	DecisionDuplicate = "duplicate" // message_id уже в журнале, повтор пропущен
+	DecisionDLQ       = "dlq"       // событие отправлено в DLQ
+	DecisionRetry     = "retry"     // временный сбой, событие отправлено в retry-топик
+	DecisionStale     = "stale"     // событие записано в журнал, но устарело и не применено (политика effective_from)
+)
+
+// ConsumerDecision — что консьюмер сделал с прочитанным сообщением
+type ConsumerDecision struct {
+	ID        int64      `json:"id"`
+	MessageID *uuid.UUID `json:"message_id,omitempty"`
+	Topic     string     `json:"topic"`
+	Partition int        `json:"partition"`
+	Offset    int64      `json:"offset"`
+	Decision  string     `json:"decision" example:"applied" enums:"applied,duplicate,dlq,retry,stale"`
+	Reason    string     `json:"reason,omitempty"`
+	DecidedAt string     `json:"decided_at"`
+}
+
+// RawMessage — произвольное сообщение для публикации «как есть»
+type RawMessage struct {
+	Topic     string            // Топик назначения
+	Key       *string           // Ключ сообщения (nil — без ключа)
+	Partition *int32            // Явный номер партиции (nil — выбор партиционером)
+	Headers   map[string]string // Заголовки сообщения
+	Body      []byte            // Тело сообщения без изменений
+}
+
+// ProduceReceipt — квитанция об отправке сообщения в Kafka
+type ProduceReceipt struct {
+	Topic     string `json:"topic" example:"hr.personal"`                                         // Топик
+	Partition int32  `json:"partition" example:"0"`                                               // Партиция, назначенная Kafka
+	Offset    int64  `json:"offset" example:"42"`                                                 // Offset, назначенный Kafka
+	Key       string `json:"key,omitempty" example:"e-1024"`                                      // Ключ сообщения
+	MessageID string `json:"message_id,omitempty" example:"6b6f9c38-3e2a-4b3d-9a9a-9f1c0f8b2a10"` // Идентификатор события (если известен)
+	Timestamp string `json:"timestamp" example:"2025-10-01T12:00:00+03:00"`                       // Время отправки (RFC3339)
+
+	Chaos  []string         `json:"chaos,omitempty" example:"duplicate 1/3,delay 2s"` // Применённый хаос продюсера
+	Copies []ProduceReceipt `json:"copies,omitempty"`                                 // Квитанции повторных отправок (chaos.duplicate)
+}
+
+// Виды нарушений порядка в OrderingReport
+const (
+	OrderingPartitionSplit   = "partition_split"   // события сотрудника в топике попали в разные партиции
+	OrderingOffsetRegression = "offset_regression" // событие применено позже события с большим offset той же партиции
+)
+
+// OrderingReport — проверка порядка применения событий по сотрудникам
+type OrderingReport struct {
+	Events     int                 `json:"events" example:"120"`   // Проверено событий
+	Employees  int                 `json:"employees" example:"10"` // Проверено пар топик/сотрудник
+	Violations []OrderingViolation `json:"violations"`             // Нарушения
+}
+
+// OrderingViolation — одно нарушение порядка
+type OrderingViolation struct {
+	Kind          string     `json:"kind" example:"offset_regression" enums:"partition_split,offset_regression"` // Вид нарушения
+	Topic         string     `json:"topic" example:"hr.positions"`                                               // Топик
+	EmployeeID    string     `json:"employee_id" example:"e-1024"`                                               // Сотрудник
+	Partitions    []int      `json:"partitions,omitempty"`                                                       // partition_split: партиции с событиями сотрудника
+	MessageID     *uuid.UUID `json:"message_id,omitempty"`                                                       // offset_regression: событие, применённое не по порядку
+	Partition     *int       `json:"partition,omitempty"`                                                        // offset_regression: партиция
+	Offset        *int64     `json:"offset,omitempty"`                                                           // offset_regression: offset события
+	PrevMessageID *uuid.UUID `json:"prev_message_id,omitempty"`                                                  // offset_regression: ранее применённое событие
+	PrevOffset    *int64     `json:"prev_offset,omitempty"`                                                      // offset_regression: его offset
 }
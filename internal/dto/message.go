@@ -0,0 +1,23 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+)
+
+// Итоговый статус сообщения в MessageLifecycle (помимо решений консьюмера)
+const (
+	MessageStatusPending = "pending" // отправлено, но консьюмер ещё не принял решение
+)
+
+// MessageLifecycle — всё, что известно об одном сообщении: от отправки до бизнес-таблиц
+type MessageLifecycle struct {
+	MessageID  uuid.UUID           `json:"message_id"`                             // Идентификатор события
+	Status     string              `json:"status" example:"applied"`               // Последнее решение консьюмера или pending
+	EmployeeID string              `json:"employee_id,omitempty" example:"e-1024"` // Сотрудник из payload
+	Receipts   []ProduceReceipt    `json:"receipts"`                               // Квитанции продюсера
+	Event      *KafkaEvent         `json:"event,omitempty"`                        // Запись в журнале kafka_events
+	DLQ        []KafkaDLQ          `json:"dlq"`                                    // Записи в kafka_dlq
+	Decisions  []ConsumerDecision  `json:"decisions"`                              // Решения консьюмера по порядку
+	Profile    *EmployeeProfile    `json:"profile,omitempty"`                      // Текущий профиль сотрудника
+	History    []EmploymentHistory `json:"history"`                                // Записи истории, созданные сообщением
+}
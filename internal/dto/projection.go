@@ -0,0 +1,48 @@
+package dto
+
+import "github.com/google/uuid"
+
+// Виды расхождений между текущим состоянием и пересобранным из журнала
+const (
+	MismatchMissing    = "missing"    // запись есть сейчас, но не восстанавливается из журнала (создана/изменена через CRUD)
+	MismatchUnexpected = "unexpected" // запись появилась только после пересборки (удалена через CRUD)
+	MismatchChanged    = "changed"    // запись есть в обоих состояниях, поля отличаются
+)
+
+// RebuildReport — результат пересборки employee_profile и employment_history из журнала kafka_events
+type RebuildReport struct {
+	DryRun     bool                 `json:"dry_run" example:"false"`  // Изменения откатаны, отчёт показывает только расхождения
+	Events     int                  `json:"events" example:"42"`      // Прочитано событий журнала
+	Applied    int                  `json:"applied" example:"40"`     // Применено заново
+	Stale      int                  `json:"stale" example:"1"`        // Устаревшие события должности (записаны только в историю назначений)
+	Failed     []ReplayFailure      `json:"failed"`                   // События, которые не удалось применить
+	Profiles   ProjectionCount      `json:"profiles"`                 // Число профилей до и после
+	History    ProjectionCount      `json:"history"`                  // Число записей истории до и после
+	Mismatches []ProjectionMismatch `json:"mismatches"`               // Расхождения с состоянием до пересборки
+	Duration   string               `json:"duration" example:"0.42s"` // Длительность пересборки
+}
+
+// ProjectionCount — число строк таблицы до и после пересборки
+type ProjectionCount struct {
+	Before int `json:"before" example:"10"`
+	After  int `json:"after" example:"9"`
+}
+
+// ReplayFailure — событие журнала, которое не применилось при пересборке
+type ReplayFailure struct {
+	MessageID uuid.UUID `json:"message_id"`                                                                  // Идентификатор сообщения
+	Topic     string    `json:"topic" example:"hr.positions"`                                                // Топик
+	Partition int       `json:"partition" example:"0"`                                                       // Партиция
+	Offset    int64     `json:"offset" example:"17"`                                                         // Offset
+	Error     string    `json:"error" example:"employee_id=e-1024 not found: create employee profile first"` // Причина
+}
+
+// ProjectionMismatch — расхождение строки проекции до и после пересборки
+type ProjectionMismatch struct {
+	Table  string   `json:"table" example:"employee_profile"`                          // Таблица
+	Key    string   `json:"key" example:"e-1024"`                                      // employee_id профиля или message_id записи истории
+	Kind   string   `json:"kind" example:"changed" enums:"missing,unexpected,changed"` // Вид расхождения
+	Fields []string `json:"fields,omitempty" example:"title,grade"`                    // Отличающиеся поля (для changed)
+	Before any      `json:"before,omitempty" swaggertype:"object"`                     // Строка до пересборки
+	After  any      `json:"after,omitempty" swaggertype:"object"`                      // Строка после пересборки
+}
@@ -0,0 +1,26 @@
+package dto
+
+// ResetReport — что было сделано при сбросе окружения
+type ResetReport struct {
+	Topics    []TopicReset          `json:"topics"`                   // Пересозданные топики
+	Offsets   []ConsumerOffsetReset `json:"offsets"`                  // Сброшенные offset групп
+	Database  bool                  `json:"database" example:"true"`  // Таблицы очищены
+	Session   string                `json:"session,omitempty"`        // Сессия, данные которой удалены (X-Session-ID); пусто — сброс всего стенда
+	Consumers []ConsumerState       `json:"consumers"`                // Состояние консьюмеров после сброса
+	Duration  string                `json:"duration" example:"2.31s"` // Длительность сброса
+}
+
+// TopicReset — пересоздание одного топика
+type TopicReset struct {
+	Topic             string `json:"topic" example:"hr.personal"`    // Топик
+	Partitions        int32  `json:"partitions" example:"3"`         // Партиций после пересоздания
+	ReplicationFactor int16  `json:"replication_factor" example:"1"` // Фактор репликации
+	Existed           bool   `json:"existed" example:"true"`         // Топик существовал и был удалён
+}
+
+// ConsumerOffsetReset — сброс offset группы одного консьюмера
+type ConsumerOffsetReset struct {
+	Consumer   string              `json:"consumer" example:"personal"`          // Имя консьюмера
+	GroupID    string              `json:"group_id" example:"consumer_personal"` // Consumer group
+	Partitions []OffsetResetResult `json:"partitions"`                           // Новые offset по партициям
+}
@@ -0,0 +1,37 @@
+package dto
+
+// Статусы шага сценария
+const (
+	ScenarioStepPassed  = "passed"  // шаг выполнен, проверка прошла
+	ScenarioStepFailed  = "failed"  // шаг не выполнен или проверка не прошла
+	ScenarioStepSkipped = "skipped" // шаг не запускался: сценарий остановлен на предыдущей ошибке
+)
+
+// ScenarioInfo — встроенный сценарий QA-чек-листа
+type ScenarioInfo struct {
+	Name        string `json:"name" example:"idempotency"`                                    // Имя сценария
+	Description string `json:"description" example:"повтор message_id не изменяет состояние"` // Что проверяет
+	Steps       int    `json:"steps" example:"6"`                                             // Число шагов
+	Source      string `json:"source,omitempty" example:"name: idempotency\nsteps: [...]"`    // YAML сценария
+}
+
+// ScenarioReport — результат прогона сценария
+type ScenarioReport struct {
+	Scenario    string               `json:"scenario" example:"idempotency"`            // Имя сценария
+	Description string               `json:"description,omitempty"`                     // Что проверяет
+	RunID       string               `json:"run_id" example:"3f9a1c2b"`                 // Идентификатор прогона (${run} в сценарии)
+	Passed      bool                 `json:"passed" example:"true"`                     // Все шаги прошли
+	StartedAt   string               `json:"started_at" example:"2025-10-01T10:00:00Z"` // Время запуска
+	Duration    string               `json:"duration" example:"3.2s"`                   // Длительность прогона
+	Steps       []ScenarioStepResult `json:"steps"`                                     // Шаги по порядку
+}
+
+// ScenarioStepResult — результат одного шага сценария
+type ScenarioStepResult struct {
+	Index    int    `json:"index" example:"1"`                                                         // Номер шага (с 1)
+	Name     string `json:"name,omitempty" example:"профиль создан"`                                   // Название шага из сценария
+	Action   string `json:"action" example:"wait_until"`                                               // Действие шага
+	Status   string `json:"status" example:"passed" enums:"passed,failed,skipped"`                     // Статус
+	Error    string `json:"error,omitempty" example:"profile e-1024: first_name='Анна', want 'Мария'"` // Причина падения
+	Duration string `json:"duration,omitempty" example:"1.05s"`                                        // Длительность шага
+}
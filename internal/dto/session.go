@@ -0,0 +1,33 @@
+package dto
+
+import "context"
+
+// HeaderSessionID — заголовок сообщения Kafka с сессией стажёра: консьюмер пишет
+// журнал, DLQ и бизнес-таблицы в пространство этой сессии
+const HeaderSessionID = "session-id"
+
+// Session — сессия стажёра: собственное пространство employee_id, журнала, DLQ и сброса
+type Session struct {
+	ID        string `json:"id" example:"6b1f3c2e-8d4a-4f7e-9c1b-2a3d4e5f6a7b"` // Идентификатор (заголовок X-Session-ID)
+	Name      string `json:"name" example:"anna"`                               // Имя сессии
+	CreatedAt string `json:"created_at" example:"2025-10-01T10:00:00+03"`       // Время создания
+}
+
+// sessionKey — ключ сессии в context.Context
+type sessionKey struct{}
+
+// SessionKey — ключ, под которым HTTP API кладёт сессию запроса в UserValue
+// (fasthttp.RequestCtx.Value читает UserValue), чтобы её видели репозитории
+var SessionKey any = sessionKey{}
+
+// WithSession возвращает контекст, в котором работают запросы сессии id
+func WithSession(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, SessionKey, id)
+}
+
+// SessionFrom — сессия контекста; пусто — общая сессия (запросы без X-Session-ID)
+func SessionFrom(ctx context.Context) string {
+	id, _ := ctx.Value(SessionKey).(string)
+
+	return id
+}
@@ -0,0 +1,233 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxChaosLatency — ограничение задержки обработки, чтобы сессия не выпадала из группы
+const maxChaosLatency = 30 * time.Second
+
+// errChaosDB — сбой репозитория, внесённый хаосом; без кода Postgres, поэтому
+// классифицируется как временный (см. IsRetryableDBError) и уходит в retry.
+var errChaosDB = errors.New("chaos: injected database failure")
+
+// Chaos — хаос консьюмеров для упражнений на at-least-once: задержка обработки,
+// падение между записью и коммитом offset, сбои репозиториев. Меняется во время
+// работы через admin API; нулевой указатель — хаос выключен.
+type Chaos struct {
+	v atomic.Pointer[chaosState]
+}
+
+type chaosState struct {
+	chaos   dto.ConsumerChaos
+	latency time.Duration
+}
+
+func NewChaos(chaos dto.ConsumerChaos) (*Chaos, error) {
+	c := &Chaos{}
+	if err := c.Set(chaos); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Chaos) Get() dto.ConsumerChaos {
+	return c.state().chaos
+}
+
+func (c *Chaos) Set(chaos dto.ConsumerChaos) error {
+	var latency time.Duration
+	if chaos.Latency != "" {
+		d, err := time.ParseDuration(chaos.Latency)
+		if err != nil || d < 0 || d > maxChaosLatency {
+			return fmt.Errorf("invalid value in field 'latency'=%s, allowed 0..%s", chaos.Latency, maxChaosLatency)
+		}
+		latency = d
+	}
+
+	if chaos.CrashPercent < 0 || chaos.CrashPercent > 100 {
+		return fmt.Errorf("invalid value in field 'crash_percent'=%d, allowed 0..100", chaos.CrashPercent)
+	}
+
+	if chaos.DBErrorPercent < 0 || chaos.DBErrorPercent > 100 {
+		return fmt.Errorf("invalid value in field 'db_error_percent'=%d, allowed 0..100", chaos.DBErrorPercent)
+	}
+
+	c.v.Store(&chaosState{chaos: chaos, latency: latency})
+
+	return nil
+}
+
+func (c *Chaos) state() chaosState {
+	if c == nil {
+		return chaosState{}
+	}
+
+	if s := c.v.Load(); s != nil {
+		return *s
+	}
+
+	return chaosState{}
+}
+
+// delay выдерживает задержку обработки; false — ctx отменён раньше
+func (c *Chaos) delay(ctx context.Context) bool {
+	latency := c.state().latency
+	if latency <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// crash роняет обработку с вероятностью crash_percent: бизнес-запись уже
+// закоммичена, offset — ещё нет, поэтому после перезапуска сессии сообщение придёт снова.
+func (c *Chaos) crash(msg *sarama.ConsumerMessage) {
+	if hit(c.state().chaos.CrashPercent) {
+		panic(fmt.Sprintf("chaos: crash before commit %s/%d/%d", msg.Topic, msg.Partition, msg.Offset))
+	}
+}
+
+// dbFailure — ошибка вызова репозитория с вероятностью db_error_percent
+func (c *Chaos) dbFailure() error {
+	if hit(c.state().chaos.DBErrorPercent) {
+		return errChaosDB
+	}
+
+	return nil
+}
+
+func hit(percent int) bool {
+	return percent > 0 && rand.IntN(100) < percent
+}
+
+// WithChaos подключает хаос консьюмеров: репозитории оборачиваются обёртками,
+// которые возвращают ошибку с вероятностью db_error_percent.
+func WithChaos(chaos *Chaos) Option {
+	return func(h *handler) {
+		h.chaos = chaos
+		h.events = chaosEvents{EventsRepository: h.events, chaos: chaos}
+		h.profiles = chaosProfiles{ProfileRepository: h.profiles, chaos: chaos}
+		if h.history != nil {
+			h.history = chaosHistory{HistoryRepository: h.history, chaos: chaos}
+		}
+	}
+}
+
+// chaosEvents — EventsRepository со сбоями. InsertDLQ и InsertDecision не искажаются:
+// их ошибки только логируются, и сообщение пропало бы бесследно.
+type chaosEvents struct {
+	EventsRepository
+	chaos *Chaos
+}
+
+func (r chaosEvents) Begin(ctx context.Context) (pgx.Tx, error) {
+	if err := r.chaos.dbFailure(); err != nil {
+		return nil, err
+	}
+
+	return r.EventsRepository.Begin(ctx)
+}
+
+func (r chaosEvents) ClaimMessageTx(ctx context.Context, tx pgx.Tx, event dto.KafkaEvent) (bool, error) {
+	if err := r.chaos.dbFailure(); err != nil {
+		return false, err
+	}
+
+	return r.EventsRepository.ClaimMessageTx(ctx, tx, event)
+}
+
+func (r chaosEvents) MarkStaleTx(ctx context.Context, tx pgx.Tx, messageID uuid.UUID) error {
+	if err := r.chaos.dbFailure(); err != nil {
+		return err
+	}
+
+	return r.EventsRepository.MarkStaleTx(ctx, tx, messageID)
+}
+
+// chaosProfiles — ProfileRepository со сбоями
+type chaosProfiles struct {
+	ProfileRepository
+	chaos *Chaos
+}
+
+func (r chaosProfiles) UpsertPersonalTx(ctx context.Context, tx pgx.Tx, profile dto.EmployeeProfile) error {
+	if err := r.chaos.dbFailure(); err != nil {
+		return err
+	}
+
+	return r.ProfileRepository.UpsertPersonalTx(ctx, tx, profile)
+}
+
+func (r chaosProfiles) GetProfile(ctx context.Context, employeeID string) (*dto.EmployeeProfile, error) {
+	if err := r.chaos.dbFailure(); err != nil {
+		return nil, err
+	}
+
+	return r.ProfileRepository.GetProfile(ctx, employeeID)
+}
+
+func (r chaosProfiles) UpsertPositionTx(ctx context.Context, tx pgx.Tx, profile dto.EmployeeProfile) error {
+	if err := r.chaos.dbFailure(); err != nil {
+		return err
+	}
+
+	return r.ProfileRepository.UpsertPositionTx(ctx, tx, profile)
+}
+
+func (r chaosProfiles) LockEffectiveFromTx(ctx context.Context, tx pgx.Tx, employeeID string) (string, error) {
+	if err := r.chaos.dbFailure(); err != nil {
+		return "", err
+	}
+
+	return r.ProfileRepository.LockEffectiveFromTx(ctx, tx, employeeID)
+}
+
+func (r chaosProfiles) InsertAssignmentTx(ctx context.Context, tx pgx.Tx, assignment dto.PositionAssignment) error {
+	if err := r.chaos.dbFailure(); err != nil {
+		return err
+	}
+
+	return r.ProfileRepository.InsertAssignmentTx(ctx, tx, assignment)
+}
+
+func (r chaosProfiles) SupersedeAssignmentsTx(ctx context.Context, tx pgx.Tx, employeeID, effectiveFrom string) error {
+	if err := r.chaos.dbFailure(); err != nil {
+		return err
+	}
+
+	return r.ProfileRepository.SupersedeAssignmentsTx(ctx, tx, employeeID, effectiveFrom)
+}
+
+// chaosHistory — HistoryRepository со сбоями
+type chaosHistory struct {
+	HistoryRepository
+	chaos *Chaos
+}
+
+func (r chaosHistory) InsertTx(ctx context.Context, tx pgx.Tx, h dto.EmploymentHistory) error {
+	if err := r.chaos.dbFailure(); err != nil {
+		return err
+	}
+
+	return r.HistoryRepository.InsertTx(ctx, tx, h)
+}
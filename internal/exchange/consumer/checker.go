@@ -0,0 +1,390 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+)
+
+// TopicReader — чтение топика целиком и committed offset группы (реализуется kafkaadmin.Admin)
+type TopicReader interface {
+	ReadTopic(ctx context.Context, topic string) ([]*sarama.ConsumerMessage, error)
+	DescribeGroup(ctx context.Context, groupID string, topics []string) (dto.ConsumerGroupLag, error)
+}
+
+type CheckEventsRepository interface {
+	ListEvents(ctx context.Context) ([]dto.KafkaEvent, error)
+	ListDLQ(ctx context.Context, filter dto.DLQFilter) ([]dto.KafkaDLQ, error)
+	ListDecisions(ctx context.Context, decision string) ([]dto.ConsumerDecision, error)
+}
+
+type CheckProfileRepository interface {
+	GetProfile(ctx context.Context, employeeID string) (*dto.EmployeeProfile, error)
+	ListPositions(ctx context.Context, employeeID string) ([]dto.PositionAssignment, error)
+}
+
+type CheckHistoryRepository interface {
+	ListByMessageID(ctx context.Context, messageID uuid.UUID) ([]dto.EmploymentHistory, error)
+}
+
+// Checker сверяет топики стенда с журналом kafka_events, kafka_dlq и бизнес-таблицами:
+// каждое сообщение топика должно быть объяснено (журнал, дубль, DLQ, retry, ещё не прочитано),
+// каждая запись журнала и DLQ — соответствовать сообщению, каждое событие журнала — быть отражено.
+type Checker struct {
+	reader    TopicReader
+	events    CheckEventsRepository
+	profiles  CheckProfileRepository
+	history   CheckHistoryRepository
+	topics    ProjectorTopics
+	consumers *Registry
+}
+
+func NewChecker(
+	reader TopicReader,
+	events CheckEventsRepository,
+	profiles CheckProfileRepository,
+	history CheckHistoryRepository,
+	topics ProjectorTopics,
+	consumers *Registry,
+) *Checker {
+	return &Checker{reader: reader, events: events, profiles: profiles, history: history, topics: topics, consumers: consumers}
+}
+
+// coord — координаты сообщения в исходном топике
+type coord struct {
+	topic     string
+	partition int
+	offset    int64
+}
+
+// Check выполняет сверку в пространстве сессии ctx: из топиков берутся только сообщения
+// с её заголовком session-id. Порядок чтения важен: сначала committed offset групп, затем
+// топики, затем БД — всё, что группа закоммитила, к моменту чтения БД уже записано.
+func (c *Checker) Check(ctx context.Context) (dto.ConsistencyReport, error) {
+	started := time.Now()
+	report := dto.ConsistencyReport{Topics: []dto.TopicConsistency{}, Issues: []dto.ConsistencyIssue{}}
+
+	topics := []string{c.topics.Personal, c.topics.Positions, c.topics.History}
+
+	groups := make(map[string]string, len(topics))
+	committed := make(map[coord]int64)
+	for _, r := range c.consumers.Runners() {
+		groups[r.topic] = r.groupID
+
+		lag, err := c.reader.DescribeGroup(ctx, r.groupID, []string{r.topic})
+		if err != nil {
+			return report, fmt.Errorf("reader.DescribeGroup %s: %w", r.groupID, err)
+		}
+		for _, p := range lag.Partitions {
+			committed[coord{topic: p.Topic, partition: int(p.Partition)}] = p.CommittedOffset
+		}
+	}
+
+	session := dto.SessionFrom(ctx)
+	messages := make(map[string][]*sarama.ConsumerMessage, len(topics))
+	for _, topic := range topics {
+		msgs, err := c.reader.ReadTopic(ctx, topic)
+		if err != nil {
+			return report, fmt.Errorf("reader.ReadTopic %s: %w", topic, err)
+		}
+		for _, msg := range msgs {
+			if sessionOf(msg) == session {
+				messages[topic] = append(messages[topic], msg)
+			}
+		}
+	}
+
+	journal, err := c.events.ListEvents(ctx)
+	if err != nil {
+		return report, fmt.Errorf("events.ListEvents: %w", err)
+	}
+
+	dlq, err := c.events.ListDLQ(ctx, dto.DLQFilter{})
+	if err != nil {
+		return report, fmt.Errorf("events.ListDLQ: %w", err)
+	}
+
+	retries, err := c.events.ListDecisions(ctx, dto.DecisionRetry)
+	if err != nil {
+		return report, fmt.Errorf("events.ListDecisions: %w", err)
+	}
+
+	journaled := make(map[coord]bool, len(journal))
+	journaledIDs := make(map[uuid.UUID]bool, len(journal))
+	for _, e := range journal {
+		journaled[coord{e.Topic, e.Partition, e.Offset}] = true
+		journaledIDs[e.MessageID] = true
+	}
+
+	inDLQ := make(map[coord]bool, len(dlq))
+	for _, d := range dlq {
+		if d.Partition != nil && d.Offset != nil {
+			inDLQ[coord{d.Topic, *d.Partition, *d.Offset}] = true
+		}
+	}
+
+	retrying := make(map[coord]bool, len(retries))
+	for _, d := range retries {
+		retrying[coord{d.Topic, d.Partition, d.Offset}] = true
+	}
+
+	inKafka := make(map[coord]bool)
+	for _, topic := range topics {
+		summary := dto.TopicConsistency{Topic: topic, GroupID: groups[topic], Messages: len(messages[topic])}
+
+		for _, msg := range messages[topic] {
+			at := coord{msg.Topic, int(msg.Partition), msg.Offset}
+			inKafka[at] = true
+
+			messageID, _ := messageIDOf(msg)
+
+			switch {
+			case journaled[at]:
+				summary.Journaled++
+			case inDLQ[at]:
+				summary.DLQ++
+			case messageID != uuid.Nil && journaledIDs[messageID]:
+				summary.Duplicates++
+			case retrying[at]:
+				summary.Retrying++
+			case msg.Offset >= committedOffset(committed, at):
+				summary.Pending++
+			default:
+				summary.Lost++
+				report.Issues = append(report.Issues, issueAt(dto.ConsistencyLost, at, messageID,
+					fmt.Sprintf("committed offset %d, message is neither in kafka_events nor in kafka_dlq", committedOffset(committed, at))))
+			}
+		}
+
+		report.Topics = append(report.Topics, summary)
+	}
+
+	watched := map[string]bool{c.topics.Personal: true, c.topics.Positions: true, c.topics.History: true}
+
+	for _, e := range journal {
+		at := coord{e.Topic, e.Partition, e.Offset}
+		if watched[e.Topic] && !inKafka[at] {
+			report.Issues = append(report.Issues, issueAt(dto.ConsistencyUnexplainedJournal, at, e.MessageID,
+				"no message at this partition/offset: topic recreated without database reset or retention expired"))
+		}
+	}
+
+	for _, d := range dlq {
+		if !watched[d.Topic] {
+			continue
+		}
+		if d.Partition == nil || d.Offset == nil {
+			report.Issues = append(report.Issues, dto.ConsistencyIssue{Kind: dto.ConsistencyUnexplainedDLQ, Topic: d.Topic, MessageID: d.MessageID,
+				Detail: fmt.Sprintf("kafka_dlq id=%d has no partition/offset", d.ID)})
+			continue
+		}
+
+		at := coord{d.Topic, *d.Partition, *d.Offset}
+		if !inKafka[at] {
+			issue := issueAt(dto.ConsistencyUnexplainedDLQ, at, uuid.Nil, fmt.Sprintf("kafka_dlq id=%d: no message at this partition/offset", d.ID))
+			issue.MessageID = d.MessageID
+			report.Issues = append(report.Issues, issue)
+		}
+	}
+
+	reflection, err := c.checkReflected(ctx, journal)
+	if err != nil {
+		return report, err
+	}
+	report.Issues = append(report.Issues, reflection...)
+
+	report.Consistent = len(report.Issues) == 0
+	report.Duration = time.Since(started).Round(10 * time.Millisecond).String()
+
+	return report, nil
+}
+
+// checkReflected проверяет, что события журнала отражены в бизнес-таблицах. Для профиля
+// сверяется только последнее применённое событие hr.personal сотрудника — более ранние перезаписаны.
+func (c *Checker) checkReflected(ctx context.Context, journal []dto.KafkaEvent) ([]dto.ConsistencyIssue, error) {
+	var issues []dto.ConsistencyIssue
+
+	// ListEvents отдаёт журнал от новых к старым: первое событие сотрудника — последнее применённое
+	latestPersonal := make(map[string]bool)
+	positions := make(map[string][]dto.PositionAssignment)
+
+	for _, e := range journal {
+		at := coord{e.Topic, e.Partition, e.Offset}
+
+		switch e.Topic {
+		case c.topics.Personal:
+			var personal PersonalPayload
+			if err := json.Unmarshal(e.Payload, &personal); err != nil {
+				continue
+			}
+
+			if latestPersonal[personal.EmployeeID] {
+				continue
+			}
+			latestPersonal[personal.EmployeeID] = true
+
+			profile, err := c.profiles.GetProfile(ctx, personal.EmployeeID)
+			if errors.Is(err, dto.ErrNotFound) {
+				issues = append(issues, reflectionIssue(dto.ConsistencyNotReflected, at, e.MessageID, personal.EmployeeID, nil, "employee_profile row is missing"))
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("profiles.GetProfile: %w", err)
+			}
+
+			fields := diffFields(
+				personalFields{personal.FirstName, personal.LastName, personal.BirthDate, personal.Contacts.Email, personal.Contacts.Phone},
+				personalFields{profile.FirstName, profile.LastName, profile.BirthDate, profile.Email, profile.Phone},
+			)
+			if len(fields) > 0 {
+				issues = append(issues, reflectionIssue(dto.ConsistencyMismatch, at, e.MessageID, personal.EmployeeID, fields, "employee_profile differs from the latest hr.personal event"))
+			}
+		case c.topics.Positions:
+			var position PositionPayload
+			if err := json.Unmarshal(e.Payload, &position); err != nil {
+				continue
+			}
+
+			assignments, ok := positions[position.EmployeeID]
+			if !ok {
+				list, err := c.profiles.ListPositions(ctx, position.EmployeeID)
+				if err != nil {
+					return nil, fmt.Errorf("profiles.ListPositions: %w", err)
+				}
+				assignments = list
+				positions[position.EmployeeID] = list
+			}
+
+			assignment := findAssignment(assignments, position.EffectiveFrom)
+			if assignment == nil {
+				issues = append(issues, reflectionIssue(dto.ConsistencyNotReflected, at, e.MessageID, position.EmployeeID, nil,
+					fmt.Sprintf("position_assignment for effective_from=%s is missing", position.EffectiveFrom)))
+				continue
+			}
+
+			// назначение на ту же дату могло быть заменено более поздним событием
+			if assignment.MessageID == nil || *assignment.MessageID != e.MessageID {
+				continue
+			}
+
+			fields := diffFields(
+				positionFields{position.Title, position.Department, position.Grade},
+				positionFields{deref(assignment.Title), deref(assignment.Department), deref(assignment.Grade)},
+			)
+			if len(fields) > 0 {
+				issues = append(issues, reflectionIssue(dto.ConsistencyMismatch, at, e.MessageID, position.EmployeeID, fields, "position_assignment differs from hr.positions event"))
+			}
+		case c.topics.History:
+			var history HistoryPayload
+			if err := json.Unmarshal(e.Payload, &history); err != nil {
+				continue
+			}
+			if history.Stack == nil {
+				history.Stack = []string{}
+			}
+
+			rows, err := c.history.ListByMessageID(ctx, e.MessageID)
+			if err != nil {
+				return nil, fmt.Errorf("history.ListByMessageID: %w", err)
+			}
+			if len(rows) == 0 {
+				issues = append(issues, reflectionIssue(dto.ConsistencyNotReflected, at, e.MessageID, history.EmployeeID, nil, "employment_history row is missing"))
+				continue
+			}
+
+			row := rows[0]
+			if row.Stack == nil {
+				row.Stack = []string{}
+			}
+
+			fields := diffFields(
+				historyFields{history.EmployeeID, history.Company, history.Position, history.Period.From, history.Period.To, history.Stack},
+				historyFields{row.EmployeeID, row.Company, row.Position, row.PeriodFrom, row.PeriodTo, row.Stack},
+			)
+			if len(fields) > 0 {
+				issues = append(issues, reflectionIssue(dto.ConsistencyMismatch, at, e.MessageID, history.EmployeeID, fields, "employment_history differs from hr.history event"))
+			}
+		}
+	}
+
+	return issues, nil
+}
+
+// Сравниваемые поля payload и строки таблицы; json-имена попадают в ConsistencyIssue.Fields
+type personalFields struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	BirthDate string `json:"birth_date"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+}
+
+type positionFields struct {
+	Title      string `json:"title"`
+	Department string `json:"department"`
+	Grade      string `json:"grade"`
+}
+
+type historyFields struct {
+	EmployeeID string   `json:"employee_id"`
+	Company    string   `json:"company"`
+	Position   string   `json:"position"`
+	PeriodFrom string   `json:"period_from"`
+	PeriodTo   string   `json:"period_to"`
+	Stack      []string `json:"stack"`
+}
+
+func findAssignment(assignments []dto.PositionAssignment, effectiveFrom string) *dto.PositionAssignment {
+	for i := range assignments {
+		if assignments[i].EffectiveFrom == effectiveFrom {
+			return &assignments[i]
+		}
+	}
+
+	return nil
+}
+
+// committedOffset — committed offset группы для партиции; -1, если коммитов не было
+func committedOffset(committed map[coord]int64, at coord) int64 {
+	offset, ok := committed[coord{topic: at.topic, partition: at.partition}]
+	if !ok {
+		return -1
+	}
+
+	return offset
+}
+
+func issueAt(kind string, at coord, messageID uuid.UUID, detail string) dto.ConsistencyIssue {
+	partition, offset := at.partition, at.offset
+
+	return dto.ConsistencyIssue{
+		Kind:      kind,
+		Topic:     at.topic,
+		Partition: &partition,
+		Offset:    &offset,
+		MessageID: nullableUUID(messageID),
+		Detail:    detail,
+	}
+}
+
+func reflectionIssue(kind string, at coord, messageID uuid.UUID, employeeID string, fields []string, detail string) dto.ConsistencyIssue {
+	issue := issueAt(kind, at, messageID, detail)
+	issue.EmployeeID = employeeID
+	issue.Fields = fields
+
+	return issue
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
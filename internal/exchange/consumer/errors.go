@@ -0,0 +1,88 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// processError — ошибка обработки сообщения с явной классификацией:
+// retryable-ошибки уходят в retry-топики, остальные — сразу в DLQ.
+type processError struct {
+	reason    string
+	retryable bool
+	err       error // исходная ошибка репозитория, если есть
+}
+
+func (e *processError) Error() string { return e.reason }
+
+func (e *processError) Unwrap() error { return e.err }
+
+// fatalError — ошибка, которую повтор не исправит (валидация, битый JSON, нет профиля)
+func fatalError(reason string) error {
+	return &processError{reason: reason}
+}
+
+// profileNotFound — событие ссылается на сотрудника без профиля
+func profileNotFound(employeeID string) error {
+	return fatalError(fmt.Sprintf("employee_id=%s not found: create employee profile first", employeeID))
+}
+
+// retryableError — временный сбой, сообщение стоит обработать позже
+func retryableError(reason string) error {
+	return &processError{reason: reason, retryable: true}
+}
+
+// dbError классифицирует ошибку репозитория (см. IsRetryableDBError)
+func dbError(op string, err error) error {
+	return &processError{
+		reason:    fmt.Sprintf("%s: %v", op, err),
+		retryable: IsRetryableDBError(err),
+		err:       err,
+	}
+}
+
+// interrupted — обработку прервала отмена сессии (Stop, ребаланс, остановка
+// сервиса) или таймаут контекста. Сообщение тут ни при чём: его нельзя ни
+// отправлять в retry-ярус, ни класть в DLQ — только доставить повторно.
+func interrupted(ctx context.Context, err error) bool {
+	return ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// IsRetryable сообщает, стоит ли повторять обработку сообщения.
+// Неклассифицированные ошибки считаются фатальными.
+func IsRetryable(err error) bool {
+	var perr *processError
+	if errors.As(err, &perr) {
+		return perr.retryable
+	}
+
+	return false
+}
+
+// IsRetryableDBError — временные сбои Postgres: нет соединения, таймаут,
+// конфликт сериализации/deadlock, нехватка ресурсов, остановка сервера.
+// Нарушения ограничений и ошибки данных повтором не лечатся.
+func IsRetryableDBError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code[:2] {
+		case "08", // connection exception
+			"40", // transaction rollback (serialization failure, deadlock)
+			"53", // insufficient resources
+			"57": // operator intervention (admin shutdown, query canceled)
+			return true
+		default:
+			return false
+		}
+	}
+
+	// сетевые ошибки и таймауты приходят без кода Postgres
+	return true
+}
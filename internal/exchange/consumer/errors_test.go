@@ -0,0 +1,102 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func pgError(code string) error {
+	return &pgconn.PgError{Severity: "ERROR", Code: code, Message: "db failure"}
+}
+
+func TestIsRetryableDBError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "connection failure 08006", err: pgError("08006"), want: true},
+		{name: "serialization failure 40001", err: pgError("40001"), want: true},
+		{name: "deadlock 40P01", err: pgError("40P01"), want: true},
+		{name: "too many connections 53300", err: pgError("53300"), want: true},
+		{name: "admin shutdown 57P01", err: pgError("57P01"), want: true},
+		{name: "unique violation 23505", err: pgError("23505"), want: false},
+		{name: "foreign key violation 23503", err: pgError("23503"), want: false},
+		{name: "invalid text representation 22P02", err: pgError("22P02"), want: false},
+		{name: "wrapped retryable pg code", err: fmt.Errorf("tx.Commit: %w", pgError("40001")), want: true},
+		{name: "wrapped fatal pg code", err: fmt.Errorf("insert: %w", pgError("23505")), want: false},
+		{name: "no pg code", err: errors.New("dial tcp 127.0.0.1:5432: connect: connection refused"), want: true},
+		{name: "context canceled", err: context.Canceled, want: false},
+		{name: "wrapped context canceled", err: fmt.Errorf("query: %w", context.Canceled), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryableDBError(tt.err); got != tt.want {
+				t.Errorf("IsRetryableDBError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		want       bool
+		wantReason string
+	}{
+		{name: "fatal", err: fatalError("missing required field employee_id"), want: false, wantReason: "missing required field employee_id"},
+		{name: "retryable", err: retryableError("broker unavailable"), want: true, wantReason: "broker unavailable"},
+		{name: "profile not found", err: profileNotFound("E-1"), want: false, wantReason: "employee_id=E-1 not found: create employee profile first"},
+		{name: "db retryable", err: dbError("tx.Commit", pgError("40001")), want: true, wantReason: "tx.Commit: ERROR: db failure (SQLSTATE 40001)"},
+		{name: "db fatal", err: dbError("profiles.UpsertPosition", pgError("23505")), want: false, wantReason: "profiles.UpsertPosition: ERROR: db failure (SQLSTATE 23505)"},
+		{name: "db without pg code", err: dbError("events.Begin", errors.New("timeout")), want: true, wantReason: "events.Begin: timeout"},
+		{name: "db context canceled", err: dbError("events.Begin", context.Canceled), want: false, wantReason: "events.Begin: context canceled"},
+		{name: "wrapped retryable", err: fmt.Errorf("apply: %w", retryableError("timeout")), want: true, wantReason: "apply: timeout"},
+		{name: "wrapped fatal", err: fmt.Errorf("apply: %w", fatalError("bad grade")), want: false, wantReason: "apply: bad grade"},
+		{name: "unclassified", err: errors.New("boom"), want: false, wantReason: "boom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+			if got := tt.err.Error(); got != tt.wantReason {
+				t.Errorf("reason = %q, want %q", got, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestInterrupted(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{name: "session canceled", ctx: canceled, err: fatalError("bad grade"), want: true},
+		{name: "db call canceled", ctx: context.Background(), err: dbError("events.InsertDLQ", context.Canceled), want: true},
+		{name: "db call deadline", ctx: context.Background(), err: dbError("events.Begin", fmt.Errorf("connect: %w", context.DeadlineExceeded)), want: true},
+		{name: "fatal", ctx: context.Background(), err: fatalError("bad grade"), want: false},
+		{name: "db error", ctx: context.Background(), err: dbError("tx.Commit", pgError("40001")), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := interrupted(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("interrupted(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
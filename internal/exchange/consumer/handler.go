@@ -4,10 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+
 	"github.com/google/uuid"
 
+	"github.com/Artexxx/HR-Kafka-QA/internal/bugs"
 	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
 	"github.com/IBM/sarama"
+	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog"
 )
 
@@ -20,80 +24,176 @@ const (
 )
 
 type handler struct {
-	kind        kind
-	events      EventsRepository
-	profiles    ProfileRepository
-	history     HistoryRepository
-	log         zerolog.Logger
-	commitOnDLQ bool
+	kind           kind
+	events         EventsRepository
+	profiles       ProfileRepository
+	history        HistoryRepository
+	log            zerolog.Logger
+	commitOnDLQ    bool
+	dlqPublisher   MessagePublisher
+	dlqSuffix      string
+	retryPublisher MessagePublisher
+	retry          RetryPolicy
+	onClaim        func(topic string, partition int32)
+	onProcessed    func(msg *sarama.ConsumerMessage)
+	positionPolicy *PositionPolicy
+	newGroup       GroupFactory
+	bugs           *bugs.Catalog
+	chaos          *Chaos
 }
 
 func (h *handler) Setup(_ sarama.ConsumerGroupSession) error   { return nil }
 func (h *handler) Cleanup(_ sarama.ConsumerGroupSession) error { return nil }
 
 func (h *handler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	if h.onClaim != nil {
+		h.onClaim(claim.Topic(), claim.Partition())
+	}
+
 	for message := range claim.Messages() {
-		messageID, err := messageIDFromKey(message)
-		if err != nil {
-			h.toDLQ(sess.Context(), message, fmt.Sprintf("error in message_id parse: %v", err))
-			if h.commitOnDLQ {
-				sess.MarkMessage(message, "")
-			}
-			continue
+		if !waitRetryDelay(sess.Context(), message) || !h.chaos.delay(sess.Context()) {
+			return nil
 		}
 
-		switch h.kind {
-		case kindPersonal:
-			var event PersonalPayload
-			if err := json.Unmarshal(message.Value, &event); err != nil {
-				h.toDLQ(sess.Context(), message, fmt.Sprintf("json.Unmarshal: %v", err))
-				if h.commitOnDLQ {
-					sess.MarkMessage(message, "")
-				}
-				continue
-			}
-			if ok := h.processPersonal(sess, message, messageID, event); ok {
-				sess.MarkMessage(message, "")
-			}
-		case kindPositions:
-			var event PositionPayload
-			if err := json.Unmarshal(message.Value, &event); err != nil {
-				h.toDLQ(sess.Context(), message, fmt.Sprintf("json.Unmarshal: %v", err))
-				if h.commitOnDLQ {
-					sess.MarkMessage(message, "")
-				}
-				continue
-			}
-			if ok := h.processPosition(sess, message, messageID, event); ok {
-				sess.MarkMessage(message, "")
+		if err := h.consume(sess, message); err != nil {
+			// после отмены сессии ошибка не нужна: claim и так завершается
+			if sess.Context().Err() != nil {
+				return nil
 			}
-		case kindHistory:
-			var event HistoryPayload
-			if err := json.Unmarshal(message.Value, &event); err != nil {
-				h.toDLQ(sess.Context(), message, fmt.Sprintf("json.Unmarshal: %v", err))
-				if h.commitOnDLQ {
-					sess.MarkMessage(message, "")
-				}
-				continue
-			}
-			if ok := h.processHistory(sess, message, messageID, event); ok {
-				sess.MarkMessage(message, "")
-			}
-		default:
-			h.log.Error().Str("kind", string(h.kind)).Msg("unknown consumer kind")
-			sess.MarkMessage(message, "")
+			return err
+		}
+
+		if h.onProcessed != nil {
+			h.onProcessed(message)
+		}
+	}
+	return nil
+}
+
+// consume обрабатывает сообщение и отмечает его offset. Паника (в том числе хаос
+// crash_percent) перехватывается: ConsumeClaim завершается без отметки, сессия
+// перезапускается, и сообщение доставляется повторно с последнего коммита.
+// Так же завершается claim, если offset коммитить нельзя: иначе отметка
+// следующего сообщения закоммитила бы offset поверх необработанного.
+func (h *handler) consume(sess sarama.ConsumerGroupSession, message *sarama.ConsumerMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("consumer crashed at %s/%d/%d, offset not committed: %v", message.Topic, message.Partition, message.Offset, r)
 		}
+	}()
+
+	if !h.handle(sessionContext(sess.Context(), message), message) {
+		return fmt.Errorf("message %s/%d/%d is not committed, redelivering from the last commit", message.Topic, message.Partition, message.Offset)
 	}
+
+	h.chaos.crash(message)
+	sess.MarkMessage(message, "")
+
 	return nil
 }
 
-func (h *handler) toDLQ(ctx context.Context, msg *sarama.ConsumerMessage, reason string) {
-	_ = h.events.InsertDLQ(ctx, dto.KafkaDLQ{
-		Topic:   msg.Topic,
-		Key:     string(msg.Key),
-		Payload: append([]byte(nil), msg.Value...),
-		Error:   reason,
+// handle обрабатывает одно сообщение; true — offset можно коммитить.
+func (h *handler) handle(ctx context.Context, message *sarama.ConsumerMessage) bool {
+	messageID, err := messageIDOf(message)
+	if err != nil {
+		return h.fail(ctx, message, uuid.Nil, fatalError(fmt.Sprintf("error in message_id parse: %v", err)))
+	}
+
+	switch h.kind {
+	case kindPersonal:
+		var event PersonalPayload
+		if err := json.Unmarshal(message.Value, &event); err != nil {
+			return h.fail(ctx, message, messageID, fatalError(fmt.Sprintf("json.Unmarshal: %v", err)))
+		}
+		err = h.processPersonal(ctx, message, messageID, event)
+	case kindPositions:
+		var event PositionPayload
+		if err := json.Unmarshal(message.Value, &event); err != nil {
+			return h.fail(ctx, message, messageID, fatalError(fmt.Sprintf("json.Unmarshal: %v", err)))
+		}
+		err = h.processPosition(ctx, message, messageID, event)
+	case kindHistory:
+		var event HistoryPayload
+		if err := json.Unmarshal(message.Value, &event); err != nil {
+			return h.fail(ctx, message, messageID, fatalError(fmt.Sprintf("json.Unmarshal: %v", err)))
+		}
+		err = h.processHistory(ctx, message, messageID, event)
+	default:
+		h.log.Error().Str("kind", string(h.kind)).Msg("unknown consumer kind")
+		return true
+	}
+
+	if err != nil {
+		return h.fail(ctx, message, messageID, err)
+	}
+
+	return true
+}
+
+// fail маршрутизирует ошибку обработки: retryable — в следующий retry-ярус,
+// фатальные и исчерпавшие ярусы — в DLQ. Прерванная обработка и несохранённая
+// запись DLQ offset не коммитят: сообщение будет доставлено повторно.
+func (h *handler) fail(ctx context.Context, msg *sarama.ConsumerMessage, messageID uuid.UUID, err error) bool {
+	reason := err.Error()
+
+	if interrupted(ctx, err) {
+		h.log.Warn().
+			Err(err).
+			Str("topic", msg.Topic).
+			Int64("offset", msg.Offset).
+			Msg("processing interrupted, offset not committed")
+		return false
+	}
+
+	if IsRetryable(err) {
+		scheduled, rerr := h.scheduleRetry(msg, reason)
+		if rerr != nil {
+			h.log.Error().Err(rerr).Str("topic", msg.Topic).Int64("offset", msg.Offset).Msg("retry failed, fallback to DLQ")
+		}
+		if scheduled {
+			h.recordDecision(ctx, msg, messageID, dto.DecisionRetry, reason)
+			return true
+		}
+	}
+
+	if h.bugs.Enabled(bugs.CommitBeforeDLQ) {
+		return true
+	}
+
+	if err := h.toDLQ(ctx, msg, messageID, reason); err != nil {
+		h.log.Error().
+			Err(err).
+			Str("topic", msg.Topic).
+			Int64("offset", msg.Offset).
+			Msg("failed to save message to DLQ, offset not committed")
+		return false
+	}
+
+	return h.commitOnDLQ
+}
+
+// toDLQ сохраняет сообщение в kafka_dlq; ошибка — запись не сохранена,
+// и коммитить offset нельзя.
+func (h *handler) toDLQ(ctx context.Context, msg *sarama.ConsumerMessage, messageID uuid.UUID, reason string) error {
+	topic, origPartition, origOffset := messageOrigin(msg)
+	partition := int(origPartition)
+	offset := origOffset
+
+	err := h.events.InsertDLQ(ctx, dto.KafkaDLQ{
+		MessageID: nullableUUID(messageID),
+		Topic:     topic,
+		Partition: &partition,
+		Offset:    &offset,
+		Key:       string(msg.Key),
+		Payload:   append([]byte(nil), msg.Value...),
+		Error:     reason,
 	})
+	if err != nil {
+		return fmt.Errorf("events.InsertDLQ: %w", err)
+	}
+
+	h.recordDecision(ctx, msg, messageID, dto.DecisionDLQ, reason)
+	h.publishDLQ(msg, reason)
 
 	h.log.Warn().
 		Str("topic", msg.Topic).
@@ -101,9 +201,184 @@ func (h *handler) toDLQ(ctx context.Context, msg *sarama.ConsumerMessage, reason
 		Int64("offset", msg.Offset).
 		Str("reason", reason).
 		Msg("message sent to DLQ")
+
+	return nil
 }
 
-func messageIDFromKey(msg *sarama.ConsumerMessage) (uuid.UUID, error) {
+// publishDLQ дублирует сообщение в DLQ-топик, если режим включён (WithDLQTopic).
+func (h *handler) publishDLQ(msg *sarama.ConsumerMessage, reason string) {
+	if h.dlqPublisher == nil {
+		return
+	}
+
+	topic, partition, offset := messageOrigin(msg)
+
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+4)
+	for _, header := range msg.Headers {
+		if header != nil && !isRetryHeader(string(header.Key)) {
+			headers = append(headers, *header)
+		}
+	}
+
+	headers = append(headers,
+		sarama.RecordHeader{Key: []byte(headerErrorReason), Value: []byte(reason)},
+		sarama.RecordHeader{Key: []byte(headerOriginalTopic), Value: []byte(topic)},
+		sarama.RecordHeader{Key: []byte(headerOriginalPartition), Value: []byte(strconv.Itoa(int(partition)))},
+		sarama.RecordHeader{Key: []byte(headerOriginalOffset), Value: []byte(strconv.FormatInt(offset, 10))},
+	)
+	if attempt := retryAttempt(msg); attempt > 0 {
+		headers = append(headers, sarama.RecordHeader{Key: []byte(headerRetryAttempt), Value: []byte(strconv.Itoa(attempt))})
+	}
+
+	dlqMsg := &sarama.ProducerMessage{
+		Topic:   topic + h.dlqSuffix,
+		Value:   sarama.ByteEncoder(msg.Value),
+		Headers: headers,
+	}
+	if msg.Key != nil {
+		dlqMsg.Key = sarama.ByteEncoder(msg.Key)
+	}
+
+	if _, _, err := h.dlqPublisher.SendMessage(dlqMsg); err != nil {
+		h.log.Error().
+			Err(err).
+			Str("topic", dlqMsg.Topic).
+			Int64("offset", msg.Offset).
+			Msg("failed to publish message to DLQ topic")
+	}
+}
+
+// recordDecision сохраняет решение по сообщению для GET /messages/{message_id};
+// ошибка записи не влияет на обработку самого сообщения.
+func (h *handler) recordDecision(ctx context.Context, msg *sarama.ConsumerMessage, messageID uuid.UUID, decision, reason string) {
+	err := h.events.InsertDecision(ctx, dto.ConsumerDecision{
+		MessageID: nullableUUID(messageID),
+		Topic:     msg.Topic,
+		Partition: int(msg.Partition),
+		Offset:    msg.Offset,
+		Decision:  decision,
+		Reason:    reason,
+	})
+	if err != nil {
+		h.log.Warn().
+			Err(err).
+			Str("topic", msg.Topic).
+			Int64("offset", msg.Offset).
+			Str("decision", decision).
+			Msg("failed to record consumer decision")
+	}
+}
+
+// applyTx атомарно применяет сообщение: захват message_id в журнале kafka_events
+// (INSERT ... ON CONFLICT DO NOTHING) и apply (валидация и запись в бизнес-таблицу)
+// выполняются в одной транзакции. Любая ошибка откатывает всё целиком, в том числе
+// захват, поэтому повторная доставка не упирается в «полуприменённое» сообщение.
+// applied=false — дубль: message_id уже применён этим или параллельным консьюмером.
+func (h *handler) applyTx(ctx context.Context, msg *sarama.ConsumerMessage, messageID uuid.UUID, apply func(tx pgx.Tx) error) (bool, error) {
+	tx, err := h.events.Begin(ctx)
+	if err != nil {
+		return false, dbError("events.Begin", err)
+	}
+	defer func() { _ = tx.Rollback(context.WithoutCancel(ctx)) }()
+
+	claimed, err := h.events.ClaimMessageTx(ctx, tx, journalEvent(msg, messageID))
+	if err != nil {
+		return false, dbError("events.ClaimMessage", err)
+	}
+
+	if !claimed {
+		return false, nil
+	}
+
+	if err := apply(tx); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, dbError("tx.Commit", err)
+	}
+
+	return true, nil
+}
+
+// reapplyTx применяет уже журналированное сообщение повторно, без захвата message_id
+// (дефект bugs.SkipIdempotency).
+func (h *handler) reapplyTx(ctx context.Context, apply func(tx pgx.Tx) error) error {
+	tx, err := h.events.Begin(ctx)
+	if err != nil {
+		return dbError("events.Begin", err)
+	}
+	defer func() { _ = tx.Rollback(context.WithoutCancel(ctx)) }()
+
+	if err := apply(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return dbError("tx.Commit", err)
+	}
+
+	return nil
+}
+
+// journalEvent — запись журнала kafka_events; retry-сообщения журналируются
+// с координатами исходного сообщения.
+func journalEvent(msg *sarama.ConsumerMessage, messageID uuid.UUID) dto.KafkaEvent {
+	topic, partition, offset := messageOrigin(msg)
+
+	return dto.KafkaEvent{
+		MessageID: messageID,
+		Topic:     topic,
+		Partition: int(partition),
+		Offset:    offset,
+		Payload:   append([]byte(nil), msg.Value...),
+	}
+}
+
+func nullableUUID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+
+	return &id
+}
+
+// sessionContext — контекст обработки сообщения: журнал, DLQ и бизнес-таблицы пишутся
+// в пространство сессии из заголовка session-id; без заголовка — в общую сессию.
+func sessionContext(ctx context.Context, msg *sarama.ConsumerMessage) context.Context {
+	return dto.WithSession(ctx, sessionOf(msg))
+}
+
+func sessionOf(msg *sarama.ConsumerMessage) string {
+	session, _ := header(msg, dto.HeaderSessionID)
+
+	return session
+}
+
+// messageIDOf ищет message_id по порядку: заголовок message-id, поле message_id
+// в теле, ключ-UUID. Так принимаются оба режима ключа продюсера.
+func messageIDOf(msg *sarama.ConsumerMessage) (uuid.UUID, error) {
+	if v, ok := header(msg, dto.HeaderMessageID); ok && v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("invalid message_id in header %s: %w", dto.HeaderMessageID, err)
+		}
+
+		return id, nil
+	}
+
+	var envelope struct {
+		MessageID string `json:"message_id"`
+	}
+	if err := json.Unmarshal(msg.Value, &envelope); err == nil && envelope.MessageID != "" {
+		id, err := uuid.Parse(envelope.MessageID)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("invalid message_id in body: %w", err)
+		}
+
+		return id, nil
+	}
+
 	if len(msg.Key) == 0 {
 		return uuid.Nil, fmt.Errorf("missing required field message_id")
 	}
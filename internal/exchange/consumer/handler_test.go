@@ -0,0 +1,225 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/Artexxx/HR-Kafka-QA/internal/repository/events"
+	"github.com/Artexxx/HR-Kafka-QA/internal/repository/memory"
+	"github.com/Artexxx/HR-Kafka-QA/internal/repository/profile"
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// deliveries — сколько раз одно и то же сообщение доставляется параллельно
+const deliveries = 16
+
+// decisionLister — журнал решений консьюмера (есть у обеих реализаций EventsRepository)
+type decisionLister interface {
+	ListDecisionsByMessageID(ctx context.Context, messageID uuid.UUID) ([]dto.ConsumerDecision, error)
+}
+
+func TestDuplicateDeliveryAppliedOnceMemory(t *testing.T) {
+	store := memory.NewStore()
+	eventsRepo := memory.NewEventsRepository(store)
+
+	testDuplicateDeliveryAppliedOnce(t, eventsRepo, memory.NewProfileRepository(store), eventsRepo)
+}
+
+// TestDuplicateDeliveryAppliedOncePostgres гоняет тот же сценарий на Postgres из
+// TEST_POSTGRES_DSN (схема накатана миграциями); message_id каждый раз новый,
+// поэтому данные базы не очищаются.
+func TestDuplicateDeliveryAppliedOncePostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("pgxpool.New: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	eventsRepo := events.NewRepository(pool)
+	testDuplicateDeliveryAppliedOnce(t, eventsRepo, profile.NewRepository(pool), eventsRepo)
+}
+
+// testDuplicateDeliveryAppliedOnce доставляет одно сообщение из нескольких горутин
+// сразу (как два экземпляра консьюмера или ребаланс) и проверяет, что применено оно
+// ровно один раз, а остальные доставки отмечены как дубли.
+func testDuplicateDeliveryAppliedOnce(t *testing.T, eventsRepo EventsRepository, profiles ProfileRepository, decisions decisionLister) {
+	t.Helper()
+
+	h := &handler{
+		kind:        kindPersonal,
+		events:      eventsRepo,
+		profiles:    profiles,
+		log:         zerolog.Nop(),
+		commitOnDLQ: true,
+	}
+
+	messageID := uuid.New()
+	msg := personalMessage(t, messageID, PersonalPayload{
+		EmployeeID: "E-" + messageID.String()[:8],
+		FirstName:  "Иван",
+		LastName:   "Петров",
+		BirthDate:  "1990-01-01",
+	})
+
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if !h.handle(ctx, msg) {
+				t.Errorf("handle: offset is not committable")
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	got, err := decisions.ListDecisionsByMessageID(ctx, messageID)
+	if err != nil {
+		t.Fatalf("ListDecisionsByMessageID: %v", err)
+	}
+
+	count := make(map[string]int)
+	for _, d := range got {
+		count[d.Decision]++
+	}
+
+	if count[dto.DecisionApplied] != 1 || count[dto.DecisionDuplicate] != deliveries-1 || len(got) != deliveries {
+		t.Fatalf("decisions = %v, want 1 %s and %d %s", count, dto.DecisionApplied, deliveries-1, dto.DecisionDuplicate)
+	}
+}
+
+func personalMessage(t *testing.T, messageID uuid.UUID, payload PersonalPayload) *sarama.ConsumerMessage {
+	t.Helper()
+
+	payload.Contacts.Email = "ivan@example.com"
+	payload.Contacts.Phone = "+79990000000"
+
+	value, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	return &sarama.ConsumerMessage{
+		Topic:   "hr.personal",
+		Key:     []byte(payload.EmployeeID),
+		Value:   value,
+		Headers: []*sarama.RecordHeader{{Key: []byte(dto.HeaderMessageID), Value: []byte(messageID.String())}},
+	}
+}
+
+// TestFailNotCommitted — прерванная обработка и несохранённая запись DLQ
+// не коммитят offset: сообщение доставляется повторно, а не теряется.
+func TestFailNotCommitted(t *testing.T) {
+	store := memory.NewStore()
+	eventsRepo := memory.NewEventsRepository(store)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		events EventsRepository
+		err    error
+	}{
+		{name: "session canceled", ctx: canceled, events: eventsRepo, err: fatalError("bad grade")},
+		{name: "db call canceled", ctx: context.Background(), events: eventsRepo, err: dbError("events.Begin", context.Canceled)},
+		{name: "DLQ write failed", ctx: context.Background(), events: failingDLQ{eventsRepo}, err: fatalError("bad grade")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &handler{kind: kindPersonal, events: tt.events, log: zerolog.Nop(), commitOnDLQ: true}
+
+			messageID := uuid.New()
+			msg := personalMessage(t, messageID, PersonalPayload{EmployeeID: "E-1"})
+
+			if h.fail(tt.ctx, msg, messageID, tt.err) {
+				t.Fatal("fail: offset is committable")
+			}
+
+			rows, err := eventsRepo.ListDLQByMessageID(context.Background(), messageID)
+			if err != nil {
+				t.Fatalf("ListDLQByMessageID: %v", err)
+			}
+			if len(rows) != 0 {
+				t.Errorf("DLQ rows = %+v, want none", rows)
+			}
+		})
+	}
+}
+
+// failingDLQ — журнал, который не может сохранить запись DLQ
+type failingDLQ struct {
+	EventsRepository
+}
+
+func (failingDLQ) InsertDLQ(context.Context, dto.KafkaDLQ) error {
+	return errors.New("connection refused")
+}
+
+func TestMessageIDOf(t *testing.T) {
+	headerID, bodyID, keyID := uuid.New(), uuid.New(), uuid.New()
+
+	body := func(id string) []byte { return []byte(`{"message_id": "` + id + `", "employee_id": "E-1"}`) }
+	withHeader := func(v string) []*sarama.RecordHeader {
+		return []*sarama.RecordHeader{{Key: []byte(dto.HeaderMessageID), Value: []byte(v)}}
+	}
+
+	tests := []struct {
+		name    string
+		msg     *sarama.ConsumerMessage
+		want    uuid.UUID
+		wantErr string
+	}{
+		{name: "header", msg: &sarama.ConsumerMessage{Key: []byte("E-1"), Value: []byte(`{}`), Headers: withHeader(headerID.String())}, want: headerID},
+		{name: "body", msg: &sarama.ConsumerMessage{Key: []byte("E-1"), Value: body(bodyID.String())}, want: bodyID},
+		{name: "key", msg: &sarama.ConsumerMessage{Key: []byte(keyID.String()), Value: []byte(`{"employee_id": "E-1"}`)}, want: keyID},
+		{name: "key with non-JSON body", msg: &sarama.ConsumerMessage{Key: []byte(keyID.String()), Value: []byte("not json")}, want: keyID},
+		{name: "empty header falls back to body", msg: &sarama.ConsumerMessage{Value: body(bodyID.String()), Headers: withHeader("")}, want: bodyID},
+		{name: "header wins over body", msg: &sarama.ConsumerMessage{Key: []byte(keyID.String()), Value: body(bodyID.String()), Headers: withHeader(headerID.String())}, want: headerID},
+		{name: "body wins over key", msg: &sarama.ConsumerMessage{Key: []byte(keyID.String()), Value: body(bodyID.String())}, want: bodyID},
+		{name: "invalid header", msg: &sarama.ConsumerMessage{Value: body(bodyID.String()), Headers: withHeader("42")}, wantErr: "invalid message_id in header message-id"},
+		{name: "invalid body", msg: &sarama.ConsumerMessage{Key: []byte(keyID.String()), Value: body("42")}, wantErr: "invalid message_id in body"},
+		{name: "invalid key", msg: &sarama.ConsumerMessage{Key: []byte("E-1"), Value: []byte(`{}`)}, wantErr: "invalid message_id in key"},
+		{name: "missing", msg: &sarama.ConsumerMessage{Value: []byte(`{}`)}, wantErr: "missing required field message_id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := messageIDOf(tt.msg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("messageIDOf: err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("messageIDOf: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("messageIDOf = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,12 +1,14 @@
 package consumer
 
 import (
+	"context"
 	"errors"
-	"fmt"
 
+	"github.com/Artexxx/HR-Kafka-QA/internal/bugs"
 	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
 	"github.com/IBM/sarama"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog"
 )
 
@@ -18,6 +20,7 @@ func NewHistoryRunner(
 	profiles ProfileRepository,
 	history HistoryRepository,
 	log zerolog.Logger,
+	opts ...Option,
 ) *Runner {
 	h := &handler{
 		kind:        kindHistory,
@@ -28,62 +31,57 @@ func NewHistoryRunner(
 		commitOnDLQ: true,
 	}
 
-	return newRunner(bootstrap, groupID, topic, h, log)
+	return newRunner(bootstrap, "history", groupID, topic, h, log, opts)
 }
 
-func (h *handler) processHistory(sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage, messageId uuid.UUID, history HistoryPayload) bool {
-	ctx := sess.Context()
-
+func (h *handler) processHistory(ctx context.Context, msg *sarama.ConsumerMessage, messageId uuid.UUID, history HistoryPayload) error {
 	if messageId == uuid.Nil {
-		h.toDLQ(ctx, msg, "missing required field message_id")
-		return h.commitOnDLQ
+		return fatalError("missing required field message_id")
 	}
 
 	if history.EmployeeID == "" {
-		h.toDLQ(ctx, msg, "missing required field employee_id")
-		return h.commitOnDLQ
+		return fatalError("missing required field employee_id")
 	}
 
 	if _, err := h.profiles.GetProfile(ctx, history.EmployeeID); err != nil {
 		if errors.Is(err, dto.ErrNotFound) {
-			h.toDLQ(ctx, msg, fmt.Sprintf("employee_id=%s not found: create employee profile first", history.EmployeeID))
-		}
-
-		if !errors.Is(err, dto.ErrNotFound) {
-			h.toDLQ(ctx, msg, fmt.Sprintf("profiles.GetProfile: db error get profile: %v", err))
+			return profileNotFound(history.EmployeeID)
 		}
 
-		return h.commitOnDLQ
+		return dbError("profiles.GetProfile: db error get profile", err)
 	}
 
-	exists, err := h.events.ExistsMessage(ctx, messageId)
+	applied, err := h.applyTx(ctx, msg, messageId, func(tx pgx.Tx) error {
+		if h.bugs.Every(bugs.DropHistory, bugs.DropHistoryEvery) {
+			return nil
+		}
+
+		return h.applyHistory(ctx, tx, messageId, history)
+	})
 	if err != nil {
-		h.toDLQ(ctx, msg, fmt.Sprintf("events.ExistsMessage: db error exists: %s", err.Error()))
-		return h.commitOnDLQ
+		return err
 	}
-	if exists {
+
+	if !applied {
 		h.log.Info().Str("message_id", messageId.String()).Str("employee_id", history.EmployeeID).Msg("duplicate message, skip (idempotency)")
-		return true
+		h.recordDecision(ctx, msg, messageId, dto.DecisionDuplicate, "")
+		return nil
 	}
 
+	h.recordDecision(ctx, msg, messageId, dto.DecisionApplied, "")
+
+	return nil
+}
+
+// applyHistory валидирует событие hr.history и добавляет запись в историю;
+// общая логика консьюмера и пересборки проекций (Projector).
+func (h *handler) applyHistory(ctx context.Context, tx pgx.Tx, messageId uuid.UUID, history HistoryPayload) error {
 	if history.Stack == nil {
 		history.Stack = []string{}
 	}
 
 	if verr := validateHistory(history); verr != "" {
-		h.toDLQ(ctx, msg, verr)
-		return h.commitOnDLQ
-	}
-
-	if err := h.events.InsertEvent(ctx, dto.KafkaEvent{
-		MessageID: messageId,
-		Topic:     msg.Topic,
-		Partition: int(msg.Partition),
-		Offset:    msg.Offset,
-		Payload:   append([]byte(nil), msg.Value...),
-	}); err != nil {
-		h.toDLQ(ctx, msg, fmt.Sprintf("events.InsertEvent: %s", err.Error()))
-		return h.commitOnDLQ
+		return fatalError(verr)
 	}
 
 	hDto := dto.EmploymentHistory{
@@ -93,13 +91,12 @@ func (h *handler) processHistory(sess sarama.ConsumerGroupSession, msg *sarama.C
 		PeriodFrom: history.Period.From,
 		PeriodTo:   history.Period.To,
 		Stack:      history.Stack,
+		MessageID:  &messageId,
 	}
 
-	if err := h.history.Insert(ctx, hDto); err != nil {
-		h.toDLQ(ctx, msg, fmt.Sprintf("history.Insert: %s", err.Error()))
-
-		return h.commitOnDLQ
+	if err := h.history.InsertTx(ctx, tx, hDto); err != nil {
+		return dbError("history.Insert", err)
 	}
 
-	return true
+	return nil
 }
@@ -1,11 +1,13 @@
 package consumer
 
 import (
-	"fmt"
+	"context"
 
+	"github.com/Artexxx/HR-Kafka-QA/internal/bugs"
 	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
 	"github.com/IBM/sarama"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog"
 )
 
@@ -16,6 +18,7 @@ func NewPersonalRunner(
 	events EventsRepository,
 	profiles ProfileRepository,
 	log zerolog.Logger,
+	opts ...Option,
 ) *Runner {
 	h := &handler{
 		kind:        kindPersonal,
@@ -26,51 +29,53 @@ func NewPersonalRunner(
 		commitOnDLQ: true,
 	}
 
-	return newRunner(bootstrap, groupID, topic, h, log)
+	return newRunner(bootstrap, "personal", groupID, topic, h, log, opts)
 }
 
-func (h *handler) processPersonal(sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage, messageId uuid.UUID, personal PersonalPayload) bool {
-	ctx := sess.Context()
-
+func (h *handler) processPersonal(ctx context.Context, msg *sarama.ConsumerMessage, messageId uuid.UUID, personal PersonalPayload) error {
 	if messageId == uuid.Nil {
-		h.toDLQ(ctx, msg, "missing required field message_id")
-		return h.commitOnDLQ
+		return fatalError("missing required field message_id")
 	}
 
 	if personal.EmployeeID == "" {
-		h.toDLQ(ctx, msg, "missing required field employee_id")
-		return h.commitOnDLQ
+		return fatalError("missing required field employee_id")
 	}
 
-	exists, err := h.events.ExistsMessage(ctx, messageId)
+	applied, err := h.applyTx(ctx, msg, messageId, func(tx pgx.Tx) error {
+		return h.applyPersonal(ctx, tx, personal)
+	})
 	if err != nil {
-		h.toDLQ(ctx, msg, fmt.Sprintf("events.ExistsMessage: %v", err))
-		return h.commitOnDLQ
+		return err
+	}
+
+	if !applied && h.bugs.Enabled(bugs.SkipIdempotency) {
+		applied, err = true, h.reapplyTx(ctx, func(tx pgx.Tx) error {
+			return h.applyPersonal(ctx, tx, personal)
+		})
+		if err != nil {
+			return err
+		}
 	}
 
-	if exists {
+	if !applied {
 		h.log.Info().
 			Str("message_id", messageId.String()).
 			Str("employee_id", personal.EmployeeID).
 			Msg("duplicate message, skip (idempotency)")
-		return true
+		h.recordDecision(ctx, msg, messageId, dto.DecisionDuplicate, "")
+		return nil
 	}
 
-	if verr := validatePersonal(personal); verr != "" {
-		h.toDLQ(ctx, msg, verr)
-		return h.commitOnDLQ
-	}
+	h.recordDecision(ctx, msg, messageId, dto.DecisionApplied, "")
 
-	if err := h.events.InsertEvent(ctx, dto.KafkaEvent{
-		MessageID: messageId,
-		Topic:     msg.Topic,
-		Partition: int(msg.Partition),
-		Offset:    msg.Offset,
-		Payload:   append([]byte(nil), msg.Value...),
-	}); err != nil {
-		h.toDLQ(ctx, msg, fmt.Sprintf("events.InsertEvent: db error insert personal: %s", err.Error()))
+	return nil
+}
 
-		return h.commitOnDLQ
+// applyPersonal валидирует событие hr.personal и записывает его в профиль;
+// общая логика консьюмера и пересборки проекций (Projector).
+func (h *handler) applyPersonal(ctx context.Context, tx pgx.Tx, personal PersonalPayload) error {
+	if verr := validatePersonal(personal); verr != "" {
+		return fatalError(verr)
 	}
 
 	employee := dto.EmployeeProfile{
@@ -82,10 +87,13 @@ func (h *handler) processPersonal(sess sarama.ConsumerGroupSession, msg *sarama.
 		Phone:      personal.Contacts.Phone,
 	}
 
-	if err := h.profiles.UpsertPersonal(ctx, employee); err != nil {
-		h.toDLQ(ctx, msg, fmt.Sprintf("profiles.UpsertPersonal: %v", err))
-		return h.commitOnDLQ
+	if h.bugs.Enabled(bugs.SwapNames) {
+		employee.FirstName, employee.LastName = employee.LastName, employee.FirstName
+	}
+
+	if err := h.profiles.UpsertPersonalTx(ctx, tx, employee); err != nil {
+		return dbError("profiles.UpsertPersonal", err)
 	}
 
-	return true
+	return nil
 }
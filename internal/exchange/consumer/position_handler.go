@@ -1,12 +1,15 @@
 package consumer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
+	"github.com/Artexxx/HR-Kafka-QA/internal/bugs"
 	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
 	"github.com/IBM/sarama"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog"
 )
 
@@ -17,6 +20,7 @@ func NewPositionsRunner(
 	events EventsRepository,
 	profiles ProfileRepository,
 	log zerolog.Logger,
+	opts ...Option,
 ) *Runner {
 	h := &handler{
 		kind:        kindPositions,
@@ -27,59 +31,105 @@ func NewPositionsRunner(
 		commitOnDLQ: true,
 	}
 
-	return newRunner(bootstrap, groupID, topic, h, log)
+	return newRunner(bootstrap, "positions", groupID, topic, h, log, opts)
 }
-func (h *handler) processPosition(sess sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage, messageId uuid.UUID, position PositionPayload) bool {
-	ctx := sess.Context()
-
+func (h *handler) processPosition(ctx context.Context, msg *sarama.ConsumerMessage, messageId uuid.UUID, position PositionPayload) error {
 	if messageId == uuid.Nil {
-		h.toDLQ(ctx, msg, "missing required field message_id")
-		return h.commitOnDLQ
+		return fatalError("missing required field message_id")
 	}
 
 	if position.EmployeeID == "" {
-		h.toDLQ(ctx, msg, "missing required field employee_id")
-		return h.commitOnDLQ
+		return fatalError("missing required field employee_id")
 	}
 
 	if _, err := h.profiles.GetProfile(ctx, position.EmployeeID); err != nil {
 		if errors.Is(err, dto.ErrNotFound) {
-			h.toDLQ(ctx, msg, fmt.Sprintf("employee_id=%s not found: create employee profile first", position.EmployeeID))
-		}
-
-		if !errors.Is(err, dto.ErrNotFound) {
-			h.toDLQ(ctx, msg, fmt.Sprintf("profiles.GetProfile: db error get profile: %v", err))
+			return profileNotFound(position.EmployeeID)
 		}
 
-		return h.commitOnDLQ
+		return dbError("profiles.GetProfile: db error get profile", err)
 	}
 
-	exists, err := h.events.ExistsMessage(ctx, messageId)
-	if err != nil {
-		h.toDLQ(ctx, msg, fmt.Sprintf("events.ExistsMessage: %v", err))
+	var decision, reason string
 
-		return h.commitOnDLQ
+	applied, err := h.applyTx(ctx, msg, messageId, func(tx pgx.Tx) (err error) {
+		decision, reason, err = h.applyPosition(ctx, tx, messageId, position)
+		return err
+	})
+	if err != nil {
+		return err
 	}
-	if exists {
+
+	if !applied {
 		h.log.Info().Str("message_id", messageId.String()).Str("employee_id", position.EmployeeID).Msg("duplicate message, skip (idempotency)")
-		return true
+		h.recordDecision(ctx, msg, messageId, dto.DecisionDuplicate, "")
+		return nil
+	}
+
+	if reason != "" {
+		h.log.Info().Str("message_id", messageId.String()).Str("employee_id", position.EmployeeID).Str("decision", decision).Msg(reason)
+	}
+
+	h.recordDecision(ctx, msg, messageId, decision, reason)
+
+	return nil
+}
+
+// applyPosition валидирует событие hr.positions и применяет его с учётом политики
+// конфликтов; общая логика консьюмера и пересборки проекций (Projector).
+func (h *handler) applyPosition(ctx context.Context, tx pgx.Tx, messageId uuid.UUID, position PositionPayload) (decision, reason string, err error) {
+	decision = dto.DecisionApplied
+
+	if verr := validatePosition(position, h.bugs.Enabled(bugs.AcceptInvalidGrade)); verr != "" {
+		return "", "", fatalError(verr)
 	}
 
-	if verr := validatePosition(position); verr != "" {
-		h.toDLQ(ctx, msg, verr)
-		return h.commitOnDLQ
+	current, err := h.profiles.LockEffectiveFromTx(ctx, tx, position.EmployeeID)
+	if err != nil {
+		return "", "", dbError("profiles.LockEffectiveFrom", err)
+	}
+
+	// даты уже провалидированы как YYYY-MM-DD, строки сравниваются как даты
+	if current != "" && position.EffectiveFrom < current {
+		stale := fmt.Sprintf("stale position event: effective_from=%s is older than current %s", position.EffectiveFrom, current)
+
+		switch h.positionPolicy.Get() {
+		case dto.PositionPolicyRejectStale:
+			return "", "", fatalError(stale)
+		case dto.PositionPolicyEffectiveFrom:
+			decision, reason = dto.DecisionStale, stale
+		default:
+			reason = stale + ", applied by arrival"
+
+			// последнее по приходу побеждает: более поздние назначения отменяются,
+			// иначе чтение по effective_from показывало бы их, а не это событие
+			if err := h.profiles.SupersedeAssignmentsTx(ctx, tx, position.EmployeeID, position.EffectiveFrom); err != nil {
+				return "", "", dbError("profiles.SupersedeAssignments", err)
+			}
+		}
+
+		if err := h.events.MarkStaleTx(ctx, tx, messageId); err != nil {
+			return "", "", dbError("events.MarkStale", err)
+		}
+	}
+
+	// устаревшее событие тоже попадает в историю: при effective_from — более ранним
+	// назначением, при arrival — текущим вместо отменённых
+	assignment := dto.PositionAssignment{
+		EmployeeID:    position.EmployeeID,
+		Title:         &position.Title,
+		Department:    &position.Department,
+		Grade:         &position.Grade,
+		EffectiveFrom: position.EffectiveFrom,
+		MessageID:     &messageId,
 	}
 
-	if err := h.events.InsertEvent(ctx, dto.KafkaEvent{
-		MessageID: messageId,
-		Topic:     msg.Topic,
-		Partition: int(msg.Partition),
-		Offset:    msg.Offset,
-		Payload:   append([]byte(nil), msg.Value...),
-	}); err != nil {
-		h.toDLQ(ctx, msg, fmt.Sprintf("events.InsertEvent: db error insert position: %v", err))
+	if err := h.profiles.InsertAssignmentTx(ctx, tx, assignment); err != nil {
+		return "", "", dbError("profiles.InsertAssignment", err)
+	}
 
-		return h.commitOnDLQ
+	if decision == dto.DecisionStale {
+		return decision, reason, nil
 	}
 
 	payload := dto.EmployeeProfile{
@@ -90,11 +140,9 @@ func (h *handler) processPosition(sess sarama.ConsumerGroupSession, msg *sarama.
 		EffectiveFrom: &position.EffectiveFrom,
 	}
 
-	if err := h.profiles.UpsertPosition(ctx, payload); err != nil {
-		h.toDLQ(ctx, msg, fmt.Sprintf("profiles.UpsertPosition: db error upsert position: %v", err))
-
-		return h.commitOnDLQ
+	if err := h.profiles.UpsertPositionTx(ctx, tx, payload); err != nil {
+		return "", "", dbError("profiles.UpsertPosition: db error upsert position", err)
 	}
 
-	return true
+	return decision, reason, nil
 }
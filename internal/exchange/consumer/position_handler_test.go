@@ -0,0 +1,282 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/Artexxx/HR-Kafka-QA/internal/repository/memory"
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const testEmployeeID = "E-1"
+
+// TestApplyPositionPolicy — событие с effective_from старше текущего обрабатывается
+// по политике: решение, причина, флаг stale в журнале и запись DLQ для reject_stale.
+func TestApplyPositionPolicy(t *testing.T) {
+	const stale = "stale position event: effective_from=2024-01-01 is older than current 2024-03-01"
+
+	tests := []struct {
+		policy       string
+		wantDecision string
+		wantReason   string
+		wantJournal  bool
+		wantDLQ      bool
+	}{
+		{policy: dto.PositionPolicyArrival, wantDecision: dto.DecisionApplied, wantReason: stale + ", applied by arrival", wantJournal: true},
+		{policy: dto.PositionPolicyEffectiveFrom, wantDecision: dto.DecisionStale, wantReason: stale, wantJournal: true},
+		{policy: dto.PositionPolicyRejectStale, wantDecision: dto.DecisionDLQ, wantReason: stale, wantDLQ: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			st := newPositionStand(t, tt.policy)
+
+			current := st.send(t, "2024-03-01")
+			st.assertDecision(t, current, dto.DecisionApplied, "")
+			st.assertJournal(t, current, true, false)
+
+			older := st.send(t, "2024-01-01")
+			st.assertDecision(t, older, tt.wantDecision, tt.wantReason)
+			st.assertJournal(t, older, tt.wantJournal, true)
+
+			dlq, err := st.events.ListDLQByMessageID(context.Background(), older)
+			if err != nil {
+				t.Fatalf("ListDLQByMessageID: %v", err)
+			}
+			if tt.wantDLQ {
+				if len(dlq) != 1 || dlq[0].Error != tt.wantReason || dlq[0].Topic != "hr.positions" {
+					t.Errorf("DLQ = %+v, want one hr.positions entry with error %q", dlq, tt.wantReason)
+				}
+			} else if len(dlq) != 0 {
+				t.Errorf("DLQ = %+v, want none", dlq)
+			}
+
+			// событие не старше текущего не устаревшее при любой политике
+			newer := st.send(t, "2024-03-01")
+			st.assertDecision(t, newer, dto.DecisionApplied, "")
+			st.assertJournal(t, newer, true, false)
+		})
+	}
+}
+
+// TestPositionReadModelOutOfOrder — события приходят в порядке 03, 01, 02: профиль,
+// ?as_of=, история должностей и флаг stale согласованы с политикой конфликтов.
+func TestPositionReadModelOutOfOrder(t *testing.T) {
+	const (
+		mar = "2024-03-01"
+		jan = "2024-01-01"
+		feb = "2024-02-01"
+	)
+
+	tests := []struct {
+		policy    string
+		decisions []string          // решения по событиям в порядке прихода
+		journal   []string          // запись журнала: fresh, stale или none (откатана)
+		current   string            // effective_from должности в профиле
+		asOf      map[string]string // дата → effective_from должности на дату, пусто — должности нет
+		timeline  []string          // effective_from:status по возрастанию даты
+	}{
+		{
+			policy:    dto.PositionPolicyArrival,
+			decisions: []string{dto.DecisionApplied, dto.DecisionApplied, dto.DecisionApplied},
+			journal:   []string{"fresh", "stale", "fresh"},
+			current:   feb,
+			asOf:      map[string]string{"2023-12-31": "", "2024-01-15": jan, "2024-02-15": feb, "2024-03-15": feb},
+			timeline:  []string{jan + ":past", feb + ":current", mar + ":superseded"},
+		},
+		{
+			policy:    dto.PositionPolicyEffectiveFrom,
+			decisions: []string{dto.DecisionApplied, dto.DecisionStale, dto.DecisionStale},
+			journal:   []string{"fresh", "stale", "stale"},
+			current:   mar,
+			asOf:      map[string]string{"2023-12-31": "", "2024-01-15": jan, "2024-02-15": feb, "2024-03-15": mar},
+			timeline:  []string{jan + ":past", feb + ":past", mar + ":current"},
+		},
+		{
+			policy:    dto.PositionPolicyRejectStale,
+			decisions: []string{dto.DecisionApplied, dto.DecisionDLQ, dto.DecisionDLQ},
+			journal:   []string{"fresh", "none", "none"},
+			current:   mar,
+			asOf:      map[string]string{"2023-12-31": "", "2024-01-15": "", "2024-02-15": "", "2024-03-15": mar},
+			timeline:  []string{mar + ":current"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			st := newPositionStand(t, tt.policy)
+			ctx := context.Background()
+
+			for i, effectiveFrom := range []string{mar, jan, feb} {
+				messageID := st.send(t, effectiveFrom)
+
+				decisions, err := st.events.ListDecisionsByMessageID(ctx, messageID)
+				if err != nil {
+					t.Fatalf("ListDecisionsByMessageID: %v", err)
+				}
+				if len(decisions) != 1 || decisions[0].Decision != tt.decisions[i] {
+					t.Errorf("%s: decisions = %+v, want %s", effectiveFrom, decisions, tt.decisions[i])
+				}
+
+				st.assertJournal(t, messageID, tt.journal[i] != "none", tt.journal[i] == "stale")
+			}
+
+			profile, err := st.profiles.GetProfile(ctx, testEmployeeID)
+			if err != nil {
+				t.Fatalf("GetProfile: %v", err)
+			}
+			assertPosition(t, "GET", profile, tt.current)
+
+			for asOf, want := range tt.asOf {
+				profile, err := st.profiles.GetProfileAsOf(ctx, testEmployeeID, asOf)
+				if err != nil {
+					t.Fatalf("GetProfileAsOf %s: %v", asOf, err)
+				}
+				assertPosition(t, "as_of="+asOf, profile, want)
+			}
+
+			positions, err := st.profiles.ListPositions(ctx, testEmployeeID)
+			if err != nil {
+				t.Fatalf("ListPositions: %v", err)
+			}
+			var timeline []string
+			for _, a := range positions {
+				timeline = append(timeline, a.EffectiveFrom+":"+a.Status)
+			}
+			if !slices.Equal(timeline, tt.timeline) {
+				t.Errorf("timeline = %v, want %v", timeline, tt.timeline)
+			}
+
+			current, err := st.profiles.LockEffectiveFromTx(ctx, nil, testEmployeeID)
+			if err != nil {
+				t.Fatalf("LockEffectiveFromTx: %v", err)
+			}
+			if current != tt.current {
+				t.Errorf("current effective_from for stale check = %s, want %s", current, tt.current)
+			}
+		})
+	}
+}
+
+// assertPosition сверяет должность профиля с событием effectiveFrom (пусто — должности нет)
+func assertPosition(t *testing.T, what string, profile *dto.EmployeeProfile, effectiveFrom string) {
+	t.Helper()
+
+	if effectiveFrom == "" {
+		if profile.Title != nil || profile.EffectiveFrom != nil {
+			t.Errorf("%s: position = %v from %v, want none", what, deref(profile.Title), deref(profile.EffectiveFrom))
+		}
+		return
+	}
+
+	if deref(profile.Title) != "QA "+effectiveFrom || deref(profile.EffectiveFrom) != effectiveFrom {
+		t.Errorf("%s: position = %q from %q, want %q from %s", what, deref(profile.Title), deref(profile.EffectiveFrom), "QA "+effectiveFrom, effectiveFrom)
+	}
+}
+
+// positionStand — консьюмер hr.positions на репозиториях в памяти
+type positionStand struct {
+	events   *memory.EventsRepository
+	profiles *memory.ProfileRepository
+	handler  *handler
+}
+
+func newPositionStand(t *testing.T, policy string) *positionStand {
+	t.Helper()
+
+	store := memory.NewStore()
+	st := &positionStand{
+		events:   memory.NewEventsRepository(store),
+		profiles: memory.NewProfileRepository(store),
+	}
+
+	positionPolicy, err := NewPositionPolicy(policy)
+	if err != nil {
+		t.Fatalf("NewPositionPolicy: %v", err)
+	}
+
+	st.handler = &handler{
+		kind:           kindPositions,
+		events:         st.events,
+		profiles:       st.profiles,
+		log:            zerolog.Nop(),
+		commitOnDLQ:    true,
+		positionPolicy: positionPolicy,
+	}
+
+	profile := dto.EmployeeProfile{EmployeeID: testEmployeeID, FirstName: "Иван", LastName: "Петров", BirthDate: "1990-01-01"}
+	if err := st.profiles.UpsertPersonal(context.Background(), profile); err != nil {
+		t.Fatalf("UpsertPersonal: %v", err)
+	}
+
+	return st
+}
+
+// send доставляет событие должности с title "QA <effectiveFrom>"
+func (st *positionStand) send(t *testing.T, effectiveFrom string) uuid.UUID {
+	t.Helper()
+
+	value, err := json.Marshal(PositionPayload{
+		EmployeeID:    testEmployeeID,
+		Title:         "QA " + effectiveFrom,
+		Department:    "Отдел качества",
+		Grade:         "Middle",
+		EffectiveFrom: effectiveFrom,
+	})
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	messageID := uuid.New()
+	msg := &sarama.ConsumerMessage{
+		Topic:   "hr.positions",
+		Key:     []byte(testEmployeeID),
+		Value:   value,
+		Headers: []*sarama.RecordHeader{{Key: []byte(dto.HeaderMessageID), Value: []byte(messageID.String())}},
+	}
+
+	if !st.handler.handle(context.Background(), msg) {
+		t.Fatalf("handle %s: offset is not committable", effectiveFrom)
+	}
+
+	return messageID
+}
+
+func (st *positionStand) assertDecision(t *testing.T, messageID uuid.UUID, decision, reason string) {
+	t.Helper()
+
+	got, err := st.events.ListDecisionsByMessageID(context.Background(), messageID)
+	if err != nil {
+		t.Fatalf("ListDecisionsByMessageID: %v", err)
+	}
+
+	if len(got) != 1 || got[0].Decision != decision || got[0].Reason != reason {
+		t.Errorf("decisions = %+v, want one %s with reason %q", got, decision, reason)
+	}
+}
+
+// assertJournal проверяет запись kafka_events: есть ли она и её флаг stale
+func (st *positionStand) assertJournal(t *testing.T, messageID uuid.UUID, exists, stale bool) {
+	t.Helper()
+
+	event, err := st.events.GetEventByMessageID(context.Background(), messageID)
+	if !exists {
+		if !errors.Is(err, dto.ErrNotFound) {
+			t.Errorf("GetEventByMessageID: err = %v, want %v", err, dto.ErrNotFound)
+		}
+		return
+	}
+
+	if err != nil {
+		t.Fatalf("GetEventByMessageID: %v", err)
+	}
+	if event.Stale != stale {
+		t.Errorf("journal stale = %v, want %v", event.Stale, stale)
+	}
+}
@@ -0,0 +1,51 @@
+package consumer
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+)
+
+// PositionPolicy — политика конфликтов событий должности по effective_from.
+// Меняется во время работы через admin API, консьюмер читает её на каждом сообщении.
+type PositionPolicy struct {
+	v atomic.Value
+}
+
+func NewPositionPolicy(policy string) (*PositionPolicy, error) {
+	p := &PositionPolicy{}
+	if err := p.Set(policy); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *PositionPolicy) Get() string {
+	if p == nil {
+		return dto.PositionPolicyArrival
+	}
+
+	return p.v.Load().(string)
+}
+
+func (p *PositionPolicy) Set(policy string) error {
+	switch policy {
+	case dto.PositionPolicyArrival, dto.PositionPolicyEffectiveFrom, dto.PositionPolicyRejectStale:
+	default:
+		return fmt.Errorf("unknown position policy '%s'", policy)
+	}
+
+	p.v.Store(policy)
+
+	return nil
+}
+
+// WithPositionPolicy задаёт политику конфликтов для консьюмера должностей;
+// без неё действует arrival — последнее по приходу побеждает.
+func WithPositionPolicy(policy *PositionPolicy) Option {
+	return func(h *handler) {
+		h.positionPolicy = policy
+	}
+}
@@ -0,0 +1,315 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+)
+
+// ProjectionEventsRepository — журнал kafka_events для пересборки проекций
+type ProjectionEventsRepository interface {
+	EventsRepository
+	ListEventsForReplay(ctx context.Context, topics []string) ([]dto.KafkaEvent, error)
+	TruncateProjectionsTx(ctx context.Context, tx pgx.Tx) error
+}
+
+type ProjectionProfileRepository interface {
+	ProfileRepository
+	ListProfilesTx(ctx context.Context, tx pgx.Tx) ([]dto.EmployeeProfile, error)
+}
+
+type ProjectionHistoryRepository interface {
+	HistoryRepository
+	ListAllTx(ctx context.Context, tx pgx.Tx) ([]dto.EmploymentHistory, error)
+}
+
+// ProjectorTopics — топики журнала; порядок пересборки: personal, positions, history
+type ProjectorTopics struct {
+	Personal  string
+	Positions string
+	History   string
+}
+
+// Projector пересобирает employee_profile, employment_history и position_assignment
+// из журнала kafka_events той же логикой применения, что и консьюмеры.
+type Projector struct {
+	events   ProjectionEventsRepository
+	profiles ProjectionProfileRepository
+	history  ProjectionHistoryRepository
+	topics   ProjectorTopics
+	handler  *handler
+}
+
+func NewProjector(
+	events ProjectionEventsRepository,
+	profiles ProjectionProfileRepository,
+	history ProjectionHistoryRepository,
+	topics ProjectorTopics,
+	log zerolog.Logger,
+	opts ...Option,
+) *Projector {
+	h := &handler{
+		events:   events,
+		profiles: profiles,
+		history:  history,
+		log:      log.With().Str("component", "projector").Logger(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return &Projector{events: events, profiles: profiles, history: history, topics: topics, handler: h}
+}
+
+// Rebuild очищает проекции и применяет журнал заново в одной транзакции: топики по
+// порядку ProjectorTopics, внутри — partition, offset. Каждое событие применяется
+// в своей точке сохранения, неприменимые попадают в Failed и не прерывают пересборку.
+// dryRun откатывает транзакцию — остаётся только отчёт о расхождениях.
+// Консьюмеры на время пересборки должны быть остановлены.
+func (p *Projector) Rebuild(ctx context.Context, dryRun bool) (report dto.RebuildReport, err error) {
+	started := time.Now()
+	report.DryRun = dryRun
+	report.Failed = []dto.ReplayFailure{}
+	report.Mismatches = []dto.ProjectionMismatch{}
+	defer func() { report.Duration = time.Since(started).Round(10 * time.Millisecond).String() }()
+
+	journal, err := p.events.ListEventsForReplay(ctx, []string{p.topics.Personal, p.topics.Positions, p.topics.History})
+	if err != nil {
+		return report, fmt.Errorf("events.ListEventsForReplay: %w", err)
+	}
+
+	tx, err := p.events.Begin(ctx)
+	if err != nil {
+		return report, fmt.Errorf("events.Begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(context.WithoutCancel(ctx)) }()
+
+	profilesBefore, historyBefore, err := p.snapshot(ctx, tx)
+	if err != nil {
+		return report, err
+	}
+
+	if err := p.events.TruncateProjectionsTx(ctx, tx); err != nil {
+		return report, fmt.Errorf("events.TruncateProjections: %w", err)
+	}
+
+	known := make(map[string]bool)
+	for _, event := range journal {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		report.Events++
+
+		decision, err := p.replayEvent(ctx, tx, event, known)
+		if err != nil {
+			report.Failed = append(report.Failed, dto.ReplayFailure{
+				MessageID: event.MessageID,
+				Topic:     event.Topic,
+				Partition: event.Partition,
+				Offset:    event.Offset,
+				Error:     err.Error(),
+			})
+			continue
+		}
+
+		if decision == dto.DecisionStale {
+			report.Stale++
+		} else {
+			report.Applied++
+		}
+	}
+
+	profilesAfter, historyAfter, err := p.snapshot(ctx, tx)
+	if err != nil {
+		return report, err
+	}
+
+	report.Profiles = dto.ProjectionCount{Before: len(profilesBefore), After: len(profilesAfter)}
+	report.History = dto.ProjectionCount{Before: len(historyBefore), After: len(historyAfter)}
+	report.Mismatches = append(report.Mismatches, diffProfiles(profilesBefore, profilesAfter)...)
+	report.Mismatches = append(report.Mismatches, diffHistory(historyBefore, historyAfter)...)
+
+	if dryRun {
+		return report, nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return report, fmt.Errorf("tx.Commit: %w", err)
+	}
+
+	return report, nil
+}
+
+func (p *Projector) snapshot(ctx context.Context, tx pgx.Tx) ([]dto.EmployeeProfile, []dto.EmploymentHistory, error) {
+	profiles, err := p.profiles.ListProfilesTx(ctx, tx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("profiles.ListProfiles: %w", err)
+	}
+
+	history, err := p.history.ListAllTx(ctx, tx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("history.ListAll: %w", err)
+	}
+
+	return profiles, history, nil
+}
+
+// replayEvent применяет одно событие журнала в точке сохранения: ошибка откатывает
+// только его. known — сотрудники, чьи профили уже восстановлены (проверка, которую
+// консьюмеры делают через GetProfile до транзакции).
+func (p *Projector) replayEvent(ctx context.Context, tx pgx.Tx, event dto.KafkaEvent, known map[string]bool) (decision string, err error) {
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("tx.Begin: %w", err)
+	}
+	defer func() { _ = sp.Rollback(context.WithoutCancel(ctx)) }()
+
+	decision = dto.DecisionApplied
+
+	switch event.Topic {
+	case p.topics.Personal:
+		var personal PersonalPayload
+		if err := json.Unmarshal(event.Payload, &personal); err != nil {
+			return "", fmt.Errorf("json.Unmarshal: %w", err)
+		}
+		if err := p.handler.applyPersonal(ctx, sp, personal); err != nil {
+			return "", err
+		}
+		known[personal.EmployeeID] = true
+	case p.topics.Positions:
+		var position PositionPayload
+		if err := json.Unmarshal(event.Payload, &position); err != nil {
+			return "", fmt.Errorf("json.Unmarshal: %w", err)
+		}
+		if !known[position.EmployeeID] {
+			return "", profileNotFound(position.EmployeeID)
+		}
+		if decision, _, err = p.handler.applyPosition(ctx, sp, event.MessageID, position); err != nil {
+			return "", err
+		}
+	case p.topics.History:
+		var history HistoryPayload
+		if err := json.Unmarshal(event.Payload, &history); err != nil {
+			return "", fmt.Errorf("json.Unmarshal: %w", err)
+		}
+		if !known[history.EmployeeID] {
+			return "", profileNotFound(history.EmployeeID)
+		}
+		if err := p.handler.applyHistory(ctx, sp, event.MessageID, history); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unknown topic %s", event.Topic)
+	}
+
+	if err := sp.Commit(ctx); err != nil {
+		return "", fmt.Errorf("tx.Commit: %w", err)
+	}
+
+	return decision, nil
+}
+
+func diffProfiles(before, after []dto.EmployeeProfile) []dto.ProjectionMismatch {
+	const table = "employee_profile"
+
+	rebuilt := make(map[string]dto.EmployeeProfile, len(after))
+	for _, profile := range after {
+		rebuilt[profile.EmployeeID] = profile
+	}
+
+	var out []dto.ProjectionMismatch
+	for _, was := range before {
+		now, ok := rebuilt[was.EmployeeID]
+		if !ok {
+			out = append(out, dto.ProjectionMismatch{Table: table, Key: was.EmployeeID, Kind: dto.MismatchMissing, Before: was})
+			continue
+		}
+		delete(rebuilt, was.EmployeeID)
+
+		if fields := diffFields(was, now); len(fields) > 0 {
+			out = append(out, dto.ProjectionMismatch{Table: table, Key: was.EmployeeID, Kind: dto.MismatchChanged, Fields: fields, Before: was, After: now})
+		}
+	}
+
+	for _, now := range after {
+		if _, ok := rebuilt[now.EmployeeID]; ok {
+			out = append(out, dto.ProjectionMismatch{Table: table, Key: now.EmployeeID, Kind: dto.MismatchUnexpected, After: now})
+		}
+	}
+
+	return out
+}
+
+// diffHistory сопоставляет записи по message_id: id меняется при пересборке,
+// а записи без message_id (созданные через CRUD) из журнала не восстановить.
+func diffHistory(before, after []dto.EmploymentHistory) []dto.ProjectionMismatch {
+	const table = "employment_history"
+
+	rebuilt := make(map[string]dto.EmploymentHistory, len(after))
+	for _, row := range after {
+		if row.MessageID != nil {
+			rebuilt[row.MessageID.String()] = row
+		}
+	}
+
+	var out []dto.ProjectionMismatch
+	for _, was := range before {
+		if was.MessageID == nil {
+			out = append(out, dto.ProjectionMismatch{Table: table, Key: fmt.Sprintf("id=%d", was.ID), Kind: dto.MismatchMissing, Before: was})
+			continue
+		}
+
+		key := was.MessageID.String()
+		now, ok := rebuilt[key]
+		if !ok {
+			out = append(out, dto.ProjectionMismatch{Table: table, Key: key, Kind: dto.MismatchMissing, Before: was})
+			continue
+		}
+		delete(rebuilt, key)
+
+		// id — суррогатный ключ, при пересборке выдаётся заново
+		was.ID, now.ID = 0, 0
+		if fields := diffFields(was, now); len(fields) > 0 {
+			out = append(out, dto.ProjectionMismatch{Table: table, Key: key, Kind: dto.MismatchChanged, Fields: fields, Before: was, After: now})
+		}
+	}
+
+	for _, now := range after {
+		if now.MessageID == nil {
+			continue
+		}
+		if _, ok := rebuilt[now.MessageID.String()]; ok {
+			out = append(out, dto.ProjectionMismatch{Table: table, Key: now.MessageID.String(), Kind: dto.MismatchUnexpected, After: now})
+		}
+	}
+
+	return out
+}
+
+// diffFields возвращает json-имена отличающихся полей двух значений одного типа
+func diffFields(before, after any) []string {
+	bv, av := reflect.ValueOf(before), reflect.ValueOf(after)
+
+	var fields []string
+	for i := 0; i < bv.NumField(); i++ {
+		if reflect.DeepEqual(bv.Field(i).Interface(), av.Field(i).Interface()) {
+			continue
+		}
+
+		name := bv.Type().Field(i).Tag.Get("json")
+		if comma := strings.IndexByte(name, ','); comma >= 0 {
+			name = name[:comma]
+		}
+		fields = append(fields, name)
+	}
+
+	return fields
+}
@@ -0,0 +1,234 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/Artexxx/HR-Kafka-QA/internal/repository/memory"
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+var testProjectorTopics = ProjectorTopics{Personal: "hr.personal", Positions: "hr.positions", History: "hr.history"}
+
+// TestProjectorRebuildMatchesJournal — пересборка из журнала, наполненного консьюмерами,
+// восстанавливает те же профили, назначения и историю: расхождений нет.
+func TestProjectorRebuildMatchesJournal(t *testing.T) {
+	st := newProjectorStand(t)
+	st.fill(t)
+
+	profilesBefore, positionsBefore, historyBefore := st.tables(t)
+
+	report, err := st.projector.Rebuild(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+
+	if report.Events != 5 || report.Applied != 4 || report.Stale != 1 || len(report.Failed) != 0 {
+		t.Errorf("report = events %d applied %d stale %d failed %+v, want 5, 4, 1 and none", report.Events, report.Applied, report.Stale, report.Failed)
+	}
+	if len(report.Mismatches) != 0 {
+		t.Errorf("mismatches = %+v, want none", report.Mismatches)
+	}
+	if report.Profiles != (dto.ProjectionCount{Before: 1, After: 1}) || report.History != (dto.ProjectionCount{Before: 1, After: 1}) {
+		t.Errorf("counts = profiles %+v history %+v, want 1 → 1", report.Profiles, report.History)
+	}
+
+	profilesAfter, positionsAfter, historyAfter := st.tables(t)
+	if !reflect.DeepEqual(profilesAfter, profilesBefore) {
+		t.Errorf("profiles after rebuild = %+v, want %+v", profilesAfter, profilesBefore)
+	}
+	if !reflect.DeepEqual(positionsAfter, positionsBefore) {
+		t.Errorf("positions after rebuild = %v, want %v", positionsAfter, positionsBefore)
+	}
+	if !reflect.DeepEqual(historyAfter, historyBefore) {
+		t.Errorf("history after rebuild = %+v, want %+v", historyAfter, historyBefore)
+	}
+}
+
+// TestProjectorRebuildCRUDRow — запись истории, созданная через CRUD, из журнала не
+// восстанавливается: отчёт показывает её как missing; dryRun таблицы не меняет.
+func TestProjectorRebuildCRUDRow(t *testing.T) {
+	st := newProjectorStand(t)
+	st.fill(t)
+
+	crud := dto.EmploymentHistory{EmployeeID: testEmployeeID, Company: "ООО Ромашка", PeriodFrom: "2015-01-01", PeriodTo: "2016-01-01"}
+	if err := st.history.Insert(context.Background(), crud); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	profilesBefore, positionsBefore, historyBefore := st.tables(t)
+	if len(historyBefore) != 2 {
+		t.Fatalf("history = %+v, want the event row and the CRUD row", historyBefore)
+	}
+	crudID := historyBefore[slices.IndexFunc(historyBefore, func(h dto.EmploymentHistory) bool { return h.MessageID == nil })].ID
+
+	report, err := st.projector.Rebuild(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Rebuild dry run: %v", err)
+	}
+
+	if !report.DryRun || len(report.Failed) != 0 {
+		t.Errorf("report = dry_run %v failed %+v, want dry run without failures", report.DryRun, report.Failed)
+	}
+	if report.History != (dto.ProjectionCount{Before: 2, After: 1}) {
+		t.Errorf("history count = %+v, want 2 → 1", report.History)
+	}
+	want := dto.ProjectionMismatch{Table: "employment_history", Key: fmt.Sprintf("id=%d", crudID), Kind: dto.MismatchMissing}
+	if len(report.Mismatches) != 1 || report.Mismatches[0].Table != want.Table || report.Mismatches[0].Key != want.Key || report.Mismatches[0].Kind != want.Kind {
+		t.Errorf("mismatches = %+v, want %s %s %s", report.Mismatches, want.Table, want.Key, want.Kind)
+	}
+
+	profilesAfter, positionsAfter, historyAfter := st.tables(t)
+	if !reflect.DeepEqual(profilesAfter, profilesBefore) || !reflect.DeepEqual(positionsAfter, positionsBefore) || !reflect.DeepEqual(historyAfter, historyBefore) {
+		t.Errorf("dry run changed tables: profiles %+v positions %v history %+v", profilesAfter, positionsAfter, historyAfter)
+	}
+
+	if _, err := st.projector.Rebuild(context.Background(), false); err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+
+	_, _, historyAfter = st.tables(t)
+	if len(historyAfter) != 1 || historyAfter[0].MessageID == nil {
+		t.Errorf("history after rebuild = %+v, want only the event row", historyAfter)
+	}
+}
+
+// projectorStand — консьюмеры и Projector над одним хранилищем в памяти
+type projectorStand struct {
+	events    *memory.EventsRepository
+	profiles  *memory.ProfileRepository
+	history   *memory.HistoryRepository
+	handlers  map[string]*handler
+	projector *Projector
+	offsets   map[string]int64
+}
+
+func newProjectorStand(t *testing.T) *projectorStand {
+	t.Helper()
+
+	store := memory.NewStore()
+	st := &projectorStand{
+		events:   memory.NewEventsRepository(store),
+		profiles: memory.NewProfileRepository(store),
+		history:  memory.NewHistoryRepository(store),
+		offsets:  make(map[string]int64),
+	}
+
+	policy, err := NewPositionPolicy(dto.PositionPolicyEffectiveFrom)
+	if err != nil {
+		t.Fatalf("NewPositionPolicy: %v", err)
+	}
+
+	newHandler := func(k kind) *handler {
+		return &handler{
+			kind:           k,
+			events:         st.events,
+			profiles:       st.profiles,
+			history:        st.history,
+			log:            zerolog.Nop(),
+			commitOnDLQ:    true,
+			positionPolicy: policy,
+		}
+	}
+	st.handlers = map[string]*handler{
+		testProjectorTopics.Personal:  newHandler(kindPersonal),
+		testProjectorTopics.Positions: newHandler(kindPositions),
+		testProjectorTopics.History:   newHandler(kindHistory),
+	}
+
+	st.projector = NewProjector(st.events, st.profiles, st.history, testProjectorTopics, zerolog.Nop(), WithPositionPolicy(policy))
+
+	return st
+}
+
+// fill наполняет журнал и проекции через консьюмеры: профиль, две должности
+// (вторая устаревшая) и запись истории
+func (st *projectorStand) fill(t *testing.T) {
+	t.Helper()
+
+	personal := PersonalPayload{EmployeeID: testEmployeeID, FirstName: "Иван", LastName: "Петров", BirthDate: "1990-01-01"}
+	personal.Contacts.Email = "ivan@example.com"
+	personal.Contacts.Phone = "+79990000000"
+	st.consume(t, testProjectorTopics.Personal, personal)
+
+	// второе событие hr.personal перезаписывает профиль
+	personal.LastName = "Сидоров"
+	st.consume(t, testProjectorTopics.Personal, personal)
+
+	for _, effectiveFrom := range []string{"2024-03-01", "2024-01-01"} {
+		st.consume(t, testProjectorTopics.Positions, PositionPayload{
+			EmployeeID:    testEmployeeID,
+			Title:         "QA " + effectiveFrom,
+			Department:    "Отдел качества",
+			Grade:         "Middle",
+			EffectiveFrom: effectiveFrom,
+		})
+	}
+
+	history := HistoryPayload{EmployeeID: testEmployeeID, Company: "ООО Ромашка", Position: "QA", Stack: []string{"Go"}}
+	history.Period.From, history.Period.To = "2020-01-01", "2022-01-01"
+	st.consume(t, testProjectorTopics.History, history)
+}
+
+func (st *projectorStand) consume(t *testing.T, topic string, payload any) {
+	t.Helper()
+
+	value, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	msg := &sarama.ConsumerMessage{
+		Topic:   topic,
+		Offset:  st.offsets[topic],
+		Key:     []byte(testEmployeeID),
+		Value:   value,
+		Headers: []*sarama.RecordHeader{{Key: []byte(dto.HeaderMessageID), Value: []byte(uuid.NewString())}},
+	}
+	st.offsets[topic]++
+
+	if !st.handlers[topic].handle(context.Background(), msg) {
+		t.Fatalf("handle %s/%d: offset is not committable", topic, msg.Offset)
+	}
+}
+
+// tables — профили, история должностей (effective_from:status:title) и история занятости
+func (st *projectorStand) tables(t *testing.T) ([]dto.EmployeeProfile, []string, []dto.EmploymentHistory) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	profiles, err := st.profiles.ListProfiles(ctx)
+	if err != nil {
+		t.Fatalf("ListProfiles: %v", err)
+	}
+
+	assignments, err := st.profiles.ListPositions(ctx, testEmployeeID)
+	if err != nil {
+		t.Fatalf("ListPositions: %v", err)
+	}
+	var positions []string
+	for _, a := range assignments {
+		positions = append(positions, a.EffectiveFrom+":"+a.Status+":"+deref(a.Title))
+	}
+
+	history, err := st.history.ListByEmployee(ctx, testEmployeeID)
+	if err != nil {
+		t.Fatalf("ListByEmployee: %v", err)
+	}
+	// id выдаётся при пересборке заново
+	for i := range history {
+		if history[i].MessageID != nil {
+			history[i].ID = 0
+		}
+	}
+
+	return profiles, positions, history
+}
@@ -0,0 +1,139 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+)
+
+// Registry — консьюмеры стенда, адресуемые по имени (personal, positions, history)
+type Registry struct {
+	runners []*Runner
+}
+
+func NewRegistry(runners ...*Runner) *Registry {
+	return &Registry{runners: runners}
+}
+
+// Runner возвращает консьюмер по имени или dto.ErrNotFound
+func (g *Registry) Runner(name string) (*Runner, error) {
+	for _, r := range g.runners {
+		if r.name == name {
+			return r, nil
+		}
+	}
+
+	return nil, fmt.Errorf("consumer %q: %w", name, dto.ErrNotFound)
+}
+
+func (g *Registry) Runners() []*Runner {
+	return g.runners
+}
+
+func (g *Registry) ListConsumers() []dto.ConsumerState {
+	states := make([]dto.ConsumerState, 0, len(g.runners))
+	for _, r := range g.runners {
+		states = append(states, r.State())
+	}
+
+	return states
+}
+
+// LastProcessed — время последней обработки по партициям; для неизвестного имени — пусто
+func (g *Registry) LastProcessed(name string) map[string]map[int32]time.Time {
+	r, err := g.Runner(name)
+	if err != nil {
+		return nil
+	}
+
+	return r.LastProcessed()
+}
+
+func (g *Registry) PauseConsumer(name string) (dto.ConsumerState, error) {
+	return g.apply(name, func(r *Runner) error { return r.Pause() })
+}
+
+func (g *Registry) ResumeConsumer(name string) (dto.ConsumerState, error) {
+	return g.apply(name, func(r *Runner) error { return r.Resume() })
+}
+
+func (g *Registry) StopConsumer(ctx context.Context, name string) (dto.ConsumerState, error) {
+	return g.apply(name, func(r *Runner) error { return r.Stop(ctx) })
+}
+
+func (g *Registry) StartConsumer(name string) (dto.ConsumerState, error) {
+	return g.apply(name, func(r *Runner) error { return r.StartConsuming() })
+}
+
+// WithStopped останавливает консьюмер (группа пустеет), выполняет fn и возвращает
+// консьюмер в прежнее состояние. Нужен операциям над offset группы.
+func (g *Registry) WithStopped(ctx context.Context, name string, fn func(st dto.ConsumerState) error) (dto.ConsumerState, error) {
+	r, err := g.Runner(name)
+	if err != nil {
+		return dto.ConsumerState{}, err
+	}
+
+	prev := r.State()
+
+	if err := r.Stop(ctx); err != nil {
+		return r.State(), err
+	}
+
+	fnErr := fn(prev)
+	err = r.restore(prev)
+
+	if fnErr != nil {
+		return r.State(), fnErr
+	}
+
+	return r.State(), err
+}
+
+// StopAll останавливает все консьюмеры и возвращает их прежние состояния для RestoreAll
+func (g *Registry) StopAll(ctx context.Context) ([]dto.ConsumerState, error) {
+	prev := g.ListConsumers()
+
+	for _, r := range g.runners {
+		if err := r.Stop(ctx); err != nil {
+			_ = g.RestoreAll(prev)
+			return prev, err
+		}
+	}
+
+	return prev, nil
+}
+
+// RestoreAll возвращает консьюмеры в состояния, снятые StopAll
+func (g *Registry) RestoreAll(prev []dto.ConsumerState) error {
+	var errs []error
+
+	for _, st := range prev {
+		r, err := g.Runner(st.Name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := r.restore(st); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (g *Registry) apply(name string, action func(r *Runner) error) (dto.ConsumerState, error) {
+	r, err := g.Runner(name)
+	if err != nil {
+		return dto.ConsumerState{}, err
+	}
+
+	if err := action(r); err != nil {
+		return r.State(), err
+	}
+
+	return r.State(), nil
+}
@@ -0,0 +1,217 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+const (
+	headerRetryAttempt      = "x-retry-attempt"
+	headerRetryNotBefore    = "x-retry-not-before"
+	headerErrorReason       = "x-error-reason"
+	headerOriginalTopic     = "x-original-topic"
+	headerOriginalPartition = "x-original-partition"
+	headerOriginalOffset    = "x-original-offset"
+)
+
+// RetryPolicy — ярусы повторной обработки: сообщение с retryable-ошибкой уходит
+// в <topic>.retry.1, затем .retry.2 и т.д.; Delays[i] — задержка перед попыткой i+1.
+type RetryPolicy struct {
+	Delays []time.Duration
+}
+
+// ParseRetryDelays разбирает список задержек вида "1s,10s,30s"
+func ParseRetryDelays(s string) ([]time.Duration, error) {
+	var out []time.Duration
+	for _, part := range splitList(s) {
+		d, err := time.ParseDuration(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry delay %q: %w", part, err)
+		}
+
+		out = append(out, d)
+	}
+
+	return out, nil
+}
+
+// RetryTopic — имя топика яруса attempt (нумерация с 1)
+func RetryTopic(topic string, attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", topic, attempt)
+}
+
+// Topics — все retry-топики для базового топика
+func (p RetryPolicy) Topics(topic string) []string {
+	out := make([]string, 0, len(p.Delays))
+	for i := range p.Delays {
+		out = append(out, RetryTopic(topic, i+1))
+	}
+
+	return out
+}
+
+// WithRetry включает retry-ярусы. Без него retryable-ошибки идут сразу в DLQ.
+func WithRetry(publisher MessagePublisher, policy RetryPolicy) Option {
+	return func(h *handler) {
+		h.retryPublisher = publisher
+		h.retry = policy
+	}
+}
+
+// scheduleRetry публикует сообщение в следующий retry-ярус.
+// false — ярусы исчерпаны или retry выключен.
+func (h *handler) scheduleRetry(msg *sarama.ConsumerMessage, reason string) (bool, error) {
+	if h.retryPublisher == nil {
+		return false, nil
+	}
+
+	attempt := retryAttempt(msg) + 1
+	if attempt > len(h.retry.Delays) {
+		return false, nil
+	}
+
+	topic, partition, offset := messageOrigin(msg)
+	notBefore := time.Now().Add(h.retry.Delays[attempt-1])
+
+	headers := make([]sarama.RecordHeader, 0, len(msg.Headers)+6)
+	for _, header := range msg.Headers {
+		if header == nil || isRetryHeader(string(header.Key)) {
+			continue
+		}
+
+		headers = append(headers, *header)
+	}
+
+	headers = append(headers,
+		sarama.RecordHeader{Key: []byte(headerRetryAttempt), Value: []byte(strconv.Itoa(attempt))},
+		sarama.RecordHeader{Key: []byte(headerRetryNotBefore), Value: []byte(notBefore.Format(time.RFC3339Nano))},
+		sarama.RecordHeader{Key: []byte(headerErrorReason), Value: []byte(reason)},
+		sarama.RecordHeader{Key: []byte(headerOriginalTopic), Value: []byte(topic)},
+		sarama.RecordHeader{Key: []byte(headerOriginalPartition), Value: []byte(strconv.Itoa(int(partition)))},
+		sarama.RecordHeader{Key: []byte(headerOriginalOffset), Value: []byte(strconv.FormatInt(offset, 10))},
+	)
+
+	retryMsg := &sarama.ProducerMessage{
+		Topic:   RetryTopic(topic, attempt),
+		Value:   sarama.ByteEncoder(msg.Value),
+		Headers: headers,
+	}
+	if msg.Key != nil {
+		retryMsg.Key = sarama.ByteEncoder(msg.Key)
+	}
+
+	if _, _, err := h.retryPublisher.SendMessage(retryMsg); err != nil {
+		return false, fmt.Errorf("send retry message: %w", err)
+	}
+
+	h.log.Warn().
+		Str("topic", retryMsg.Topic).
+		Int("attempt", attempt).
+		Time("not_before", notBefore).
+		Str("reason", reason).
+		Msg("message scheduled for retry")
+
+	return true, nil
+}
+
+// waitRetryDelay выдерживает задержку яруса перед обработкой retry-сообщения.
+// false — сессия завершилась во время ожидания.
+func waitRetryDelay(ctx context.Context, msg *sarama.ConsumerMessage) bool {
+	raw, ok := header(msg, headerRetryNotBefore)
+	if !ok {
+		return true
+	}
+
+	notBefore, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return true
+	}
+
+	wait := time.Until(notBefore)
+	if wait <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func retryAttempt(msg *sarama.ConsumerMessage) int {
+	raw, ok := header(msg, headerRetryAttempt)
+	if !ok {
+		return 0
+	}
+
+	attempt, err := strconv.Atoi(raw)
+	if err != nil || attempt < 0 {
+		return 0
+	}
+
+	return attempt
+}
+
+// messageOrigin — координаты исходного сообщения: для retry-сообщений берутся
+// из заголовков x-original-*, для обычных — из самого сообщения.
+func messageOrigin(msg *sarama.ConsumerMessage) (string, int32, int64) {
+	topic, ok := header(msg, headerOriginalTopic)
+	if !ok {
+		return msg.Topic, msg.Partition, msg.Offset
+	}
+
+	partition, offset := msg.Partition, msg.Offset
+	if raw, ok := header(msg, headerOriginalPartition); ok {
+		if p, err := strconv.ParseInt(raw, 10, 32); err == nil {
+			partition = int32(p)
+		}
+	}
+	if raw, ok := header(msg, headerOriginalOffset); ok {
+		if o, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			offset = o
+		}
+	}
+
+	return topic, partition, offset
+}
+
+func isRetryHeader(key string) bool {
+	switch key {
+	case headerRetryAttempt, headerRetryNotBefore, headerErrorReason,
+		headerOriginalTopic, headerOriginalPartition, headerOriginalOffset:
+		return true
+	}
+
+	return false
+}
+
+func header(msg *sarama.ConsumerMessage, key string) (string, bool) {
+	for _, h := range msg.Headers {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value), true
+		}
+	}
+
+	return "", false
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,272 @@
+package consumer
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/Artexxx/HR-Kafka-QA/internal/repository/memory"
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// TestRetryTiers — retryable-ошибка проходит ярусы по порядку с исходными
+// координатами сообщения, после последнего яруса сообщение уходит в DLQ.
+func TestRetryTiers(t *testing.T) {
+	store := memory.NewStore()
+	eventsRepo := memory.NewEventsRepository(store)
+	retries, dlq := &fakePublisher{}, &fakePublisher{}
+
+	h := &handler{
+		kind:        kindPositions,
+		events:      eventsRepo,
+		log:         zerolog.Nop(),
+		commitOnDLQ: true,
+	}
+	WithRetry(retries, RetryPolicy{Delays: []time.Duration{time.Second, 10 * time.Second}})(h)
+	WithDLQTopic(dlq, ".dlq")(h)
+
+	ctx := dto.WithSession(context.Background(), "s-1")
+	messageID := uuid.New()
+	msg := &sarama.ConsumerMessage{
+		Topic:     "hr.positions",
+		Partition: 1,
+		Offset:    7,
+		Key:       []byte("E-1"),
+		Value:     []byte(`{"employee_id": "E-1"}`),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(dto.HeaderMessageID), Value: []byte(messageID.String())},
+			{Key: []byte(dto.HeaderSessionID), Value: []byte("s-1")},
+		},
+	}
+
+	for attempt, delay := range []time.Duration{time.Second, 10 * time.Second} {
+		start := time.Now()
+		if !h.fail(ctx, msg, messageID, retryableError("db timeout")) {
+			t.Fatalf("attempt %d: offset is not committable", attempt+1)
+		}
+
+		sent := retries.last(t)
+		if want := RetryTopic("hr.positions", attempt+1); sent.Topic != want {
+			t.Errorf("attempt %d: topic = %s, want %s", attempt+1, sent.Topic, want)
+		}
+		assertHeaders(t, sent.Headers, map[string]string{
+			dto.HeaderMessageID:     messageID.String(),
+			dto.HeaderSessionID:     "s-1",
+			headerRetryAttempt:      strconv.Itoa(attempt + 1),
+			headerErrorReason:       "db timeout",
+			headerOriginalTopic:     "hr.positions",
+			headerOriginalPartition: "1",
+			headerOriginalOffset:    "7",
+		})
+
+		notBefore, err := time.Parse(time.RFC3339Nano, producedHeader(sent.Headers, headerRetryNotBefore))
+		if err != nil {
+			t.Fatalf("attempt %d: %s: %v", attempt+1, headerRetryNotBefore, err)
+		}
+		if notBefore.Before(start.Add(delay)) || notBefore.After(time.Now().Add(delay)) {
+			t.Errorf("attempt %d: not before = %s, want now + %s", attempt+1, notBefore, delay)
+		}
+
+		// следующую попытку консьюмер читает из retry-топика, со своими координатами
+		msg = consumed(sent, 0, int64(10+attempt))
+	}
+
+	if !h.fail(ctx, msg, messageID, retryableError("db timeout")) {
+		t.Fatal("last attempt: offset is not committable")
+	}
+
+	if len(retries.sent) != 2 {
+		t.Errorf("retry messages = %d, want 2", len(retries.sent))
+	}
+
+	sent := dlq.last(t)
+	if sent.Topic != "hr.positions.dlq" {
+		t.Errorf("DLQ topic = %s, want hr.positions.dlq", sent.Topic)
+	}
+	assertHeaders(t, sent.Headers, map[string]string{
+		dto.HeaderMessageID:     messageID.String(),
+		headerRetryAttempt:      "2",
+		headerErrorReason:       "db timeout",
+		headerOriginalTopic:     "hr.positions",
+		headerOriginalPartition: "1",
+		headerOriginalOffset:    "7",
+	})
+	if v := producedHeader(sent.Headers, headerRetryNotBefore); v != "" {
+		t.Errorf("DLQ message has %s = %s", headerRetryNotBefore, v)
+	}
+
+	rows, err := eventsRepo.ListDLQByMessageID(ctx, messageID)
+	if err != nil {
+		t.Fatalf("ListDLQByMessageID: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Topic != "hr.positions" || *rows[0].Partition != 1 || *rows[0].Offset != 7 {
+		t.Fatalf("DLQ rows = %+v, want one at hr.positions/1/7", rows)
+	}
+
+	decisions, err := eventsRepo.ListDecisionsByMessageID(ctx, messageID)
+	if err != nil {
+		t.Fatalf("ListDecisionsByMessageID: %v", err)
+	}
+	var got []string
+	for _, d := range decisions {
+		got = append(got, d.Decision)
+	}
+	if want := []string{dto.DecisionRetry, dto.DecisionRetry, dto.DecisionDLQ}; !slices.Equal(got, want) {
+		t.Errorf("decisions = %v, want %v", got, want)
+	}
+}
+
+// TestRetryDisabled — без WithRetry retryable-ошибка идёт сразу в DLQ
+func TestRetryDisabled(t *testing.T) {
+	h := &handler{log: zerolog.Nop()}
+
+	scheduled, err := h.scheduleRetry(&sarama.ConsumerMessage{Topic: "hr.positions"}, "db timeout")
+	if scheduled || err != nil {
+		t.Fatalf("scheduleRetry = %v, %v; want false, nil", scheduled, err)
+	}
+}
+
+func TestMessageOrigin(t *testing.T) {
+	tests := []struct {
+		name          string
+		headers       map[string]string
+		wantTopic     string
+		wantPartition int32
+		wantOffset    int64
+	}{
+		{name: "plain message", wantTopic: "hr.positions.retry.1", wantPartition: 2, wantOffset: 5},
+		{
+			name:      "retry message",
+			headers:   map[string]string{headerOriginalTopic: "hr.positions", headerOriginalPartition: "1", headerOriginalOffset: "7"},
+			wantTopic: "hr.positions", wantPartition: 1, wantOffset: 7,
+		},
+		{
+			name:      "broken coordinates",
+			headers:   map[string]string{headerOriginalTopic: "hr.positions", headerOriginalPartition: "x", headerOriginalOffset: "y"},
+			wantTopic: "hr.positions", wantPartition: 2, wantOffset: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &sarama.ConsumerMessage{Topic: "hr.positions.retry.1", Partition: 2, Offset: 5}
+			for k, v := range tt.headers {
+				msg.Headers = append(msg.Headers, &sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+			}
+
+			topic, partition, offset := messageOrigin(msg)
+			if topic != tt.wantTopic || partition != tt.wantPartition || offset != tt.wantOffset {
+				t.Errorf("messageOrigin = %s/%d/%d, want %s/%d/%d", topic, partition, offset, tt.wantTopic, tt.wantPartition, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestWaitRetryDelay(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		notBefore string
+		want      bool
+		minWait   time.Duration
+	}{
+		{name: "no header", ctx: canceled, want: true},
+		{name: "invalid header", ctx: canceled, notBefore: "soon", want: true},
+		{name: "in the past", ctx: canceled, notBefore: time.Now().Add(-time.Second).Format(time.RFC3339Nano), want: true},
+		{name: "waits", ctx: context.Background(), notBefore: time.Now().Add(50 * time.Millisecond).Format(time.RFC3339Nano), want: true, minWait: 40 * time.Millisecond},
+		{name: "session canceled", ctx: canceled, notBefore: time.Now().Add(time.Hour).Format(time.RFC3339Nano), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &sarama.ConsumerMessage{Topic: "hr.positions.retry.1"}
+			if tt.notBefore != "" {
+				msg.Headers = []*sarama.RecordHeader{{Key: []byte(headerRetryNotBefore), Value: []byte(tt.notBefore)}}
+			}
+
+			start := time.Now()
+			if got := waitRetryDelay(tt.ctx, msg); got != tt.want {
+				t.Errorf("waitRetryDelay = %v, want %v", got, tt.want)
+			}
+			if waited := time.Since(start); waited < tt.minWait {
+				t.Errorf("waited %s, want at least %s", waited, tt.minWait)
+			}
+		})
+	}
+}
+
+// fakePublisher запоминает отправленные сообщения
+type fakePublisher struct {
+	mu   sync.Mutex
+	sent []*sarama.ProducerMessage
+}
+
+func (p *fakePublisher) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sent = append(p.sent, msg)
+
+	return 0, int64(len(p.sent) - 1), nil
+}
+
+func (p *fakePublisher) last(t *testing.T) *sarama.ProducerMessage {
+	t.Helper()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.sent) == 0 {
+		t.Fatal("no messages published")
+	}
+
+	return p.sent[len(p.sent)-1]
+}
+
+// consumed — опубликованное сообщение глазами консьюмера
+func consumed(msg *sarama.ProducerMessage, partition int32, offset int64) *sarama.ConsumerMessage {
+	out := &sarama.ConsumerMessage{Topic: msg.Topic, Partition: partition, Offset: offset}
+	if msg.Key != nil {
+		out.Key, _ = msg.Key.Encode()
+	}
+	out.Value, _ = msg.Value.Encode()
+	for _, h := range msg.Headers {
+		out.Headers = append(out.Headers, &sarama.RecordHeader{Key: h.Key, Value: h.Value})
+	}
+
+	return out
+}
+
+func producedHeader(headers []sarama.RecordHeader, key string) string {
+	for _, h := range headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+
+	return ""
+}
+
+func assertHeaders(t *testing.T, headers []sarama.RecordHeader, want map[string]string) {
+	t.Helper()
+
+	count := make(map[string]int)
+	for _, h := range headers {
+		count[string(h.Key)]++
+	}
+
+	for key, value := range want {
+		if got := producedHeader(headers, key); got != value || count[key] != 1 {
+			t.Errorf("header %s = %q (%d times), want %q once", key, got, count[key], value)
+		}
+	}
+}
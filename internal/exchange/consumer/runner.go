@@ -3,41 +3,108 @@ package consumer
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/Artexxx/HR-Kafka-QA/internal/bugs"
 	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
 	"github.com/IBM/sarama"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog"
 )
 
 type EventsRepository interface {
-	ExistsMessage(ctx context.Context, messageID uuid.UUID) (bool, error)
-	InsertEvent(ctx context.Context, event dto.KafkaEvent) error
+	Begin(ctx context.Context) (pgx.Tx, error)
+	ClaimMessageTx(ctx context.Context, tx pgx.Tx, event dto.KafkaEvent) (bool, error)
+	MarkStaleTx(ctx context.Context, tx pgx.Tx, messageID uuid.UUID) error
 	InsertDLQ(ctx context.Context, dlq dto.KafkaDLQ) error
+	InsertDecision(ctx context.Context, decision dto.ConsumerDecision) error
 }
 
 type ProfileRepository interface {
-	UpsertPersonal(ctx context.Context, profile dto.EmployeeProfile) error
+	UpsertPersonalTx(ctx context.Context, tx pgx.Tx, profile dto.EmployeeProfile) error
 	GetProfile(ctx context.Context, employeeID string) (*dto.EmployeeProfile, error)
-	UpsertPosition(ctx context.Context, profile dto.EmployeeProfile) error
+	UpsertPositionTx(ctx context.Context, tx pgx.Tx, profile dto.EmployeeProfile) error
+	LockEffectiveFromTx(ctx context.Context, tx pgx.Tx, employeeID string) (string, error)
+	InsertAssignmentTx(ctx context.Context, tx pgx.Tx, assignment dto.PositionAssignment) error
+	SupersedeAssignmentsTx(ctx context.Context, tx pgx.Tx, employeeID, effectiveFrom string) error
 }
 
 type HistoryRepository interface {
-	Insert(ctx context.Context, h dto.EmploymentHistory) error
+	InsertTx(ctx context.Context, tx pgx.Tx, h dto.EmploymentHistory) error
+}
+
+// MessagePublisher — отправка сообщений в Kafka (реализуется sarama.SyncProducer)
+type MessagePublisher interface {
+	SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error)
+}
+
+// Option — необязательная настройка консьюмера
+type Option func(h *handler)
+
+// GroupFactory создаёт consumer group; по умолчанию — sarama.NewConsumerGroup к bootstrap
+type GroupFactory func(groupID string, cfg *sarama.Config) (sarama.ConsumerGroup, error)
+
+// WithGroupFactory подменяет подключение к Kafka, например брокером в памяти (memkafka)
+func WithGroupFactory(factory GroupFactory) Option {
+	return func(h *handler) {
+		h.newGroup = factory
+	}
+}
+
+// WithBugs подключает каталог заложенных дефектов (режим «найди баг»)
+func WithBugs(catalog *bugs.Catalog) Option {
+	return func(h *handler) {
+		h.bugs = catalog
+	}
+}
+
+// WithDLQTopic включает дублирование DLQ в Kafka: ошибочное сообщение публикуется
+// в <topic><suffix> с исходным ключом и заголовками плюс x-error-reason,
+// x-original-topic, x-original-partition, x-original-offset.
+func WithDLQTopic(publisher MessagePublisher, suffix string) Option {
+	return func(h *handler) {
+		h.dlqPublisher = publisher
+		h.dlqSuffix = suffix
+	}
 }
 
 type Runner struct {
-	brokers   []string
+	name      string
 	groupID   string
 	topic     string
+	topics    []string
 	handler   *handler
 	log       zerolog.Logger
 	createCfg func() *sarama.Config
+	newGroup  GroupFactory
+
+	mu        sync.Mutex
+	state     string
+	changedAt time.Time
+	group     sarama.ConsumerGroup // группа текущей сессии; nil, пока консьюмер остановлен
+	cancel    context.CancelFunc   // завершает текущую сессию
+	done      chan struct{}        // закрывается, когда сессия вышла из группы
+	wake      chan struct{}        // сигнал запуска остановленного консьюмера
+	processed map[string]map[int32]time.Time
+	joined    bool  // консьюмер хотя бы раз вошёл в группу
+	lastErr   error // ошибка последнего входа в группу; nil после успешного входа
 }
 
-func newRunner(bootstrap, groupID, topic string, h *handler, log zerolog.Logger) *Runner {
+// Задержки повторного входа в группу после ошибки: от rejoinMinDelay, удваиваясь до rejoinMaxDelay
+const (
+	rejoinMinDelay = 500 * time.Millisecond
+	rejoinMaxDelay = 30 * time.Second
+)
+
+func newRunner(bootstrap, name, groupID, topic string, h *handler, log zerolog.Logger, opts []Option) *Runner {
+	for _, opt := range opts {
+		opt(h)
+	}
+
 	createCfg := func() *sarama.Config {
 		cfg := sarama.NewConfig()
 		cfg.Version = sarama.V3_3_2_0
@@ -47,25 +114,143 @@ func newRunner(bootstrap, groupID, topic string, h *handler, log zerolog.Logger)
 		// Автокоммит управляется вызовами session.MarkMessage
 		return cfg
 	}
-	return &Runner{
-		brokers:   []string{bootstrap},
+	// retry-ярусы читает та же группа, что и основной топик
+	topics := []string{topic}
+	if h.retryPublisher != nil {
+		topics = append(topics, h.retry.Topics(topic)...)
+	}
+
+	newGroup := h.newGroup
+	if newGroup == nil {
+		newGroup = func(groupID string, cfg *sarama.Config) (sarama.ConsumerGroup, error) {
+			return sarama.NewConsumerGroup([]string{bootstrap}, groupID, cfg)
+		}
+	}
+
+	r := &Runner{
+		name:      name,
 		groupID:   groupID,
 		topic:     topic,
+		topics:    topics,
 		handler:   h,
 		log:       log.With().Str("topic", topic).Str("group", groupID).Logger(),
 		createCfg: createCfg,
+		newGroup:  newGroup,
+		state:     dto.ConsumerStateRunning,
+		changedAt: time.Now(),
+		wake:      make(chan struct{}, 1),
+		processed: make(map[string]map[int32]time.Time),
 	}
+	h.onClaim = r.repause
+	h.onProcessed = r.markProcessed
+
+	return r
 }
 
+// Start крутит консьюмер до отмены ctx. Остановленный через Stop консьюмер
+// ждёт StartConsuming, не завершая Start. Ошибку входа в группу Start возвращает
+// только при первом запуске (неверная конфигурация); после него консьюмер
+// повторяет вход с нарастающей задержкой, а ошибка видна в State.
 func (r *Runner) Start(ctx context.Context) error {
+	r.log.Info().Msg("consumer started")
+	defer r.log.Info().Msg("consumer stopped")
+
+	delay := rejoinMinDelay
+	for {
+		// после отмены ctx run сразу возвращается, а запущенный консьюмер
+		// не ждёт в waitStarted — без этой проверки цикл не завершится
+		if ctx.Err() != nil || !r.waitStarted(ctx) {
+			return nil
+		}
+
+		err := r.run(ctx)
+		if err == nil {
+			delay = rejoinMinDelay
+			continue
+		}
+
+		r.mu.Lock()
+		joined := r.joined
+		r.lastErr = err
+		r.mu.Unlock()
+
+		if !joined {
+			return err
+		}
+
+		r.log.Error().Err(err).Dur("retry_in", delay).Msg("consumer group join failed")
+		if !r.sleep(ctx, delay) {
+			return nil
+		}
+		delay = min(delay*2, rejoinMaxDelay)
+	}
+}
+
+// sleep выдерживает задержку перед повторным входом в группу; Stop и StartConsuming
+// её прерывают. false — ctx отменён.
+func (r *Runner) sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+	case <-r.wake:
+	}
+
+	return true
+}
+
+// waitStarted блокируется, пока консьюмер в состоянии stopped; false — ctx отменён.
+func (r *Runner) waitStarted(ctx context.Context) bool {
+	for {
+		r.mu.Lock()
+		stopped := r.state == dto.ConsumerStateStopped
+		r.mu.Unlock()
+
+		if !stopped {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-r.wake:
+		}
+	}
+}
+
+// run — одна сессия консьюмера: от входа в группу до Stop или отмены ctx.
+func (r *Runner) run(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	cfg := r.createCfg()
 
-	consumerGroup, err := sarama.NewConsumerGroup(r.brokers, r.groupID, cfg)
+	consumerGroup, err := r.newGroup(r.groupID, cfg)
 	if err != nil {
-		return err
+		return fmt.Errorf("create consumer group: %w", err)
 	}
 	defer func() { _ = consumerGroup.Close() }()
 
+	r.mu.Lock()
+	r.joined, r.lastErr = true, nil
+	if r.state == dto.ConsumerStateStopped {
+		r.mu.Unlock()
+		return nil
+	}
+	done := make(chan struct{})
+	r.group, r.cancel, r.done = consumerGroup, cancel, done
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.group, r.cancel, r.done = nil, nil, nil
+		r.mu.Unlock()
+		close(done)
+	}()
+
 	go func() {
 		for err := range consumerGroup.Errors() {
 			if err == nil || errors.Is(err, context.Canceled) || (strings.Contains(err.Error(), "context canceled")) {
@@ -76,17 +261,14 @@ func (r *Runner) Start(ctx context.Context) error {
 		}
 	}()
 
-	r.log.Info().Msg("consumer started")
-	defer r.log.Info().Msg("consumer stopped")
-
 	for {
-		if ctx.Err() != nil {
+		if runCtx.Err() != nil {
 			return nil
 		}
 
-		err := consumerGroup.Consume(ctx, []string{r.topic}, r.handler)
+		err := consumerGroup.Consume(runCtx, r.topics, r.handler)
 
-		if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+		if errors.Is(err, context.Canceled) || runCtx.Err() != nil {
 			return nil
 		}
 
@@ -96,3 +278,171 @@ func (r *Runner) Start(ctx context.Context) error {
 		}
 	}
 }
+
+// Name — имя консьюмера в admin API
+func (r *Runner) Name() string {
+	return r.name
+}
+
+// State возвращает текущее состояние консьюмера
+func (r *Runner) State() dto.ConsumerState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state := dto.ConsumerState{
+		Name:      r.name,
+		GroupID:   r.groupID,
+		Topics:    r.topics,
+		State:     r.state,
+		ChangedAt: r.changedAt.UTC().Format(time.RFC3339),
+	}
+	if r.lastErr != nil {
+		state.LastError = r.lastErr.Error()
+	}
+
+	return state
+}
+
+// Pause приостанавливает fetch всех партиций (PauseAll), оставаясь в группе.
+// Уже выбранные из Kafka сообщения могут дообработаться.
+func (r *Runner) Pause() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch r.state {
+	case dto.ConsumerStatePaused:
+		return nil
+	case dto.ConsumerStateStopped:
+		return fmt.Errorf("consumer %s is stopped: %w", r.name, dto.ErrInvalidState)
+	}
+
+	if r.group != nil {
+		r.group.PauseAll()
+	}
+	r.setState(dto.ConsumerStatePaused)
+
+	return nil
+}
+
+// Resume снимает паузу (ResumeAll)
+func (r *Runner) Resume() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch r.state {
+	case dto.ConsumerStateRunning:
+		return nil
+	case dto.ConsumerStateStopped:
+		return fmt.Errorf("consumer %s is stopped: %w", r.name, dto.ErrInvalidState)
+	}
+
+	if r.group != nil {
+		r.group.ResumeAll()
+	}
+	r.setState(dto.ConsumerStateRunning)
+
+	return nil
+}
+
+// Stop завершает сессию и выводит консьюмер из группы; ждёт выхода не дольше ctx.
+// Закоммиченные offset сохраняются, после StartConsuming чтение продолжится с них.
+func (r *Runner) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	if r.state == dto.ConsumerStateStopped && r.done == nil {
+		r.mu.Unlock()
+		return nil
+	}
+
+	r.setState(dto.ConsumerStateStopped)
+	cancel, done := r.cancel, r.done
+	r.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("consumer %s stop: %w", r.name, ctx.Err())
+	}
+}
+
+// StartConsuming запускает остановленный консьюмер: он заново входит в группу.
+func (r *Runner) StartConsuming() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.state != dto.ConsumerStateStopped {
+		return nil
+	}
+	r.setState(dto.ConsumerStateRunning)
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// restore возвращает остановленный консьюмер в состояние prev
+func (r *Runner) restore(prev dto.ConsumerState) error {
+	switch prev.State {
+	case dto.ConsumerStateRunning:
+		return r.StartConsuming()
+	case dto.ConsumerStatePaused:
+		if err := r.StartConsuming(); err != nil {
+			return err
+		}
+
+		return r.Pause()
+	}
+
+	return nil
+}
+
+// repause вызывается при получении партиции: после ребаланса новые
+// partition consumer не наследуют паузу, поэтому её нужно применить заново.
+func (r *Runner) repause(topic string, partition int32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.state == dto.ConsumerStatePaused && r.group != nil {
+		r.group.Pause(map[string][]int32{topic: {partition}})
+	}
+}
+
+// LastProcessed — время последнего обработанного сообщения по топикам и партициям
+func (r *Runner) LastProcessed() map[string]map[int32]time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make(map[string]map[int32]time.Time, len(r.processed))
+	for topic, partitions := range r.processed {
+		out[topic] = make(map[int32]time.Time, len(partitions))
+		for p, at := range partitions {
+			out[topic][p] = at
+		}
+	}
+
+	return out
+}
+
+func (r *Runner) markProcessed(msg *sarama.ConsumerMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.processed[msg.Topic] == nil {
+		r.processed[msg.Topic] = make(map[int32]time.Time)
+	}
+	r.processed[msg.Topic][msg.Partition] = time.Now()
+}
+
+func (r *Runner) setState(state string) {
+	r.state = state
+	r.changedAt = time.Now()
+	r.log.Info().Str("state", state).Msg("consumer state changed")
+}
@@ -0,0 +1,193 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/Artexxx/HR-Kafka-QA/internal/exchange/memkafka"
+	"github.com/Artexxx/HR-Kafka-QA/internal/repository/memory"
+	"github.com/IBM/sarama"
+	"github.com/rs/zerolog"
+)
+
+// shutdownTimeout — за сколько Start должен вернуться после отмены ctx
+const shutdownTimeout = 2 * time.Second
+
+// TestRunnerStartReturnsAfterCancel — Start завершается после отмены ctx в любом
+// состоянии консьюмера (регрессия: запущенный консьюмер крутил цикл бесконечно).
+func TestRunnerStartReturnsAfterCancel(t *testing.T) {
+	tests := []struct {
+		name  string
+		state func(r *Runner) error
+	}{
+		{name: dto.ConsumerStateRunning, state: func(*Runner) error { return nil }},
+		{name: dto.ConsumerStatePaused, state: func(r *Runner) error { return r.Pause() }},
+		{name: dto.ConsumerStateStopped, state: func(r *Runner) error { return r.Stop(context.Background()) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRunner(t)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() { done <- r.Start(ctx) }()
+
+			waitJoined(t, r)
+			if err := tt.state(r); err != nil {
+				t.Fatalf("set state %s: %v", tt.name, err)
+			}
+
+			cancel()
+
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatalf("Start: %v", err)
+				}
+			case <-time.After(shutdownTimeout):
+				t.Fatalf("Start did not return within %s after cancel", shutdownTimeout)
+			}
+		})
+	}
+}
+
+// TestRunnerRejoinsAfterGroupError — ошибка входа в группу после Stop/Start не
+// завершает Start: консьюмер повторяет вход, а ошибка видна в состоянии.
+func TestRunnerRejoinsAfterGroupError(t *testing.T) {
+	factory := &flakyGroups{}
+	r := newTestRunner(t, factory.wrap)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- r.Start(ctx) }()
+
+	waitJoined(t, r)
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	factory.failNext(2)
+	if err := r.StartConsuming(); err != nil {
+		t.Fatalf("StartConsuming: %v", err)
+	}
+
+	deadline := time.Now().Add(shutdownTimeout)
+	for r.State().LastError == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := r.State().LastError; !strings.Contains(got, errBrokerDown.Error()) {
+		t.Fatalf("last_error = %q, want %q", got, errBrokerDown)
+	}
+
+	waitJoined(t, r)
+	if state := r.State(); state.LastError != "" || state.State != dto.ConsumerStateRunning {
+		t.Errorf("state after rejoin = %+v, want running without last_error", state)
+	}
+
+	select {
+	case err := <-done:
+		t.Fatalf("Start returned before cancel: %v", err)
+	default:
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+	case <-time.After(shutdownTimeout):
+		t.Fatalf("Start did not return within %s after cancel", shutdownTimeout)
+	}
+}
+
+// TestRunnerFirstJoinError — при первом запуске ошибка входа в группу
+// возвращается из Start: это ошибка конфигурации стенда.
+func TestRunnerFirstJoinError(t *testing.T) {
+	factory := &flakyGroups{}
+	factory.failNext(1)
+	r := newTestRunner(t, factory.wrap)
+
+	if err := r.Start(context.Background()); !errors.Is(err, errBrokerDown) {
+		t.Fatalf("Start: err = %v, want %v", err, errBrokerDown)
+	}
+}
+
+var errBrokerDown = errors.New("broker unreachable")
+
+// flakyGroups — фабрика групп, которая отказывает заданное число раз
+type flakyGroups struct {
+	mu       sync.Mutex
+	failures int
+}
+
+func (f *flakyGroups) failNext(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failures = n
+}
+
+func (f *flakyGroups) wrap(next GroupFactory) GroupFactory {
+	return func(groupID string, cfg *sarama.Config) (sarama.ConsumerGroup, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		if f.failures > 0 {
+			f.failures--
+			return nil, errBrokerDown
+		}
+
+		return next(groupID, cfg)
+	}
+}
+
+// newTestRunner — консьюмер personal на брокере в памяти; wrap подменяет фабрику групп
+func newTestRunner(t *testing.T, wrap ...func(GroupFactory) GroupFactory) *Runner {
+	t.Helper()
+
+	broker := memkafka.NewBroker(nil)
+	if _, err := broker.EnsureTopics(context.Background(), []dto.TopicSpec{{Name: "hr.personal", Partitions: 1}}); err != nil {
+		t.Fatalf("EnsureTopics: %v", err)
+	}
+
+	var newGroup GroupFactory = broker.NewConsumerGroup
+	for _, w := range wrap {
+		newGroup = w(newGroup)
+	}
+
+	store := memory.NewStore()
+
+	return NewPersonalRunner("", "hr.personal", "test-personal",
+		memory.NewEventsRepository(store), memory.NewProfileRepository(store), zerolog.Nop(),
+		WithGroupFactory(newGroup))
+}
+
+// waitJoined ждёт, пока консьюмер войдёт в группу
+func waitJoined(t *testing.T, r *Runner) {
+	t.Helper()
+
+	deadline := time.Now().Add(shutdownTimeout)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		joined := r.group != nil
+		r.mu.Unlock()
+
+		if joined {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("consumer did not join the group within %s", shutdownTimeout)
+}
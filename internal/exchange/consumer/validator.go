@@ -40,7 +40,8 @@ func validatePersonal(payload PersonalPayload) string {
 	return ""
 }
 
-func validatePosition(payload PositionPayload) string {
+// anyGrade — не проверять грейд по списку допустимых (дефект bugs.AcceptInvalidGrade)
+func validatePosition(payload PositionPayload, anyGrade bool) string {
 	if strings.TrimSpace(payload.Title) == "" {
 		return "required field 'title'"
 	}
@@ -53,7 +54,7 @@ func validatePosition(payload PositionPayload) string {
 		return "required field 'grade'"
 	}
 
-	if _, ok := allowedGrades[payload.Grade]; !ok {
+	if _, ok := allowedGrades[payload.Grade]; !ok && !anyGrade {
 		return fmt.Sprintf("invalid enum value: grade %s not in allowed grades %v", payload.Grade, allowedGrades)
 	}
 
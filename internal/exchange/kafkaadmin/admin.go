@@ -0,0 +1,481 @@
+package kafkaadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/IBM/sarama"
+)
+
+// topicRecreatePoll — пауза между попытками создать топик, пока идёт его удаление
+const topicRecreatePoll = 500 * time.Millisecond
+
+// Admin — служебные операции с кластером: offset групп, high-water mark, участники, топики
+type Admin struct {
+	client sarama.Client
+	admin  sarama.ClusterAdmin
+}
+
+func NewAdmin(bootstrap string) (*Admin, error) {
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.V3_3_2_0
+	// ошибки чтения партиции (ReadTopic) возвращаются вызывающему, а не только в лог
+	cfg.Consumer.Return.Errors = true
+
+	client, err := sarama.NewClient([]string{bootstrap}, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("sarama.NewClient: %w", err)
+	}
+
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("sarama.NewClusterAdminFromClient: %w", err)
+	}
+
+	return &Admin{client: client, admin: admin}, nil
+}
+
+// Close закрывает admin вместе с клиентом
+func (a *Admin) Close() error {
+	return a.admin.Close()
+}
+
+// DescribeGroup возвращает committed offset, high-water mark и lag группы по всем
+// партициям topics, а также участников группы с их назначениями.
+// Для партиции без коммитов lag считается от самого раннего offset (Offsets.Initial = Oldest).
+func (a *Admin) DescribeGroup(_ context.Context, groupID string, topics []string) (dto.ConsumerGroupLag, error) {
+	out := dto.ConsumerGroupLag{
+		GroupID:    groupID,
+		Members:    []dto.GroupMember{},
+		Partitions: []dto.PartitionLag{},
+	}
+
+	if err := a.client.RefreshMetadata(topics...); err != nil {
+		return out, fmt.Errorf("client.RefreshMetadata: %w", err)
+	}
+
+	assigned := make(map[string]map[int32]string)
+
+	groups, err := a.admin.DescribeConsumerGroups([]string{groupID})
+	if err != nil {
+		return out, fmt.Errorf("admin.DescribeConsumerGroups: %w", err)
+	}
+
+	for _, g := range groups {
+		out.GroupState = g.State
+
+		for memberID, m := range g.Members {
+			member := dto.GroupMember{
+				MemberID:   memberID,
+				ClientID:   m.ClientId,
+				ClientHost: m.ClientHost,
+				Assignment: map[string][]int32{},
+			}
+
+			if assignment, err := m.GetMemberAssignment(); err == nil && assignment != nil {
+				for topic, partitions := range assignment.Topics {
+					member.Assignment[topic] = partitions
+					for _, p := range partitions {
+						if assigned[topic] == nil {
+							assigned[topic] = make(map[int32]string)
+						}
+						assigned[topic][p] = memberID
+					}
+				}
+			}
+
+			out.Members = append(out.Members, member)
+		}
+	}
+	sort.Slice(out.Members, func(i, j int) bool { return out.Members[i].MemberID < out.Members[j].MemberID })
+
+	topicPartitions := make(map[string][]int32, len(topics))
+	for _, topic := range topics {
+		partitions, err := a.client.Partitions(topic)
+		if err != nil {
+			return out, fmt.Errorf("client.Partitions %s: %w", topic, err)
+		}
+		topicPartitions[topic] = partitions
+	}
+
+	committed, err := a.admin.ListConsumerGroupOffsets(groupID, topicPartitions)
+	if err != nil {
+		return out, fmt.Errorf("admin.ListConsumerGroupOffsets: %w", err)
+	}
+
+	for _, topic := range topics {
+		partitions := topicPartitions[topic]
+		sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+
+		for _, p := range partitions {
+			hwm, err := a.client.GetOffset(topic, p, sarama.OffsetNewest)
+			if err != nil {
+				return out, fmt.Errorf("client.GetOffset %s/%d: %w", topic, p, err)
+			}
+
+			pl := dto.PartitionLag{
+				Topic:           topic,
+				Partition:       p,
+				CommittedOffset: -1,
+				HighWaterMark:   hwm,
+				Member:          assigned[topic][p],
+			}
+
+			if block := committed.GetBlock(topic, p); block != nil && block.Err == sarama.ErrNoError {
+				pl.CommittedOffset = block.Offset
+			}
+
+			from := pl.CommittedOffset
+			if from < 0 {
+				if from, err = a.client.GetOffset(topic, p, sarama.OffsetOldest); err != nil {
+					return out, fmt.Errorf("client.GetOffset %s/%d: %w", topic, p, err)
+				}
+			}
+
+			pl.Lag = max(hwm-from, 0)
+			out.TotalLag += pl.Lag
+			out.Partitions = append(out.Partitions, pl)
+		}
+	}
+
+	return out, nil
+}
+
+// ResetGroupOffsets переставляет закоммиченные offset группы на любую позицию — вперёд,
+// назад и для партиций без коммитов. Группа должна быть пустой (консьюмер остановлен),
+// иначе координатор отклонит коммит. Offset вне диапазона партиции прижимается к [earliest, latest].
+func (a *Admin) ResetGroupOffsets(_ context.Context, groupID string, topics []string, reset dto.OffsetReset) ([]dto.OffsetResetResult, error) {
+	if err := a.client.RefreshMetadata(topics...); err != nil {
+		return nil, fmt.Errorf("client.RefreshMetadata: %w", err)
+	}
+
+	topicPartitions := make(map[string][]int32, len(topics))
+	for _, topic := range topics {
+		if reset.Topic != "" && reset.Topic != topic {
+			continue
+		}
+
+		partitions, err := a.client.Partitions(topic)
+		if err != nil {
+			return nil, fmt.Errorf("client.Partitions %s: %w", topic, err)
+		}
+
+		if len(reset.Partitions) > 0 {
+			partitions, err = selectPartitions(topic, partitions, reset.Partitions)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+		topicPartitions[topic] = partitions
+	}
+
+	previous, err := a.admin.ListConsumerGroupOffsets(groupID, topicPartitions)
+	if err != nil {
+		return nil, fmt.Errorf("admin.ListConsumerGroupOffsets: %w", err)
+	}
+
+	// Коммит вне поколения группы (generation -1, пустой member_id), как у
+	// kafka-consumer-groups --reset-offsets: offset записывается как есть. OffsetManager
+	// sarama для этого не годится — ResetOffset только уменьшает offset, MarkOffset только
+	// увеличивает, а для партиции без коммитов не делает ничего.
+	req := &sarama.OffsetCommitRequest{
+		Version:                 7,
+		ConsumerGroup:           groupID,
+		ConsumerGroupGeneration: sarama.GroupGenerationUndefined,
+	}
+
+	var results []dto.OffsetResetResult
+	for _, topic := range topics {
+		for _, p := range topicPartitions[topic] {
+			target, err := a.targetOffset(topic, p, reset)
+			if err != nil {
+				return nil, err
+			}
+
+			req.AddBlockWithLeaderEpoch(topic, p, target, -1, 0, "")
+
+			result := dto.OffsetResetResult{Topic: topic, Partition: p, Previous: -1, Offset: target}
+			if block := previous.GetBlock(topic, p); block != nil && block.Err == sarama.ErrNoError {
+				result.Previous = block.Offset
+			}
+			results = append(results, result)
+		}
+	}
+
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	coordinator, err := a.client.Coordinator(groupID)
+	if err != nil {
+		return nil, fmt.Errorf("client.Coordinator %s: %w", groupID, err)
+	}
+
+	resp, err := coordinator.CommitOffset(req)
+	if err != nil {
+		return nil, fmt.Errorf("broker.CommitOffset: %w", err)
+	}
+
+	for _, r := range results {
+		kerr, ok := resp.Errors[r.Topic][r.Partition]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("offset %s/%d: no commit result from coordinator", r.Topic, r.Partition)
+		case errors.Is(kerr, sarama.ErrUnknownMemberId), errors.Is(kerr, sarama.ErrIllegalGeneration), errors.Is(kerr, sarama.ErrRebalanceInProgress):
+			return nil, fmt.Errorf("offset %s/%d not committed: group %s is not empty: %w", r.Topic, r.Partition, groupID, kerr)
+		case kerr != sarama.ErrNoError:
+			return nil, fmt.Errorf("offset %s/%d not committed: %w", r.Topic, r.Partition, kerr)
+		}
+	}
+
+	return results, nil
+}
+
+func (a *Admin) targetOffset(topic string, partition int32, reset dto.OffsetReset) (int64, error) {
+	oldest, err := a.client.GetOffset(topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		return 0, fmt.Errorf("client.GetOffset %s/%d: %w", topic, partition, err)
+	}
+
+	newest, err := a.client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, fmt.Errorf("client.GetOffset %s/%d: %w", topic, partition, err)
+	}
+
+	switch reset.To {
+	case dto.OffsetResetEarliest:
+		return oldest, nil
+	case dto.OffsetResetLatest:
+		return newest, nil
+	case dto.OffsetResetOffset:
+		return min(max(reset.Offset, oldest), newest), nil
+	case dto.OffsetResetTimestamp:
+		if reset.Timestamp == nil {
+			return 0, fmt.Errorf("timestamp is required for reset to %s", reset.To)
+		}
+
+		offset, err := a.client.GetOffset(topic, partition, reset.Timestamp.UnixMilli())
+		if err != nil {
+			return 0, fmt.Errorf("client.GetOffset %s/%d: %w", topic, partition, err)
+		}
+
+		// -1: после timestamp сообщений нет
+		if offset < 0 {
+			return newest, nil
+		}
+
+		return offset, nil
+	}
+
+	return 0, fmt.Errorf("unknown offset reset target '%s'", reset.To)
+}
+
+func selectPartitions(topic string, existing, wanted []int32) ([]int32, error) {
+	known := make(map[int32]bool, len(existing))
+	for _, p := range existing {
+		known[p] = true
+	}
+
+	out := make([]int32, 0, len(wanted))
+	for _, p := range wanted {
+		if !known[p] {
+			return nil, fmt.Errorf("topic %s has no partition %d: %w", topic, p, dto.ErrNotFound)
+		}
+		out = append(out, p)
+	}
+
+	return out, nil
+}
+
+// RecreateTopic удаляет топик и создаёт заново с partitions партициями
+// (partitions <= 0 — сохранить прежнее число, для нового топика — 1).
+// Удаление в Kafka асинхронное, поэтому создание повторяется, пока брокер
+// не перестанет отвечать TopicAlreadyExists, но не дольше ctx.
+func (a *Admin) RecreateTopic(ctx context.Context, topic string, partitions int32) (dto.TopicReset, error) {
+	out := dto.TopicReset{Topic: topic, Partitions: partitions, ReplicationFactor: 1}
+
+	metadata, err := a.admin.DescribeTopics([]string{topic})
+	if err != nil {
+		return out, fmt.Errorf("admin.DescribeTopics: %w", err)
+	}
+
+	for _, md := range metadata {
+		if md.Err != sarama.ErrNoError || len(md.Partitions) == 0 {
+			continue
+		}
+
+		out.Existed = true
+		if out.Partitions <= 0 {
+			out.Partitions = int32(len(md.Partitions))
+		}
+		out.ReplicationFactor = int16(len(md.Partitions[0].Replicas))
+	}
+
+	if out.Partitions <= 0 {
+		out.Partitions = 1
+	}
+
+	if out.Existed {
+		if err := a.admin.DeleteTopic(topic); err != nil && !errors.Is(err, sarama.ErrUnknownTopicOrPartition) {
+			return out, fmt.Errorf("admin.DeleteTopic %s: %w", topic, err)
+		}
+	}
+
+	detail := &sarama.TopicDetail{NumPartitions: out.Partitions, ReplicationFactor: out.ReplicationFactor}
+
+	for {
+		err := a.admin.CreateTopic(topic, detail, false)
+		if err == nil {
+			break
+		}
+
+		if !errors.Is(err, sarama.ErrTopicAlreadyExists) {
+			return out, fmt.Errorf("admin.CreateTopic %s: %w", topic, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return out, fmt.Errorf("admin.CreateTopic %s: topic is still being deleted: %w", topic, ctx.Err())
+		case <-time.After(topicRecreatePoll):
+		}
+	}
+
+	if err := a.client.RefreshMetadata(topic); err != nil {
+		return out, fmt.Errorf("client.RefreshMetadata: %w", err)
+	}
+
+	return out, nil
+}
+
+// EnsureTopics создаёт недостающие топики и добавляет партиции существующим, если их
+// меньше требуемого. Уменьшить число партиций Kafka не позволяет — такие топики
+// возвращаются в отчёте с фактическим числом партиций.
+func (a *Admin) EnsureTopics(_ context.Context, specs []dto.TopicSpec) ([]dto.TopicReset, error) {
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		names = append(names, spec.Name)
+	}
+
+	metadata, err := a.admin.DescribeTopics(names)
+	if err != nil {
+		return nil, fmt.Errorf("admin.DescribeTopics: %w", err)
+	}
+
+	current := make(map[string]int32, len(metadata))
+	for _, md := range metadata {
+		if md.Err == sarama.ErrNoError && len(md.Partitions) > 0 {
+			current[md.Name] = int32(len(md.Partitions))
+		}
+	}
+
+	out := make([]dto.TopicReset, 0, len(specs))
+	for _, spec := range specs {
+		partitions := max(spec.Partitions, 1)
+		state := dto.TopicReset{Topic: spec.Name, Partitions: partitions, ReplicationFactor: 1}
+
+		have, exists := current[spec.Name]
+		switch {
+		case !exists:
+			err := a.admin.CreateTopic(spec.Name, &sarama.TopicDetail{NumPartitions: partitions, ReplicationFactor: 1}, false)
+			if err != nil && !errors.Is(err, sarama.ErrTopicAlreadyExists) {
+				return out, fmt.Errorf("admin.CreateTopic %s: %w", spec.Name, err)
+			}
+		case have < partitions:
+			state.Existed = true
+			if err := a.admin.CreatePartitions(spec.Name, partitions, nil, false); err != nil {
+				return out, fmt.Errorf("admin.CreatePartitions %s: %w", spec.Name, err)
+			}
+		default:
+			state.Existed = true
+			state.Partitions = have
+		}
+
+		out = append(out, state)
+	}
+
+	if err := a.client.RefreshMetadata(names...); err != nil {
+		return out, fmt.Errorf("client.RefreshMetadata: %w", err)
+	}
+
+	return out, nil
+}
+
+// ReadTopic читает топик с самого раннего доступного offset до high-water mark
+// на момент вызова по всем партициям; offset группы при этом не меняются.
+func (a *Admin) ReadTopic(ctx context.Context, topic string) ([]*sarama.ConsumerMessage, error) {
+	partitions, err := a.client.Partitions(topic)
+	if err != nil {
+		return nil, fmt.Errorf("client.Partitions %s: %w", topic, err)
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+
+	consumer, err := sarama.NewConsumerFromClient(a.client)
+	if err != nil {
+		return nil, fmt.Errorf("sarama.NewConsumerFromClient: %w", err)
+	}
+	defer func() { _ = consumer.Close() }()
+
+	var out []*sarama.ConsumerMessage
+	for _, p := range partitions {
+		messages, err := a.readPartition(ctx, consumer, topic, p)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, messages...)
+	}
+
+	return out, nil
+}
+
+func (a *Admin) readPartition(ctx context.Context, consumer sarama.Consumer, topic string, partition int32) ([]*sarama.ConsumerMessage, error) {
+	oldest, err := a.client.GetOffset(topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		return nil, fmt.Errorf("client.GetOffset %s/%d: %w", topic, partition, err)
+	}
+
+	hwm, err := a.client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return nil, fmt.Errorf("client.GetOffset %s/%d: %w", topic, partition, err)
+	}
+
+	if hwm <= oldest {
+		return nil, nil
+	}
+
+	pc, err := consumer.ConsumePartition(topic, partition, oldest)
+	if err != nil {
+		return nil, fmt.Errorf("consumer.ConsumePartition %s/%d: %w", topic, partition, err)
+	}
+	defer func() { _ = pc.Close() }()
+
+	out := make([]*sarama.ConsumerMessage, 0, hwm-oldest)
+	errs := pc.Errors()
+	for {
+		select {
+		case <-ctx.Done():
+			return out, fmt.Errorf("read %s/%d: %w", topic, partition, ctx.Err())
+		case msg, ok := <-pc.Messages():
+			if !ok {
+				return out, fmt.Errorf("read %s/%d: partition consumer closed after %d of %d messages", topic, partition, len(out), hwm-oldest)
+			}
+			out = append(out, msg)
+			if msg.Offset >= hwm-1 {
+				return out, nil
+			}
+		case err, ok := <-errs:
+			if !ok {
+				// Errors закрывается вместе с Messages: о закрытии сообщит ветка Messages
+				errs = nil
+				continue
+			}
+			return out, fmt.Errorf("read %s/%d: %w", topic, partition, err)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package kafkaadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/IBM/sarama"
+)
+
+const (
+	testTopic = "hr.personal"
+	testGroup = "hr-personal-group"
+)
+
+// TestResetGroupOffsets — offset переставляется в любую сторону и для партиции без коммитов;
+// партиция 0: коммит 5, партиция 1: коммитов нет; диапазон каждой партиции [2, 10).
+func TestResetGroupOffsets(t *testing.T) {
+	tests := []struct {
+		name  string
+		reset dto.OffsetReset
+		want  map[int32]int64
+	}{
+		{name: "latest", reset: dto.OffsetReset{To: dto.OffsetResetLatest}, want: map[int32]int64{0: 10, 1: 10}},
+		{name: "earliest", reset: dto.OffsetReset{To: dto.OffsetResetEarliest}, want: map[int32]int64{0: 2, 1: 2}},
+		{name: "offset forward", reset: dto.OffsetReset{To: dto.OffsetResetOffset, Offset: 8}, want: map[int32]int64{0: 8, 1: 8}},
+		{name: "offset clamped", reset: dto.OffsetReset{To: dto.OffsetResetOffset, Offset: 100}, want: map[int32]int64{0: 10, 1: 10}},
+		{name: "one partition", reset: dto.OffsetReset{To: dto.OffsetResetLatest, Partitions: []int32{1}}, want: map[int32]int64{1: 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := newMockCluster(t, map[string]sarama.MockResponse{"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t)})
+			admin := newTestAdmin(t, broker)
+
+			results, err := admin.ResetGroupOffsets(context.Background(), testGroup, []string{testTopic}, tt.reset)
+			if err != nil {
+				t.Fatalf("ResetGroupOffsets: %v", err)
+			}
+
+			if len(results) != len(tt.want) {
+				t.Fatalf("results = %+v, want %d partitions", results, len(tt.want))
+			}
+
+			committed := committedOffsets(t, broker)
+			for _, r := range results {
+				previous := int64(-1)
+				if r.Partition == 0 {
+					previous = 5
+				}
+
+				if r.Offset != tt.want[r.Partition] || r.Previous != previous {
+					t.Errorf("result %d = %+v, want offset %d previous %d", r.Partition, r, tt.want[r.Partition], previous)
+				}
+				if committed[r.Partition] != tt.want[r.Partition] {
+					t.Errorf("committed %d = %d, want %d", r.Partition, committed[r.Partition], tt.want[r.Partition])
+				}
+			}
+		})
+	}
+}
+
+// TestResetGroupOffsetsActiveGroup — активная группа отклоняет коммит вне поколения
+func TestResetGroupOffsetsActiveGroup(t *testing.T) {
+	commit := sarama.NewMockOffsetCommitResponse(t).
+		SetError(testGroup, testTopic, 0, sarama.ErrUnknownMemberId).
+		SetError(testGroup, testTopic, 1, sarama.ErrUnknownMemberId)
+	admin := newTestAdmin(t, newMockCluster(t, map[string]sarama.MockResponse{"OffsetCommitRequest": commit}))
+
+	if _, err := admin.ResetGroupOffsets(context.Background(), testGroup, []string{testTopic}, dto.OffsetReset{To: dto.OffsetResetEarliest}); err == nil {
+		t.Fatal("ResetGroupOffsets: want error for a non-empty group")
+	}
+}
+
+// TestReadTopic — сообщения всех партиций от самого раннего offset до high-water mark
+func TestReadTopic(t *testing.T) {
+	fetch := sarama.NewMockFetchResponse(t, 3)
+	for p := int32(0); p < 2; p++ {
+		for offset := int64(2); offset < 10; offset++ {
+			fetch.SetMessage(testTopic, p, offset, sarama.StringEncoder(fmt.Sprintf("m-%d-%d", p, offset)))
+		}
+		fetch.SetHighWaterMark(testTopic, p, 10)
+	}
+	admin := newTestAdmin(t, newMockCluster(t, map[string]sarama.MockResponse{"FetchRequest": fetch}))
+
+	messages, err := admin.ReadTopic(context.Background(), testTopic)
+	if err != nil {
+		t.Fatalf("ReadTopic: %v", err)
+	}
+
+	if len(messages) != 16 {
+		t.Fatalf("read %d messages, want 16", len(messages))
+	}
+	for i, msg := range messages {
+		p, offset := int32(i/8), int64(2+i%8)
+		if msg.Partition != p || msg.Offset != offset || string(msg.Value) != fmt.Sprintf("m-%d-%d", p, offset) {
+			t.Errorf("message %d = %d/%d %q, want %d/%d", i, msg.Partition, msg.Offset, msg.Value, p, offset)
+		}
+	}
+}
+
+// TestReadTopicPartitionError — ошибка чтения партиции возвращается, а не роняет
+// процесс на закрытом канале Messages (регрессия: nil-сообщение после закрытия)
+func TestReadTopicPartitionError(t *testing.T) {
+	fetch := &sarama.FetchResponse{Version: 11}
+	fetch.AddError(testTopic, 0, sarama.ErrOffsetOutOfRange)
+	admin := newTestAdmin(t, newMockCluster(t, map[string]sarama.MockResponse{"FetchRequest": sarama.NewMockWrapper(fetch)}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := admin.ReadTopic(ctx, testTopic)
+	if !errors.Is(err, sarama.ErrOffsetOutOfRange) {
+		t.Fatalf("ReadTopic: err = %v, want %v", err, sarama.ErrOffsetOutOfRange)
+	}
+}
+
+// newMockCluster — один брокер: лидер обеих партиций testTopic и координатор testGroup;
+// handlers дополняют или заменяют ответы по умолчанию
+func newMockCluster(t *testing.T, handlers map[string]sarama.MockResponse) *sarama.MockBroker {
+	t.Helper()
+
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+
+	responses := map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetController(broker.BrokerID()).
+			SetLeader(testTopic, 0, broker.BrokerID()).
+			SetLeader(testTopic, 1, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset(testTopic, 0, sarama.OffsetOldest, 2).
+			SetOffset(testTopic, 0, sarama.OffsetNewest, 10).
+			SetOffset(testTopic, 1, sarama.OffsetOldest, 2).
+			SetOffset(testTopic, 1, sarama.OffsetNewest, 10),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, testGroup, broker),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset(testGroup, testTopic, 0, 5, "", sarama.ErrNoError),
+	}
+	maps.Copy(responses, handlers)
+	broker.SetHandlerByMap(responses)
+
+	return broker
+}
+
+func newTestAdmin(t *testing.T, broker *sarama.MockBroker) *Admin {
+	t.Helper()
+
+	admin, err := NewAdmin(broker.Addr())
+	if err != nil {
+		t.Fatalf("NewAdmin: %v", err)
+	}
+	t.Cleanup(func() { _ = admin.Close() })
+
+	return admin
+}
+
+// committedOffsets — offset из OffsetCommitRequest, полученного брокером
+func committedOffsets(t *testing.T, broker *sarama.MockBroker) map[int32]int64 {
+	t.Helper()
+
+	out := make(map[int32]int64)
+	for _, rr := range broker.History() {
+		req, ok := rr.Request.(*sarama.OffsetCommitRequest)
+		if !ok {
+			continue
+		}
+
+		if req.ConsumerGroupGeneration != sarama.GroupGenerationUndefined {
+			t.Errorf("commit generation = %d, want %d", req.ConsumerGroupGeneration, sarama.GroupGenerationUndefined)
+		}
+
+		for _, p := range []int32{0, 1} {
+			if offset, _, err := req.Offset(testTopic, p); err == nil {
+				out[p] = offset
+			}
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,377 @@
+package memkafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/IBM/sarama"
+)
+
+// Broker — Kafka в памяти процесса для запуска стенда без брокера (kafka.in_memory).
+// Реализует пути, которыми пользуется стенд: синхронный продюсер (SyncProducer),
+// consumer group с range-назначением партиций, коммитами и паузой (NewConsumerGroup)
+// и служебные операции kafkaadmin.Admin. Топики сами не создаются, как и на стенде
+// с auto.create.topics.enable=false: их создаёт EnsureTopics при старте.
+type Broker struct {
+	mu          sync.Mutex
+	topics      map[string]*topic
+	groups      map[string]*group
+	partitioner sarama.PartitionerConstructor
+	memberSeq   int
+}
+
+type topic struct {
+	partitions []*partition
+}
+
+type partition struct {
+	messages []*sarama.ConsumerMessage // offset сообщения = индекс
+	appended chan struct{}             // закрывается и заменяется при каждой записи
+}
+
+func newPartition() *partition {
+	return &partition{appended: make(chan struct{})}
+}
+
+// NewBroker создаёт пустой брокер; partitioner — как в sarama.Config.Producer.Partitioner
+// (nil — hash по ключу).
+func NewBroker(partitioner sarama.PartitionerConstructor) *Broker {
+	if partitioner == nil {
+		partitioner = sarama.NewHashPartitioner
+	}
+
+	return &Broker{
+		topics:      make(map[string]*topic),
+		groups:      make(map[string]*group),
+		partitioner: partitioner,
+	}
+}
+
+// Close — для совместимости с kafkaadmin.Admin; данные живут до конца процесса
+func (b *Broker) Close() error {
+	return nil
+}
+
+func (b *Broker) produce(msg *sarama.ProducerMessage) (int32, int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[msg.Topic]
+	if !ok {
+		return -1, -1, fmt.Errorf("topic %s: %w", msg.Topic, sarama.ErrUnknownTopicOrPartition)
+	}
+
+	p, err := b.partitioner(msg.Topic).Partition(msg, int32(len(t.partitions)))
+	if err != nil {
+		return -1, -1, err
+	}
+	if p < 0 || int(p) >= len(t.partitions) {
+		return -1, -1, sarama.ErrInvalidPartition
+	}
+
+	out := &sarama.ConsumerMessage{
+		Topic:     msg.Topic,
+		Partition: p,
+		Timestamp: msg.Timestamp,
+	}
+	if out.Timestamp.IsZero() {
+		out.Timestamp = time.Now()
+	}
+
+	if msg.Key != nil {
+		if out.Key, err = msg.Key.Encode(); err != nil {
+			return -1, -1, fmt.Errorf("key.Encode: %w", err)
+		}
+	}
+	if msg.Value != nil {
+		if out.Value, err = msg.Value.Encode(); err != nil {
+			return -1, -1, fmt.Errorf("value.Encode: %w", err)
+		}
+	}
+	for _, h := range msg.Headers {
+		out.Headers = append(out.Headers, &sarama.RecordHeader{Key: h.Key, Value: h.Value})
+	}
+
+	part := t.partitions[p]
+	out.Offset = int64(len(part.messages))
+	part.messages = append(part.messages, out)
+	close(part.appended)
+	part.appended = make(chan struct{})
+
+	msg.Partition, msg.Offset, msg.Timestamp = p, out.Offset, out.Timestamp
+
+	return p, out.Offset, nil
+}
+
+// fetch возвращает сообщение по offset; если его ещё нет — канал, который закроется
+// при следующей записи в партицию. ok=false — партиции больше нет.
+func (b *Broker) fetch(topicName string, p int32, offset int64) (msg *sarama.ConsumerMessage, appended <-chan struct{}, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, exists := b.topics[topicName]
+	if !exists || int(p) >= len(t.partitions) {
+		return nil, nil, false
+	}
+
+	part := t.partitions[p]
+	if offset < int64(len(part.messages)) {
+		return part.messages[offset], nil, true
+	}
+
+	return nil, part.appended, true
+}
+
+// highWaterMark — offset следующего сообщения партиции; -1 — партиции нет. Вызывается под b.mu.
+func (b *Broker) highWaterMark(topicName string, p int32) int64 {
+	t, ok := b.topics[topicName]
+	if !ok || int(p) >= len(t.partitions) {
+		return -1
+	}
+
+	return int64(len(t.partitions[p].messages))
+}
+
+// EnsureTopics создаёт недостающие топики и добавляет партиции существующим
+func (b *Broker) EnsureTopics(_ context.Context, specs []dto.TopicSpec) ([]dto.TopicReset, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := make([]dto.TopicReset, 0, len(specs))
+	for _, spec := range specs {
+		partitions := max(spec.Partitions, 1)
+		state := dto.TopicReset{Topic: spec.Name, Partitions: partitions, ReplicationFactor: 1}
+
+		t, exists := b.topics[spec.Name]
+		switch {
+		case !exists:
+			b.topics[spec.Name] = newTopic(partitions)
+		case int32(len(t.partitions)) < partitions:
+			state.Existed = true
+			for int32(len(t.partitions)) < partitions {
+				t.partitions = append(t.partitions, newPartition())
+			}
+			b.rebalanceTopic(spec.Name)
+		default:
+			state.Existed = true
+			state.Partitions = int32(len(t.partitions))
+		}
+
+		out = append(out, state)
+	}
+
+	return out, nil
+}
+
+// RecreateTopic удаляет сообщения топика и создаёт его заново с partitions партициями
+// (partitions <= 0 — сохранить прежнее число). Как и в Kafka, вместе с топиком удаляются
+// закоммиченные offset всех групп по нему: без перестановки группа читает его с Consumer.Offsets.Initial.
+func (b *Broker) RecreateTopic(_ context.Context, topicName string, partitions int32) (dto.TopicReset, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := dto.TopicReset{Topic: topicName, Partitions: partitions, ReplicationFactor: 1}
+
+	if t, ok := b.topics[topicName]; ok {
+		out.Existed = true
+		if out.Partitions <= 0 {
+			out.Partitions = int32(len(t.partitions))
+		}
+		for _, part := range t.partitions {
+			close(part.appended)
+		}
+		for _, g := range b.groups {
+			delete(g.committed, topicName)
+		}
+	}
+
+	if out.Partitions <= 0 {
+		out.Partitions = 1
+	}
+
+	b.topics[topicName] = newTopic(out.Partitions)
+	b.rebalanceTopic(topicName)
+
+	return out, nil
+}
+
+func newTopic(partitions int32) *topic {
+	t := &topic{partitions: make([]*partition, partitions)}
+	for i := range t.partitions {
+		t.partitions[i] = newPartition()
+	}
+
+	return t
+}
+
+// ReadTopic возвращает все сообщения топика по партициям; offset групп не меняются
+func (b *Broker) ReadTopic(_ context.Context, topicName string) ([]*sarama.ConsumerMessage, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[topicName]
+	if !ok {
+		return nil, fmt.Errorf("topic %s: %w", topicName, sarama.ErrUnknownTopicOrPartition)
+	}
+
+	var out []*sarama.ConsumerMessage
+	for _, part := range t.partitions {
+		out = append(out, part.messages...)
+	}
+
+	return out, nil
+}
+
+// DescribeGroup возвращает committed offset, high-water mark, lag и участников группы
+func (b *Broker) DescribeGroup(_ context.Context, groupID string, topics []string) (dto.ConsumerGroupLag, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := dto.ConsumerGroupLag{
+		GroupID:    groupID,
+		GroupState: "Empty",
+		Members:    []dto.GroupMember{},
+		Partitions: []dto.PartitionLag{},
+	}
+
+	g := b.groups[groupID]
+	assigned := make(map[string]map[int32]string)
+
+	if g != nil && len(g.members) > 0 {
+		out.GroupState = "Stable"
+
+		for _, s := range g.sessions {
+			member := dto.GroupMember{
+				MemberID:   s.memberID,
+				ClientID:   s.clientID,
+				ClientHost: "in-memory",
+				Assignment: s.claims,
+			}
+			for topicName, partitions := range s.claims {
+				for _, p := range partitions {
+					if assigned[topicName] == nil {
+						assigned[topicName] = make(map[int32]string)
+					}
+					assigned[topicName][p] = s.memberID
+				}
+			}
+			out.Members = append(out.Members, member)
+		}
+	}
+	sort.Slice(out.Members, func(i, j int) bool { return out.Members[i].MemberID < out.Members[j].MemberID })
+
+	for _, topicName := range topics {
+		t, ok := b.topics[topicName]
+		if !ok {
+			return out, fmt.Errorf("topic %s: %w", topicName, sarama.ErrUnknownTopicOrPartition)
+		}
+
+		for i := range t.partitions {
+			p := int32(i)
+			pl := dto.PartitionLag{
+				Topic:           topicName,
+				Partition:       p,
+				CommittedOffset: g.committedOffset(topicName, p),
+				HighWaterMark:   b.highWaterMark(topicName, p),
+				Member:          assigned[topicName][p],
+			}
+
+			pl.Lag = max(pl.HighWaterMark-max(pl.CommittedOffset, 0), 0)
+			out.TotalLag += pl.Lag
+			out.Partitions = append(out.Partitions, pl)
+		}
+	}
+
+	return out, nil
+}
+
+// ResetGroupOffsets переставляет закоммиченные offset группы. Как и в Kafka, группа
+// не должна читать (консьюмер остановлен): при активной сессии коммит отклоняется.
+func (b *Broker) ResetGroupOffsets(_ context.Context, groupID string, topics []string, reset dto.OffsetReset) ([]dto.OffsetResetResult, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g := b.group(groupID)
+	if len(g.sessions) > 0 {
+		return nil, fmt.Errorf("offsets not committed: group %s is not empty", groupID)
+	}
+
+	var results []dto.OffsetResetResult
+	for _, topicName := range topics {
+		if reset.Topic != "" && reset.Topic != topicName {
+			continue
+		}
+
+		t, ok := b.topics[topicName]
+		if !ok {
+			return nil, fmt.Errorf("topic %s: %w", topicName, sarama.ErrUnknownTopicOrPartition)
+		}
+
+		partitions := make([]int32, 0, len(t.partitions))
+		if len(reset.Partitions) > 0 {
+			for _, p := range reset.Partitions {
+				if p < 0 || int(p) >= len(t.partitions) {
+					return nil, fmt.Errorf("topic %s has no partition %d: %w", topicName, p, dto.ErrNotFound)
+				}
+				partitions = append(partitions, p)
+			}
+		} else {
+			for i := range t.partitions {
+				partitions = append(partitions, int32(i))
+			}
+		}
+
+		for _, p := range partitions {
+			target, err := targetOffset(t.partitions[p], reset)
+			if err != nil {
+				return nil, err
+			}
+
+			results = append(results, dto.OffsetResetResult{Topic: topicName, Partition: p, Previous: g.committedOffset(topicName, p), Offset: target})
+		}
+	}
+
+	for _, r := range results {
+		g.commit(r.Topic, r.Partition, r.Offset)
+	}
+
+	return results, nil
+}
+
+func targetOffset(part *partition, reset dto.OffsetReset) (int64, error) {
+	newest := int64(len(part.messages))
+
+	switch reset.To {
+	case dto.OffsetResetEarliest:
+		return 0, nil
+	case dto.OffsetResetLatest:
+		return newest, nil
+	case dto.OffsetResetOffset:
+		return min(max(reset.Offset, 0), newest), nil
+	case dto.OffsetResetTimestamp:
+		if reset.Timestamp == nil {
+			return 0, fmt.Errorf("timestamp is required for reset to %s", reset.To)
+		}
+
+		for _, msg := range part.messages {
+			if !msg.Timestamp.Before(*reset.Timestamp) {
+				return msg.Offset, nil
+			}
+		}
+
+		return newest, nil
+	}
+
+	return 0, fmt.Errorf("unknown offset reset target '%s'", reset.To)
+}
+
+var (
+	_ sarama.SyncProducer         = (*syncProducer)(nil)
+	_ sarama.ConsumerGroup        = (*consumerGroup)(nil)
+	_ sarama.ConsumerGroupSession = (*session)(nil)
+	_ sarama.ConsumerGroupClaim   = (*claim)(nil)
+)
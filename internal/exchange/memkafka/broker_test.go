@@ -0,0 +1,420 @@
+package memkafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/IBM/sarama"
+)
+
+// waitTimeout — сколько ждать сообщений и ребаланса
+const waitTimeout = 2 * time.Second
+
+func TestProduceHashPartitioning(t *testing.T) {
+	b := newTestBroker(t, dto.TopicSpec{Name: "hr.personal", Partitions: 3})
+	producer := b.SyncProducer()
+
+	hash := sarama.NewHashPartitioner("hr.personal")
+	offsets := make(map[int32]int64)
+
+	for i := range 12 {
+		key := fmt.Sprintf("e-%d", i%4)
+		msg := &sarama.ProducerMessage{Topic: "hr.personal", Key: sarama.StringEncoder(key), Value: sarama.StringEncoder("{}")}
+
+		want, err := hash.Partition(msg, 3)
+		if err != nil {
+			t.Fatalf("hash.Partition: %v", err)
+		}
+
+		partition, offset, err := producer.SendMessage(msg)
+		if err != nil {
+			t.Fatalf("SendMessage: %v", err)
+		}
+
+		if partition != want {
+			t.Errorf("key %s: partition = %d, want %d", key, partition, want)
+		}
+		if offset != offsets[partition] {
+			t.Errorf("key %s: offset = %d, want %d", key, offset, offsets[partition])
+		}
+		offsets[partition]++
+	}
+
+	_, _, err := producer.SendMessage(&sarama.ProducerMessage{Topic: "hr.unknown", Value: sarama.StringEncoder("{}")})
+	if !errors.Is(err, sarama.ErrUnknownTopicOrPartition) {
+		t.Errorf("SendMessage to unknown topic: err = %v, want %v", err, sarama.ErrUnknownTopicOrPartition)
+	}
+}
+
+// TestConsumeResumesFromCommit — новый участник группы читает с закоммиченного offset
+func TestConsumeResumesFromCommit(t *testing.T) {
+	b := newTestBroker(t, dto.TopicSpec{Name: "hr.personal", Partitions: 1})
+	produceN(t, b, "hr.personal", 5)
+
+	first := startMember(t, b, "group", "hr.personal")
+	first.waitOffsets(t, "hr.personal", 0, 1, 2, 3, 4)
+	first.close(t)
+
+	if got := committed(t, b, "group", "hr.personal", 0); got != 5 {
+		t.Fatalf("committed = %d, want 5", got)
+	}
+
+	produceN(t, b, "hr.personal", 2)
+
+	second := startMember(t, b, "group", "hr.personal")
+	second.waitOffsets(t, "hr.personal", 5, 6)
+}
+
+func TestMarkOffsetOnlyGrowsResetOffsetMovesBack(t *testing.T) {
+	b := newTestBroker(t, dto.TopicSpec{Name: "hr.personal", Partitions: 1})
+	s := &session{broker: b, groupID: "group"}
+
+	s.MarkOffset("hr.personal", 0, 3, "")
+	s.MarkOffset("hr.personal", 0, 1, "")
+	if got := committed(t, b, "group", "hr.personal", 0); got != 3 {
+		t.Fatalf("after MarkOffset 3, 1: committed = %d, want 3", got)
+	}
+
+	s.ResetOffset("hr.personal", 0, 1, "")
+	if got := committed(t, b, "group", "hr.personal", 0); got != 1 {
+		t.Fatalf("after ResetOffset 1: committed = %d, want 1", got)
+	}
+}
+
+// TestRebalanceOnJoinAndLeave — range-назначение делит партиции между участниками,
+// а после выхода одного из них оставшийся получает все партиции.
+func TestRebalanceOnJoinAndLeave(t *testing.T) {
+	b := newTestBroker(t, dto.TopicSpec{Name: "hr.personal", Partitions: 4})
+
+	first := startMember(t, b, "group", "hr.personal")
+	first.waitClaims(t, map[string][]int32{"hr.personal": {0, 1, 2, 3}})
+
+	second := startMember(t, b, "group", "hr.personal")
+	first.waitClaims(t, map[string][]int32{"hr.personal": {0, 1}})
+	second.waitClaims(t, map[string][]int32{"hr.personal": {2, 3}})
+
+	second.close(t)
+	first.waitClaims(t, map[string][]int32{"hr.personal": {0, 1, 2, 3}})
+
+	// после ребаланса сообщения всех партиций доходят до оставшегося участника
+	produceN(t, b, "hr.personal", 8)
+	first.waitCount(t, 8)
+}
+
+func TestPauseAllStopsDelivery(t *testing.T) {
+	b := newTestBroker(t, dto.TopicSpec{Name: "hr.personal", Partitions: 1})
+
+	m := startMember(t, b, "group", "hr.personal")
+	m.waitClaims(t, map[string][]int32{"hr.personal": {0}})
+
+	m.group.PauseAll()
+	produceN(t, b, "hr.personal", 3)
+
+	time.Sleep(100 * time.Millisecond)
+	if n := m.count(); n != 0 {
+		t.Fatalf("paused member received %d messages", n)
+	}
+
+	m.group.ResumeAll()
+	m.waitOffsets(t, "hr.personal", 0, 1, 2)
+}
+
+// TestRecreateTopicDropsGroupOffsets — как и в Kafka, удаление топика удаляет offset групп по нему
+func TestRecreateTopicDropsGroupOffsets(t *testing.T) {
+	b := newTestBroker(t,
+		dto.TopicSpec{Name: "hr.personal", Partitions: 2},
+		dto.TopicSpec{Name: "hr.history", Partitions: 1},
+	)
+	ctx := context.Background()
+
+	produceN(t, b, "hr.personal", 4)
+	produceN(t, b, "hr.history", 2)
+	if _, err := b.ResetGroupOffsets(ctx, "group", []string{"hr.personal", "hr.history"}, dto.OffsetReset{To: dto.OffsetResetLatest}); err != nil {
+		t.Fatalf("ResetGroupOffsets: %v", err)
+	}
+
+	recreated, err := b.RecreateTopic(ctx, "hr.personal", 3)
+	if err != nil {
+		t.Fatalf("RecreateTopic: %v", err)
+	}
+	if !recreated.Existed || recreated.Partitions != 3 {
+		t.Fatalf("RecreateTopic = %+v, want existed with 3 partitions", recreated)
+	}
+
+	lag, err := b.DescribeGroup(ctx, "group", []string{"hr.personal", "hr.history"})
+	if err != nil {
+		t.Fatalf("DescribeGroup: %v", err)
+	}
+
+	// пересозданный топик пуст и без коммитов, offset соседнего топика на месте
+	for _, p := range lag.Partitions {
+		wantCommitted, wantHWM := int64(-1), int64(0)
+		if p.Topic == "hr.history" {
+			wantCommitted, wantHWM = 2, 2
+		}
+
+		if p.CommittedOffset != wantCommitted || p.HighWaterMark != wantHWM {
+			t.Errorf("%s/%d: committed %d, hwm %d; want %d, %d", p.Topic, p.Partition, p.CommittedOffset, p.HighWaterMark, wantCommitted, wantHWM)
+		}
+	}
+
+	results, err := b.ResetGroupOffsets(ctx, "group", []string{"hr.personal"}, dto.OffsetReset{To: dto.OffsetResetEarliest})
+	if err != nil {
+		t.Fatalf("ResetGroupOffsets after recreate: %v", err)
+	}
+	for _, r := range results {
+		if r.Previous != -1 || r.Offset != 0 {
+			t.Errorf("reset %s/%d = %+v, want previous -1, offset 0", r.Topic, r.Partition, r)
+		}
+	}
+}
+
+func TestResetGroupOffsetsRejectsActiveGroup(t *testing.T) {
+	b := newTestBroker(t, dto.TopicSpec{Name: "hr.personal", Partitions: 1})
+
+	m := startMember(t, b, "group", "hr.personal")
+	m.waitClaims(t, map[string][]int32{"hr.personal": {0}})
+
+	if _, err := b.ResetGroupOffsets(context.Background(), "group", []string{"hr.personal"}, dto.OffsetReset{To: dto.OffsetResetEarliest}); err == nil {
+		t.Fatal("ResetGroupOffsets: want error for a group with an active session")
+	}
+}
+
+func TestResetGroupOffsetsTargets(t *testing.T) {
+	b := newTestBroker(t, dto.TopicSpec{Name: "hr.personal", Partitions: 1})
+	ctx := context.Background()
+
+	produceN(t, b, "hr.personal", 3)
+	boundary := time.Now()
+	time.Sleep(time.Millisecond)
+	produceN(t, b, "hr.personal", 2)
+
+	tests := []struct {
+		name  string
+		reset dto.OffsetReset
+		want  int64
+	}{
+		{name: "latest", reset: dto.OffsetReset{To: dto.OffsetResetLatest}, want: 5},
+		{name: "earliest", reset: dto.OffsetReset{To: dto.OffsetResetEarliest}, want: 0},
+		{name: "offset", reset: dto.OffsetReset{To: dto.OffsetResetOffset, Offset: 4}, want: 4},
+		{name: "offset clamped", reset: dto.OffsetReset{To: dto.OffsetResetOffset, Offset: 100}, want: 5},
+		{name: "timestamp", reset: dto.OffsetReset{To: dto.OffsetResetTimestamp, Timestamp: &boundary}, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := b.ResetGroupOffsets(ctx, "group", []string{"hr.personal"}, tt.reset); err != nil {
+				t.Fatalf("ResetGroupOffsets: %v", err)
+			}
+
+			if got := committed(t, b, "group", "hr.personal", 0); got != tt.want {
+				t.Fatalf("committed = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func newTestBroker(t *testing.T, specs ...dto.TopicSpec) *Broker {
+	t.Helper()
+
+	b := NewBroker(nil)
+	if _, err := b.EnsureTopics(context.Background(), specs); err != nil {
+		t.Fatalf("EnsureTopics: %v", err)
+	}
+
+	return b
+}
+
+func produceN(t *testing.T, b *Broker, topic string, n int) {
+	t.Helper()
+
+	producer := b.SyncProducer()
+	for i := range n {
+		msg := &sarama.ProducerMessage{Topic: topic, Key: sarama.StringEncoder(fmt.Sprintf("e-%d", i)), Value: sarama.StringEncoder("{}")}
+		if _, _, err := producer.SendMessage(msg); err != nil {
+			t.Fatalf("SendMessage: %v", err)
+		}
+	}
+}
+
+func committed(t *testing.T, b *Broker, groupID, topic string, partition int32) int64 {
+	t.Helper()
+
+	lag, err := b.DescribeGroup(context.Background(), groupID, []string{topic})
+	if err != nil {
+		t.Fatalf("DescribeGroup: %v", err)
+	}
+
+	for _, p := range lag.Partitions {
+		if p.Partition == partition {
+			return p.CommittedOffset
+		}
+	}
+
+	t.Fatalf("DescribeGroup: no partition %s/%d", topic, partition)
+
+	return 0
+}
+
+// member — участник группы, который крутит Consume, как consumer.Runner, и
+// запоминает полученные сообщения и назначения каждой сессии.
+type member struct {
+	group  sarama.ConsumerGroup
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu       sync.Mutex
+	claims   map[string][]int32
+	messages []*sarama.ConsumerMessage
+	changed  chan struct{}
+}
+
+func startMember(t *testing.T, b *Broker, groupID string, topics ...string) *member {
+	t.Helper()
+
+	// как у consumer.Runner: без коммитов — с начала партиции
+	cfg := sarama.NewConfig()
+	cfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+
+	group, err := b.NewConsumerGroup(groupID, cfg)
+	if err != nil {
+		t.Fatalf("NewConsumerGroup: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &member{group: group, cancel: cancel, done: make(chan struct{}), changed: make(chan struct{})}
+
+	go func() {
+		defer close(m.done)
+		for ctx.Err() == nil {
+			if err := group.Consume(ctx, topics, m); err != nil {
+				return
+			}
+		}
+	}()
+
+	t.Cleanup(func() { m.close(t) })
+
+	return m
+}
+
+func (m *member) close(t *testing.T) {
+	t.Helper()
+
+	m.cancel()
+	select {
+	case <-m.done:
+	case <-time.After(waitTimeout):
+		t.Fatalf("member did not leave within %s", waitTimeout)
+	}
+
+	if err := m.group.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func (m *member) Setup(s sarama.ConsumerGroupSession) error {
+	m.update(func() { m.claims = s.Claims() })
+	return nil
+}
+
+func (m *member) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (m *member) ConsumeClaim(s sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		m.update(func() { m.messages = append(m.messages, msg) })
+		s.MarkMessage(msg, "")
+	}
+
+	return nil
+}
+
+func (m *member) update(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fn()
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+func (m *member) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.messages)
+}
+
+// waitFor ждёт, пока ok не вернёт true; ok вызывается под m.mu
+func (m *member) waitFor(t *testing.T, what string, ok func() bool) {
+	t.Helper()
+
+	timeout := time.After(waitTimeout)
+	for {
+		m.mu.Lock()
+		done, changed := ok(), m.changed
+		m.mu.Unlock()
+
+		if done {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-timeout:
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			t.Fatalf("timeout waiting for %s: claims %v, %d messages", what, m.claims, len(m.messages))
+		}
+	}
+}
+
+func (m *member) waitClaims(t *testing.T, want map[string][]int32) {
+	t.Helper()
+
+	m.waitFor(t, fmt.Sprintf("claims %v", want), func() bool {
+		if len(m.claims) != len(want) {
+			return false
+		}
+		for topic, partitions := range want {
+			if !slices.Equal(m.claims[topic], partitions) {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+func (m *member) waitCount(t *testing.T, n int) {
+	t.Helper()
+
+	m.waitFor(t, fmt.Sprintf("%d messages", n), func() bool { return len(m.messages) >= n })
+}
+
+// waitOffsets ждёт сообщения партиции 0 с offsets и проверяет, что других не было
+func (m *member) waitOffsets(t *testing.T, topic string, offsets ...int64) {
+	t.Helper()
+
+	m.waitCount(t, len(offsets))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	got := make([]int64, 0, len(m.messages))
+	for _, msg := range m.messages {
+		if msg.Topic == topic {
+			got = append(got, msg.Offset)
+		}
+	}
+
+	if !slices.Equal(got, offsets) {
+		t.Fatalf("offsets = %v, want %v", got, offsets)
+	}
+}
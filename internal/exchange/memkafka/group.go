@@ -0,0 +1,487 @@
+package memkafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+// group — consumer group: закоммиченные offset, участники и их текущие сессии
+type group struct {
+	committed  map[string]map[int32]int64
+	members    map[string][]string   // member_id → подписка
+	sessions   map[string]*session   // активная сессия участника
+	closing    map[*session]struct{} // сессии, отменённые ребалансом и ещё не завершившиеся
+	generation int32
+}
+
+// group возвращает группу, создавая её при первом обращении. Вызывается под b.mu.
+func (b *Broker) group(groupID string) *group {
+	g, ok := b.groups[groupID]
+	if !ok {
+		g = &group{
+			committed: make(map[string]map[int32]int64),
+			members:   make(map[string][]string),
+			sessions:  make(map[string]*session),
+			closing:   make(map[*session]struct{}),
+		}
+		b.groups[groupID] = g
+	}
+
+	return g
+}
+
+// committedOffset — закоммиченный offset партиции; -1 — коммитов не было
+func (g *group) committedOffset(topic string, partition int32) int64 {
+	if g == nil {
+		return -1
+	}
+
+	offset, ok := g.committed[topic][partition]
+	if !ok {
+		return -1
+	}
+
+	return offset
+}
+
+func (g *group) commit(topic string, partition int32, offset int64) {
+	if g.committed[topic] == nil {
+		g.committed[topic] = make(map[int32]int64)
+	}
+	g.committed[topic][partition] = offset
+}
+
+// rebalance завершает сессии всех участников: каждый заново войдёт в группу
+// и получит назначение по актуальному составу. Вызывается под b.mu.
+func (g *group) rebalance() {
+	g.generation++
+	for memberID, s := range g.sessions {
+		s.cancel()
+		g.closing[s] = struct{}{}
+		delete(g.sessions, memberID)
+	}
+}
+
+// rebalanceTopic — ребаланс групп, подписанных на топик (изменилось число партиций). Вызывается под b.mu.
+func (b *Broker) rebalanceTopic(topic string) {
+	for _, g := range b.groups {
+		for _, topics := range g.members {
+			if slices.Contains(topics, topic) {
+				g.rebalance()
+				break
+			}
+		}
+	}
+}
+
+// assign — range-назначение: партиции каждого топика делятся на непрерывные
+// диапазоны между подписанными участниками в порядке member_id. Вызывается под b.mu.
+func (b *Broker) assign(g *group, memberID string) map[string][]int32 {
+	claims := make(map[string][]int32)
+
+	for _, topicName := range g.members[memberID] {
+		t, ok := b.topics[topicName]
+		if !ok {
+			continue
+		}
+
+		var subscribed []string
+		for id, topics := range g.members {
+			if slices.Contains(topics, topicName) {
+				subscribed = append(subscribed, id)
+			}
+		}
+		sort.Strings(subscribed)
+
+		idx := slices.Index(subscribed, memberID)
+		n, m := len(t.partitions), len(subscribed)
+		per, extra := n/m, n%m
+		start := idx*per + min(idx, extra)
+		count := per
+		if idx < extra {
+			count++
+		}
+
+		for p := start; p < start+count; p++ {
+			claims[topicName] = append(claims[topicName], int32(p))
+		}
+	}
+
+	return claims
+}
+
+// consumerGroup — участник группы; реализует sarama.ConsumerGroup
+type consumerGroup struct {
+	broker   *Broker
+	groupID  string
+	memberID string
+	cfg      *sarama.Config
+	errors   chan error
+	closed   chan struct{}
+
+	mu           sync.Mutex
+	isClosed     bool
+	pausedAll    bool
+	paused       map[string]map[int32]bool
+	pauseChanged chan struct{} // закрывается и заменяется при каждой смене паузы
+}
+
+// NewConsumerGroup — аналог sarama.NewConsumerGroup для брокера в памяти
+func (b *Broker) NewConsumerGroup(groupID string, cfg *sarama.Config) (sarama.ConsumerGroup, error) {
+	if cfg == nil {
+		cfg = sarama.NewConfig()
+	}
+
+	b.mu.Lock()
+	b.memberSeq++
+	memberID := fmt.Sprintf("%s-%d", cfg.ClientID, b.memberSeq)
+	b.mu.Unlock()
+
+	return &consumerGroup{
+		broker:       b,
+		groupID:      groupID,
+		memberID:     memberID,
+		cfg:          cfg,
+		errors:       make(chan error, cfg.ChannelBufferSize),
+		closed:       make(chan struct{}),
+		paused:       make(map[string]map[int32]bool),
+		pauseChanged: make(chan struct{}),
+	}, nil
+}
+
+// Consume входит в группу и держит сессию, пока не отменён ctx, не случился ребаланс
+// или не завершился один из ConsumeClaim — как sarama.ConsumerGroup.Consume.
+func (c *consumerGroup) Consume(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) error {
+	select {
+	case <-c.closed:
+		return sarama.ErrClosedConsumerGroup
+	default:
+	}
+
+	if len(topics) == 0 {
+		return errors.New("no topics provided")
+	}
+
+	s, wait := c.join(ctx, topics)
+
+	// партиции отдаются только после выхода участников из прошлого поколения
+	for _, done := range wait {
+		select {
+		case <-done:
+		case <-s.ctx.Done():
+			c.broker.endSession(c.groupID, s)
+			return nil
+		}
+	}
+
+	return c.runSession(s, handler)
+}
+
+func (c *consumerGroup) join(ctx context.Context, topics []string) (*session, []<-chan struct{}) {
+	b := c.broker
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g := b.group(c.groupID)
+	if prev, ok := g.members[c.memberID]; !ok || !slices.Equal(prev, topics) {
+		g.members[c.memberID] = slices.Clone(topics)
+		g.rebalance()
+	}
+
+	var wait []<-chan struct{}
+	for s := range g.closing {
+		wait = append(wait, s.done)
+	}
+
+	sessCtx, cancel := context.WithCancel(ctx)
+	s := &session{
+		ctx:        sessCtx,
+		cancel:     cancel,
+		broker:     b,
+		groupID:    c.groupID,
+		memberID:   c.memberID,
+		clientID:   c.cfg.ClientID,
+		generation: g.generation,
+		claims:     b.assign(g, c.memberID),
+		done:       make(chan struct{}),
+	}
+	g.sessions[c.memberID] = s
+
+	return s, wait
+}
+
+func (c *consumerGroup) runSession(s *session, handler sarama.ConsumerGroupHandler) error {
+	defer c.broker.endSession(c.groupID, s)
+
+	if err := handler.Setup(s); err != nil {
+		s.cancel()
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for topicName, partitions := range s.claims {
+		for _, p := range partitions {
+			cl := c.newClaim(topicName, p)
+
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				c.feed(s, cl)
+			}()
+			go func() {
+				defer wg.Done()
+				// как в sarama: завершение любого ConsumeClaim завершает сессию
+				defer s.cancel()
+				if err := handler.ConsumeClaim(s, cl); err != nil {
+					c.handleError(err)
+				}
+			}()
+		}
+	}
+
+	<-s.ctx.Done()
+	wg.Wait()
+
+	return handler.Cleanup(s)
+}
+
+// newClaim начинает с закоммиченного offset, а без коммита или за пределами
+// партиции — с Consumer.Offsets.Initial.
+func (c *consumerGroup) newClaim(topicName string, p int32) *claim {
+	b := c.broker
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hwm := b.highWaterMark(topicName, p)
+	offset := b.group(c.groupID).committedOffset(topicName, p)
+	if offset < 0 || offset > hwm {
+		offset = 0
+		if c.cfg.Consumer.Offsets.Initial == sarama.OffsetNewest {
+			offset = hwm
+		}
+	}
+
+	return &claim{
+		topic:     topicName,
+		partition: p,
+		initial:   offset,
+		hwm:       hwm,
+		messages:  make(chan *sarama.ConsumerMessage, c.cfg.ChannelBufferSize),
+	}
+}
+
+// feed переносит сообщения партиции в канал claim, пока сессия жива
+func (c *consumerGroup) feed(s *session, cl *claim) {
+	defer close(cl.messages)
+
+	next := cl.initial
+	for s.ctx.Err() == nil {
+		if paused, changed := c.pauseState(cl.topic, cl.partition); paused {
+			select {
+			case <-s.ctx.Done():
+			case <-changed:
+			}
+			continue
+		}
+
+		msg, appended, ok := c.broker.fetch(cl.topic, cl.partition, next)
+		if !ok {
+			<-s.ctx.Done()
+			return
+		}
+
+		if msg == nil {
+			_, changed := c.pauseState(cl.topic, cl.partition)
+			select {
+			case <-s.ctx.Done():
+			case <-appended:
+			case <-changed:
+			}
+			continue
+		}
+
+		select {
+		case cl.messages <- msg:
+			next++
+		case <-s.ctx.Done():
+		}
+	}
+}
+
+func (c *consumerGroup) handleError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.isClosed || !c.cfg.Consumer.Return.Errors {
+		return
+	}
+
+	select {
+	case c.errors <- err:
+	default:
+	}
+}
+
+func (c *consumerGroup) Errors() <-chan error {
+	return c.errors
+}
+
+// Close выводит участника из группы (ребаланс для остальных)
+func (c *consumerGroup) Close() error {
+	c.mu.Lock()
+	if c.isClosed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.isClosed = true
+	close(c.closed)
+	close(c.errors)
+	c.mu.Unlock()
+
+	b := c.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g := b.group(c.groupID)
+	if _, ok := g.members[c.memberID]; ok {
+		delete(g.members, c.memberID)
+		g.rebalance()
+	}
+
+	return nil
+}
+
+func (c *consumerGroup) pauseState(topic string, partition int32) (bool, <-chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.pausedAll || c.paused[topic][partition], c.pauseChanged
+}
+
+func (c *consumerGroup) changePause(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fn()
+	close(c.pauseChanged)
+	c.pauseChanged = make(chan struct{})
+}
+
+func (c *consumerGroup) Pause(partitions map[string][]int32) {
+	c.changePause(func() {
+		for topic, ps := range partitions {
+			if c.paused[topic] == nil {
+				c.paused[topic] = make(map[int32]bool)
+			}
+			for _, p := range ps {
+				c.paused[topic][p] = true
+			}
+		}
+	})
+}
+
+func (c *consumerGroup) Resume(partitions map[string][]int32) {
+	c.changePause(func() {
+		for topic, ps := range partitions {
+			for _, p := range ps {
+				delete(c.paused[topic], p)
+			}
+		}
+	})
+}
+
+func (c *consumerGroup) PauseAll() {
+	c.changePause(func() { c.pausedAll = true })
+}
+
+func (c *consumerGroup) ResumeAll() {
+	c.changePause(func() {
+		c.pausedAll = false
+		c.paused = make(map[string]map[int32]bool)
+	})
+}
+
+// endSession убирает завершённую сессию из группы
+func (b *Broker) endSession(groupID string, s *session) {
+	s.cancel()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g := b.group(groupID)
+	if g.sessions[s.memberID] == s {
+		delete(g.sessions, s.memberID)
+	}
+	delete(g.closing, s)
+
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+}
+
+// session — поколение участника в группе; реализует sarama.ConsumerGroupSession
+type session struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	broker     *Broker
+	groupID    string
+	memberID   string
+	clientID   string
+	generation int32
+	claims     map[string][]int32
+	done       chan struct{}
+}
+
+func (s *session) Claims() map[string][]int32 { return s.claims }
+func (s *session) MemberID() string           { return s.memberID }
+func (s *session) GenerationID() int32        { return s.generation }
+func (s *session) Context() context.Context   { return s.ctx }
+func (s *session) Commit()                    {}
+
+// MarkOffset коммитит offset сразу (аналог автокоммита); offset только растёт
+func (s *session) MarkOffset(topic string, partition int32, offset int64, _ string) {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	g := s.broker.group(s.groupID)
+	if offset > g.committedOffset(topic, partition) {
+		g.commit(topic, partition, offset)
+	}
+}
+
+// ResetOffset коммитит offset, в том числе назад
+func (s *session) ResetOffset(topic string, partition int32, offset int64, _ string) {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	s.broker.group(s.groupID).commit(topic, partition, offset)
+}
+
+func (s *session) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1, metadata)
+}
+
+// claim — партиция, назначенная сессии; реализует sarama.ConsumerGroupClaim
+type claim struct {
+	topic     string
+	partition int32
+	initial   int64
+	hwm       int64
+	messages  chan *sarama.ConsumerMessage
+}
+
+func (c *claim) Topic() string                            { return c.topic }
+func (c *claim) Partition() int32                         { return c.partition }
+func (c *claim) InitialOffset() int64                     { return c.initial }
+func (c *claim) HighWaterMarkOffset() int64               { return c.hwm }
+func (c *claim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
@@ -0,0 +1,55 @@
+package memkafka
+
+import (
+	"errors"
+
+	"github.com/IBM/sarama"
+)
+
+// errTransactions — транзакционный продюсер стенду не нужен
+var errTransactions = errors.New("memkafka: transactions are not supported")
+
+// syncProducer — sarama.SyncProducer поверх брокера в памяти
+type syncProducer struct {
+	broker *Broker
+}
+
+// SyncProducer возвращает продюсер, пишущий в брокер; партиция выбирается партиционером брокера
+func (b *Broker) SyncProducer() sarama.SyncProducer {
+	return &syncProducer{broker: b}
+}
+
+func (p *syncProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	return p.broker.produce(msg)
+}
+
+func (p *syncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	var errs sarama.ProducerErrors
+	for _, msg := range msgs {
+		if _, _, err := p.broker.produce(msg); err != nil {
+			errs = append(errs, &sarama.ProducerError{Msg: msg, Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *syncProducer) Close() error { return nil }
+
+func (p *syncProducer) TxnStatus() sarama.ProducerTxnStatusFlag { return sarama.ProducerTxnFlagReady }
+func (p *syncProducer) IsTransactional() bool                   { return false }
+func (p *syncProducer) BeginTxn() error                         { return errTransactions }
+func (p *syncProducer) CommitTxn() error                        { return errTransactions }
+func (p *syncProducer) AbortTxn() error                         { return errTransactions }
+
+func (p *syncProducer) AddOffsetsToTxn(map[string][]*sarama.PartitionOffsetMetadata, string) error {
+	return errTransactions
+}
+
+func (p *syncProducer) AddMessageToTxn(*sarama.ConsumerMessage, string, *string) error {
+	return errTransactions
+}
@@ -0,0 +1,90 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+)
+
+// Chaos — глобальный хаос продюсера: действует на события, для которых в запросе
+// не передан собственный.
+func (p *HRProducer) Chaos() dto.ProducerChaos {
+	if c := p.chaos.Load(); c != nil {
+		return *c
+	}
+
+	return dto.ProducerChaos{}
+}
+
+// SetChaos заменяет глобальный хаос продюсера (значение проверяет вызывающая сторона)
+func (p *HRProducer) SetChaos(chaos dto.ProducerChaos) {
+	p.chaos.Store(&chaos)
+}
+
+func (p *HRProducer) chaosFor(chaos *dto.ProducerChaos) dto.ProducerChaos {
+	if chaos != nil {
+		return *chaos
+	}
+
+	return p.Chaos()
+}
+
+// applyChaos искажает событие до отправки: убирает заголовок, портит тело, выдерживает
+// задержку. Возвращает тело и описание применённого хаоса.
+func applyChaos(ctx context.Context, chaos dto.ProducerChaos, value []byte, headers map[string]string) ([]byte, []string, error) {
+	var applied []string
+
+	if name := chaos.DropHeader; name != "" {
+		if _, ok := headers[name]; ok {
+			delete(headers, name)
+			applied = append(applied, "drop header "+name)
+		}
+	}
+
+	if chaos.CorruptBytes > 0 && len(value) > 0 {
+		var positions []string
+		value, positions = corrupt(value, chaos.CorruptBytes)
+		applied = append(applied, "corrupt bytes "+strings.Join(positions, ","))
+	}
+
+	if chaos.Delay != "" {
+		delay, err := time.ParseDuration(chaos.Delay)
+		if err != nil {
+			return nil, nil, fmt.Errorf("chaos delay: %w", err)
+		}
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+
+		applied = append(applied, "delay "+delay.String())
+	}
+
+	return value, applied, nil
+}
+
+// corrupt инвертирует n случайных байт копии тела; возвращает номера изменённых байт
+func corrupt(value []byte, n int) ([]byte, []string) {
+	out := append([]byte(nil), value...)
+
+	idx := rand.Perm(len(out))[:min(n, len(out))]
+	sort.Ints(idx)
+
+	positions := make([]string, 0, len(idx))
+	for _, i := range idx {
+		out[i] ^= 0xFF
+		positions = append(positions, fmt.Sprint(i))
+	}
+
+	return out, positions
+}
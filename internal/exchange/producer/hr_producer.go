@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"sync/atomic"
+	"time"
 
 	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
 	"github.com/IBM/sarama"
@@ -12,12 +15,20 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// Режимы выбора ключа сообщения
+const (
+	KeyModeMessageID  = "message_id"  // ключ — message_id: партиции заполняются равномерно, порядок по сотруднику не гарантирован
+	KeyModeEmployeeID = "employee_id" // ключ — employee_id: все события сотрудника в одной партиции, порядок сохраняется
+)
+
 type HRProducer struct {
 	sp             sarama.SyncProducer
+	keyMode        string
 	topicPersonal  string
 	topicPositions string
 	topicHistory   string
 	source         string
+	chaos          atomic.Pointer[dto.ProducerChaos]
 	log            zerolog.Logger
 }
 
@@ -26,11 +37,13 @@ type Config struct {
 	TopicPositions string
 	TopicHistory   string
 	Source         string
+	KeyMode        string // KeyModeMessageID (по умолчанию) или KeyModeEmployeeID
 }
 
 func NewHRProducer(sp sarama.SyncProducer, cfg Config, log zerolog.Logger) *HRProducer {
 	return &HRProducer{
 		sp:             sp,
+		keyMode:        cfg.KeyMode,
 		topicPersonal:  cfg.TopicPersonal,
 		topicPositions: cfg.TopicPositions,
 		topicHistory:   cfg.TopicHistory,
@@ -46,9 +59,10 @@ func (p *HRProducer) Close() error {
 	return p.sp.Close()
 }
 
-func (p *HRProducer) ProducePersonal(ctx context.Context, messageID uuid.UUID, profile dto.EmployeeProfile) error {
+func (p *HRProducer) ProducePersonal(ctx context.Context, messageID uuid.UUID, profile dto.EmployeeProfile, chaos *dto.ProducerChaos) (dto.ProduceReceipt, error) {
 	var payload PersonalPayload
 
+	payload.MessageID = messageID.String()
 	payload.EmployeeID = profile.EmployeeID
 	payload.FirstName = profile.FirstName
 	payload.LastName = profile.LastName
@@ -58,18 +72,19 @@ func (p *HRProducer) ProducePersonal(ctx context.Context, messageID uuid.UUID, p
 
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("marshal personal payload: %w", err)
+		return dto.ProduceReceipt{}, fmt.Errorf("marshal personal payload: %w", err)
 	}
 
-	return p.send(ctx, p.topicPersonal, messageID.String(), body, map[string]string{
+	return p.send(ctx, p.topicPersonal, messageID, profile.EmployeeID, body, map[string]string{
 		"event-kind":   "personal",
 		"source":       p.source,
 		"content-type": "application/json",
-	})
+	}, chaos)
 }
 
-func (p *HRProducer) ProducePosition(ctx context.Context, messageID uuid.UUID, profile dto.EmployeeProfile) error {
+func (p *HRProducer) ProducePosition(ctx context.Context, messageID uuid.UUID, profile dto.EmployeeProfile, chaos *dto.ProducerChaos) (dto.ProduceReceipt, error) {
 	var payload = PositionPayload{
+		MessageID:     messageID.String(),
 		EmployeeID:    profile.EmployeeID,
 		Title:         strPtrOrEmpty(profile.Title),
 		Department:    strPtrOrEmpty(profile.Department),
@@ -79,19 +94,20 @@ func (p *HRProducer) ProducePosition(ctx context.Context, messageID uuid.UUID, p
 
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("marshal position payload: %w", err)
+		return dto.ProduceReceipt{}, fmt.Errorf("marshal position payload: %w", err)
 	}
 
-	return p.send(ctx, p.topicPositions, messageID.String(), body, map[string]string{
+	return p.send(ctx, p.topicPositions, messageID, profile.EmployeeID, body, map[string]string{
 		"event-kind":   "position",
 		"source":       p.source,
 		"content-type": "application/json",
-	})
+	}, chaos)
 }
 
-func (p *HRProducer) ProduceHistory(ctx context.Context, messageID uuid.UUID, history dto.EmploymentHistory) error {
+func (p *HRProducer) ProduceHistory(ctx context.Context, messageID uuid.UUID, history dto.EmploymentHistory, chaos *dto.ProducerChaos) (dto.ProduceReceipt, error) {
 	var body HistoryPayload
 
+	body.MessageID = messageID.String()
 	body.EmployeeID = history.EmployeeID
 	body.Company = history.Company
 	body.Position = history.Position
@@ -101,52 +117,163 @@ func (p *HRProducer) ProduceHistory(ctx context.Context, messageID uuid.UUID, hi
 
 	message, err := json.Marshal(body)
 	if err != nil {
-		return fmt.Errorf("json.Marshal: %w", err)
+		return dto.ProduceReceipt{}, fmt.Errorf("json.Marshal: %w", err)
 	}
 
-	return p.send(ctx, p.topicHistory, messageID.String(), message, map[string]string{
+	return p.send(ctx, p.topicHistory, messageID, history.EmployeeID, message, map[string]string{
 		"event-kind": "history",
 		"source":     p.source,
-	})
+	}, chaos)
 }
 
-func (p *HRProducer) send(_ context.Context, topic, key string, value []byte, headers map[string]string) error {
-	if p == nil || p.sp == nil {
-		return errors.New("sync producer is not initialized")
+// ProduceRaw публикует сообщение без какой-либо обработки: ключ, заголовки,
+// партиция и тело передаются в Kafka как есть. Единственное дополнение — заголовок
+// сессии контекста, если он не задан явно.
+func (p *HRProducer) ProduceRaw(ctx context.Context, raw dto.RawMessage) (dto.ProduceReceipt, error) {
+	headers := raw.Headers
+	if session := dto.SessionFrom(ctx); session != "" {
+		if _, ok := headers[dto.HeaderSessionID]; !ok {
+			headers = maps.Clone(headers)
+			if headers == nil {
+				headers = make(map[string]string, 1)
+			}
+			headers[dto.HeaderSessionID] = session
+		}
 	}
 
-	var hs []sarama.RecordHeader
-	for k, v := range headers {
-		hs = append(hs, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	msg := &sarama.ProducerMessage{
+		Topic:   raw.Topic,
+		Value:   sarama.ByteEncoder(raw.Body),
+		Headers: recordHeaders(headers),
 	}
 
-	msg := &sarama.ProducerMessage{
-		Topic:   topic,
-		Key:     sarama.StringEncoder(key),
-		Value:   sarama.ByteEncoder(value),
-		Headers: hs,
+	if raw.Key != nil {
+		msg.Key = sarama.StringEncoder(*raw.Key)
+	}
+
+	if raw.Partition != nil {
+		msg.Partition = *raw.Partition
+		msg.Metadata = explicitPartition{}
+	}
+
+	receipt, err := p.sendMessage(ctx, msg)
+	if err != nil {
+		return dto.ProduceReceipt{}, err
+	}
+
+	// консьюмер берёт message_id из заголовка, а при его отсутствии — из ключа-UUID
+	if id, err := uuid.Parse(raw.Headers[dto.HeaderMessageID]); err == nil {
+		receipt.MessageID = id.String()
+	} else if id, err := uuid.Parse(receipt.Key); err == nil {
+		receipt.MessageID = id.String()
+	}
+
+	return receipt, nil
+}
+
+// send публикует событие; message_id всегда уходит в заголовке message-id, сессия
+// контекста — в заголовке session-id, ключ выбирается режимом keyMode. chaos == nil — действует глобальный хаос (SetChaos);
+// при chaos.duplicate повторные отправки возвращаются в Copies.
+func (p *HRProducer) send(ctx context.Context, topic string, messageID uuid.UUID, employeeID string, value []byte, headers map[string]string, chaos *dto.ProducerChaos) (dto.ProduceReceipt, error) {
+	key := messageID.String()
+	if p.keyMode == KeyModeEmployeeID {
+		key = employeeID
+	}
+	headers[dto.HeaderMessageID] = messageID.String()
+	if session := dto.SessionFrom(ctx); session != "" {
+		headers[dto.HeaderSessionID] = session
+	}
+
+	c := p.chaosFor(chaos)
+
+	value, applied, err := applyChaos(ctx, c, value, headers)
+	if err != nil {
+		return dto.ProduceReceipt{}, err
+	}
+
+	copies := max(c.Duplicate, 1)
+
+	var first dto.ProduceReceipt
+	for i := range copies {
+		receipt, err := p.sendMessage(ctx, &sarama.ProducerMessage{
+			Topic:   topic,
+			Key:     sarama.StringEncoder(key),
+			Value:   sarama.ByteEncoder(value),
+			Headers: recordHeaders(headers),
+		})
+		if err != nil {
+			if i > 0 {
+				return dto.ProduceReceipt{}, fmt.Errorf("duplicate %d/%d: %w", i+1, copies, err)
+			}
+
+			return dto.ProduceReceipt{}, err
+		}
+
+		receipt.MessageID = messageID.String()
+		receipt.Chaos = applied
+		if copies > 1 {
+			receipt.Chaos = append(append([]string(nil), applied...), fmt.Sprintf("duplicate %d/%d", i+1, copies))
+		}
+
+		if i == 0 {
+			first = receipt
+		} else {
+			first.Copies = append(first.Copies, receipt)
+		}
+	}
+
+	return first, nil
+}
+
+func (p *HRProducer) sendMessage(_ context.Context, msg *sarama.ProducerMessage) (dto.ProduceReceipt, error) {
+	if p == nil || p.sp == nil {
+		return dto.ProduceReceipt{}, errors.New("sync producer is not initialized")
 	}
 
+	var key string
+	if msg.Key != nil {
+		b, _ := msg.Key.Encode()
+		key = string(b)
+	}
+
+	msg.Timestamp = time.Now()
+
 	part, off, err := p.sp.SendMessage(msg)
 	if err != nil {
 		p.log.Error().
 			Err(err).
-			Str("topic", topic).
+			Str("topic", msg.Topic).
 			Str("key", key).
-			Int("headers_count", len(headers)).
-			Int("bytes", len(value)).
+			Int("headers_count", len(msg.Headers)).
+			Int("bytes", msg.Value.Length()).
 			Msg("failed to send kafka message")
-		return fmt.Errorf("send kafka message: %w", err)
+		return dto.ProduceReceipt{}, fmt.Errorf("send kafka message: %w", err)
 	}
 
 	p.log.Info().
-		Str("topic", topic).
+		Str("topic", msg.Topic).
 		Str("key", key).
 		Int32("partition", part).
 		Int64("offset", off).
-		Int("bytes", len(value)).
+		Int("bytes", msg.Value.Length()).
 		Msg("kafka message sent")
-	return nil
+
+	return dto.ProduceReceipt{
+		Topic:     msg.Topic,
+		Partition: part,
+		Offset:    off,
+		Key:       key,
+		Timestamp: msg.Timestamp.Format(time.RFC3339),
+	}, nil
+}
+
+func recordHeaders(headers map[string]string) []sarama.RecordHeader {
+	var hs []sarama.RecordHeader
+	for k, v := range headers {
+		hs = append(hs, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+
+	return hs
 }
 
 func strPtrOrEmpty(p *string) string {
@@ -2,10 +2,11 @@ package producer
 
 // PersonalPayload — событие о персональных данных сотрудника
 type PersonalPayload struct {
-	EmployeeID string `json:"employee_id" example:"e-1024"`    // Внутренний идентификатор сотрудника
-	FirstName  string `json:"first_name" example:"Анна"`       // Имя
-	LastName   string `json:"last_name"  example:"Иванова"`    // Фамилия
-	BirthDate  string `json:"birth_date" example:"1994-06-12"` // Дата рождения (YYYY-MM-DD)
+	MessageID  string `json:"message_id" example:"3f1c1e7a-2a0b-4c4e-9f0a-1b2c3d4e5f60"` // Идентификатор события
+	EmployeeID string `json:"employee_id" example:"e-1024"`                              // Внутренний идентификатор сотрудника
+	FirstName  string `json:"first_name" example:"Анна"`                                 // Имя
+	LastName   string `json:"last_name"  example:"Иванова"`                              // Фамилия
+	BirthDate  string `json:"birth_date" example:"1994-06-12"`                           // Дата рождения (YYYY-MM-DD)
 	Contacts   struct {
 		Email string `json:"email" example:"anna.ivanova@company.ru"` // E-mail
 		Phone string `json:"phone" example:"+7 916 123-45-67"`        // Телефон
@@ -14,18 +15,20 @@ type PersonalPayload struct {
 
 // PositionPayload — событие о должности/позиции сотрудника
 type PositionPayload struct {
-	EmployeeID    string `json:"employee_id" example:"e-1024"`                             // Внутренний идентификатор сотрудника
-	Title         string `json:"title,omitempty"        example:"Инженер по тестированию"` // Должность
-	Department    string `json:"department,omitempty"   example:"Отдел качества"`          // Подразделение/отдел
-	Grade         string `json:"grade,omitempty"        example:"Middle"`                  // Грейд
-	EffectiveFrom string `json:"effective_from,omitempty" example:"2025-10-01"`            // Дата вступления в силу (YYYY-MM-DD)
+	MessageID     string `json:"message_id" example:"3f1c1e7a-2a0b-4c4e-9f0a-1b2c3d4e5f60"` // Идентификатор события
+	EmployeeID    string `json:"employee_id" example:"e-1024"`                              // Внутренний идентификатор сотрудника
+	Title         string `json:"title,omitempty"        example:"Инженер по тестированию"`  // Должность
+	Department    string `json:"department,omitempty"   example:"Отдел качества"`           // Подразделение/отдел
+	Grade         string `json:"grade,omitempty"        example:"Middle"`                   // Грейд
+	EffectiveFrom string `json:"effective_from,omitempty" example:"2025-10-01"`             // Дата вступления в силу (YYYY-MM-DD)
 }
 
 // HistoryPayload — событие об изменении/добавлении записи в историю работы
 type HistoryPayload struct {
-	EmployeeID string `json:"employee_id" example:"e-1024"`      // Внутренний идентификатор сотрудника
-	Company    string `json:"company"     example:"ООО Ромашка"` // Компания (РФ)
-	Position   string `json:"position"    example:"Инженер QA"`  // Должность
+	MessageID  string `json:"message_id" example:"3f1c1e7a-2a0b-4c4e-9f0a-1b2c3d4e5f60"` // Идентификатор события
+	EmployeeID string `json:"employee_id" example:"e-1024"`                              // Внутренний идентификатор сотрудника
+	Company    string `json:"company"     example:"ООО Ромашка"`                         // Компания (РФ)
+	Position   string `json:"position"    example:"Инженер QA"`                          // Должность
 	Period     struct {
 		From string `json:"from" example:"2022-07-01"` // Дата начала (YYYY-MM-DD)
 		To   string `json:"to"   example:"2025-09-30"` // Дата окончания (YYYY-MM-DD)
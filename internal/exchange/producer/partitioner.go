@@ -0,0 +1,35 @@
+package producer
+
+import (
+	"github.com/IBM/sarama"
+)
+
+// explicitPartition — метка в ProducerMessage.Metadata: партиция задана вызывающей стороной
+type explicitPartition struct{}
+
+// partitioner — hash-партиционер по ключу, который уважает явно заданную партицию
+// у сырых сообщений (см. ProduceRaw).
+type partitioner struct {
+	hash sarama.Partitioner
+}
+
+// NewPartitioner — конструктор для sarama.Config.Producer.Partitioner
+func NewPartitioner(topic string) sarama.Partitioner {
+	return &partitioner{hash: sarama.NewHashPartitioner(topic)}
+}
+
+func (p *partitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if _, ok := msg.Metadata.(explicitPartition); ok {
+		if msg.Partition < 0 || msg.Partition >= numPartitions {
+			return -1, sarama.ErrInvalidPartition
+		}
+
+		return msg.Partition, nil
+	}
+
+	return p.hash.Partition(msg, numPartitions)
+}
+
+func (p *partitioner) RequiresConsistency() bool {
+	return p.hash.RequiresConsistency()
+}
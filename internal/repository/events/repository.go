@@ -2,8 +2,10 @@ package events
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
 	"github.com/google/uuid"
@@ -26,36 +28,46 @@ func NewRepository(pool PgxPoolIface) *Repository {
 	return &Repository{pool: pool}
 }
 
-func (r *Repository) ExistsMessage(ctx context.Context, messageID uuid.UUID) (bool, error) {
+// Begin открывает транзакцию для атомарного применения сообщения:
+// захват message_id в журнале и бизнес-таблицы (см. *Tx-методы репозиториев).
+func (r *Repository) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.pool.Begin(ctx)
+}
+
+// ClaimMessageTx атомарно «захватывает» message_id: вставляет событие в журнал,
+// а при конфликте по уникальному в сессии message_id ничего не делает и возвращает false.
+// Конкурентная транзакция с тем же message_id ждёт на уникальном индексе до
+// commit/rollback первой, поэтому сообщение применяется ровно один раз.
+func (r *Repository) ClaimMessageTx(ctx context.Context, tx pgx.Tx, event dto.KafkaEvent) (bool, error) {
 	query := `
-SELECT 1
-FROM kafka_events
-WHERE message_id = $1::uuid
-LIMIT 1;
+INSERT INTO kafka_events
+	(session_id, topic, message_id, partition, "offset", payload, received_at)
+VALUES
+	($1, $2, $3::uuid, $4, $5, $6::jsonb, NOW())
+ON CONFLICT (session_id, message_id) DO NOTHING
+RETURNING id;
 `
-	row := r.pool.QueryRow(ctx, query, messageID)
-
-	var x int
-	err := row.Scan(&x)
+	var id int64
+	err := tx.QueryRow(ctx, query, dto.SessionFrom(ctx), event.Topic, event.MessageID, event.Partition, event.Offset, string(event.Payload)).Scan(&id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return false, nil
 		}
 
-		return false, err
+		return false, fmt.Errorf("tx.QueryRow: %w", err)
 	}
 
 	return true, nil
 }
 
-func (r *Repository) InsertEvent(ctx context.Context, event dto.KafkaEvent) error {
+func (r *Repository) InsertDLQ(ctx context.Context, dlq dto.KafkaDLQ) error {
 	query := `
-INSERT INTO kafka_events
-	(topic, message_id, partition, "offset", payload, received_at)
+INSERT INTO kafka_dlq
+	(session_id, topic, message_id, partition, "offset", msg_key, payload, raw_payload, error, received_at)
 VALUES
-	($1, $2::uuid, $3, $4, $5::jsonb, NOW());
+	($1, $2, $3::uuid, $4, $5, $6, $7::jsonb, $8, $9, NOW());
 `
-	_, err := r.pool.Exec(ctx, query, event.Topic, event.MessageID, event.Partition, event.Offset, string(event.Payload))
+	_, err := r.pool.Exec(ctx, query, dto.SessionFrom(ctx), dlq.Topic, dlq.MessageID, dlq.Partition, dlq.Offset, dlq.Key, string(dlqPayload(dlq.Payload)), []byte(dlq.Payload), dlq.Error)
 	if err != nil {
 		return fmt.Errorf("pool.Exec: %w", err)
 	}
@@ -63,14 +75,14 @@ VALUES
 	return nil
 }
 
-func (r *Repository) InsertDLQ(ctx context.Context, dlq dto.KafkaDLQ) error {
+func (r *Repository) InsertReceipt(ctx context.Context, receipt dto.ProduceReceipt) error {
 	query := `
-INSERT INTO kafka_dlq
-	(topic, msg_key, payload, error, received_at)
+INSERT INTO kafka_produced
+	(session_id, message_id, topic, partition, "offset", msg_key, produced_at)
 VALUES
-	($1, $2, $3::jsonb, $4, NOW());
+	($1, nullif($2, '')::uuid, $3, $4, $5, $6, $7::timestamptz);
 `
-	_, err := r.pool.Exec(ctx, query, dlq.Topic, dlq.Key, string(dlq.Payload), dlq.Error)
+	_, err := r.pool.Exec(ctx, query, dto.SessionFrom(ctx), receipt.MessageID, receipt.Topic, receipt.Partition, receipt.Offset, receipt.Key, receipt.Timestamp)
 	if err != nil {
 		return fmt.Errorf("pool.Exec: %w", err)
 	}
@@ -78,13 +90,41 @@ VALUES
 	return nil
 }
 
+func (r *Repository) InsertDecision(ctx context.Context, decision dto.ConsumerDecision) error {
+	query := `
+INSERT INTO kafka_decisions
+	(session_id, message_id, topic, partition, "offset", decision, reason, decided_at)
+VALUES
+	($1, $2::uuid, $3, $4, $5, $6, nullif($7, ''), NOW());
+`
+	_, err := r.pool.Exec(ctx, query, dto.SessionFrom(ctx), decision.MessageID, decision.Topic, decision.Partition, decision.Offset, decision.Decision, decision.Reason)
+	if err != nil {
+		return fmt.Errorf("pool.Exec: %w", err)
+	}
+
+	return nil
+}
+
+// dlqPayload — payload не обязан быть JSON (битое сообщение), а колонка jsonb:
+// такие тела сохраняются JSON-строкой.
+func dlqPayload(payload []byte) []byte {
+	if json.Valid(payload) {
+		return payload
+	}
+
+	wrapped, _ := json.Marshal(string(payload))
+
+	return wrapped
+}
+
 func (r *Repository) ListEvents(ctx context.Context) ([]dto.KafkaEvent, error) {
 	query := `
-SELECT id, topic, message_id, partition, "offset", payload, to_char(received_at, 'YYYY-MM-DD"T"HH24:MI:SSOF')
+SELECT id, topic, message_id, partition, "offset", payload, to_char(received_at, 'YYYY-MM-DD"T"HH24:MI:SSOF'), stale
 FROM kafka_events
+WHERE session_id = $1
 ORDER BY id DESC
 `
-	rows, err := r.pool.Query(ctx, query)
+	rows, err := r.pool.Query(ctx, query, dto.SessionFrom(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("pool.Query: %w", err)
 	}
@@ -97,7 +137,7 @@ ORDER BY id DESC
 			payload    []byte
 		)
 
-		err = rows.Scan(&kafkaEvent.ID, &kafkaEvent.Topic, &kafkaEvent.MessageID, &kafkaEvent.Partition, &kafkaEvent.Offset, &payload, &kafkaEvent.ReceivedAt)
+		err = rows.Scan(&kafkaEvent.ID, &kafkaEvent.Topic, &kafkaEvent.MessageID, &kafkaEvent.Partition, &kafkaEvent.Offset, &payload, &kafkaEvent.ReceivedAt, &kafkaEvent.Stale)
 		if err != nil {
 			return nil, fmt.Errorf("rows.Scan: %w", err)
 		}
@@ -112,30 +152,185 @@ ORDER BY id DESC
 	return out, nil
 }
 
-func (r *Repository) ListDLQ(ctx context.Context) ([]dto.KafkaDLQ, error) {
-	query := `
-select id, topic, msg_key, payload, error, to_char(received_at, 'YYYY-MM-DD"T"HH24:MI:SSOF')
+const dlqColumns = `
+select id, message_id, topic, partition, "offset", msg_key, payload, raw_payload, error, to_char(received_at, 'YYYY-MM-DD"T"HH24:MI:SSOF'),
+       coalesce(replay_status, ''), replay_attempts, to_char(last_replayed_at, 'YYYY-MM-DD"T"HH24:MI:SSOF'), coalesce(last_replay_error, '')
 from kafka_dlq
-order by id desc
 `
-	rows, err := r.pool.Query(ctx, query)
+
+// MarkStaleTx помечает событие журнала устаревшим
+func (r *Repository) MarkStaleTx(ctx context.Context, tx pgx.Tx, messageID uuid.UUID) error {
+	query := `
+UPDATE kafka_events
+SET stale = true
+WHERE session_id = $1 AND message_id = $2::uuid;
+`
+	if _, err := tx.Exec(ctx, query, dto.SessionFrom(ctx), messageID); err != nil {
+		return fmt.Errorf("tx.Exec: %w", err)
+	}
+
+	return nil
+}
+
+// ListEventsForOrdering возвращает события в порядке применения (по id) внутри топика;
+// пустые topic/employeeID — без фильтра.
+func (r *Repository) ListEventsForOrdering(ctx context.Context, topic, employeeID string) ([]dto.KafkaEvent, error) {
+	query := `
+SELECT id, topic, message_id, partition, "offset", payload, to_char(received_at, 'YYYY-MM-DD"T"HH24:MI:SSOF'), stale
+FROM kafka_events
+WHERE session_id = @session_id
+  AND (@topic = '' OR topic = @topic)
+  AND (@employee_id = '' OR payload->>'employee_id' = @employee_id)
+ORDER BY topic, id
+`
+	rows, err := r.pool.Query(ctx, query, pgx.NamedArgs{"session_id": dto.SessionFrom(ctx), "topic": topic, "employee_id": employeeID})
 	if err != nil {
 		return nil, fmt.Errorf("pool.Query: %w", err)
 	}
 	defer rows.Close()
 
+	var out []dto.KafkaEvent
+	for rows.Next() {
+		var (
+			kafkaEvent dto.KafkaEvent
+			payload    []byte
+		)
+
+		err = rows.Scan(&kafkaEvent.ID, &kafkaEvent.Topic, &kafkaEvent.MessageID, &kafkaEvent.Partition, &kafkaEvent.Offset, &payload, &kafkaEvent.ReceivedAt, &kafkaEvent.Stale)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		kafkaEvent.Payload = payload
+		out = append(out, kafkaEvent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return out, nil
+}
+
+func (r *Repository) ListDLQ(ctx context.Context, filter dto.DLQFilter) ([]dto.KafkaDLQ, error) {
+	where := []string{"session_id = @session_id"}
+	args := pgx.NamedArgs{"session_id": dto.SessionFrom(ctx)}
+
+	if filter.Topic != "" {
+		where = append(where, "topic = @topic")
+		args["topic"] = filter.Topic
+	}
+	if filter.ErrorContains != "" {
+		where = append(where, "error ilike '%' || @error || '%'")
+		args["error"] = filter.ErrorContains
+	}
+	if filter.From != "" {
+		where = append(where, "received_at >= @from::timestamptz")
+		args["from"] = filter.From
+	}
+	if filter.To != "" {
+		where = append(where, "received_at <= @to::timestamptz")
+		args["to"] = filter.To
+	}
+	if filter.OnlyPending {
+		where = append(where, "coalesce(replay_status, '') <> 'replayed'")
+	}
+
+	query := dlqColumns + "where " + strings.Join(where, " and ") + "\n"
+	query += "order by id desc\n"
+	if filter.Limit > 0 {
+		query += "limit @limit\n"
+		args["limit"] = filter.Limit
+	}
+
+	rows, err := r.pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("pool.Query: %w", err)
+	}
+
+	return scanDLQ(rows)
+}
+
+func (r *Repository) GetDLQ(ctx context.Context, id int64) (*dto.KafkaDLQ, error) {
+	rows, err := r.pool.Query(ctx, dlqColumns+"where session_id = $1 and id = $2\n", dto.SessionFrom(ctx), id)
+	if err != nil {
+		return nil, fmt.Errorf("pool.Query: %w", err)
+	}
+
+	out, err := scanDLQ(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, dto.ErrNotFound
+	}
+
+	return &out[0], nil
+}
+
+func (r *Repository) ListDLQByMessageID(ctx context.Context, messageID uuid.UUID) ([]dto.KafkaDLQ, error) {
+	rows, err := r.pool.Query(ctx, dlqColumns+"where session_id = $1 and message_id = $2::uuid\norder by id\n", dto.SessionFrom(ctx), messageID)
+	if err != nil {
+		return nil, fmt.Errorf("pool.Query: %w", err)
+	}
+
+	return scanDLQ(rows)
+}
+
+// MarkDLQReplay фиксирует попытку replay: статус, счётчик попыток и ошибку.
+func (r *Repository) MarkDLQReplay(ctx context.Context, id int64, status, replayErr string) error {
+	query := `
+UPDATE kafka_dlq
+SET replay_status     = $3,
+    replay_attempts   = replay_attempts + 1,
+    last_replayed_at  = NOW(),
+    last_replay_error = nullif($4, '')
+WHERE session_id = $1 AND id = $2;
+`
+	tag, err := r.pool.Exec(ctx, query, dto.SessionFrom(ctx), id, status, replayErr)
+	if err != nil {
+		return fmt.Errorf("pool.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return dto.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanDLQ(rows pgx.Rows) ([]dto.KafkaDLQ, error) {
+	defer rows.Close()
+
 	var out []dto.KafkaDLQ
 	for rows.Next() {
 		var (
 			kafkaDLQ dto.KafkaDLQ
+			key      *string
 			payload  []byte
 		)
 
-		err = rows.Scan(&kafkaDLQ.ID, &kafkaDLQ.Topic, &kafkaDLQ.Key, &payload, &kafkaDLQ.Error, &kafkaDLQ.ReceivedAt)
+		err := rows.Scan(
+			&kafkaDLQ.ID,
+			&kafkaDLQ.MessageID,
+			&kafkaDLQ.Topic,
+			&kafkaDLQ.Partition,
+			&kafkaDLQ.Offset,
+			&key,
+			&payload,
+			&kafkaDLQ.RawPayload,
+			&kafkaDLQ.Error,
+			&kafkaDLQ.ReceivedAt,
+			&kafkaDLQ.ReplayStatus,
+			&kafkaDLQ.ReplayAttempts,
+			&kafkaDLQ.LastReplayedAt,
+			&kafkaDLQ.LastReplayError,
+		)
 		if err != nil {
 			return nil, fmt.Errorf("rows.Scan: %w", err)
 		}
 
+		if key != nil {
+			kafkaDLQ.Key = *key
+		}
 		kafkaDLQ.Payload = payload
 		out = append(out, kafkaDLQ)
 	}
@@ -146,12 +341,194 @@ order by id desc
 	return out, nil
 }
 
+func (r *Repository) GetEventByMessageID(ctx context.Context, messageID uuid.UUID) (*dto.KafkaEvent, error) {
+	query := `
+SELECT id, topic, message_id, partition, "offset", payload, to_char(received_at, 'YYYY-MM-DD"T"HH24:MI:SSOF'), stale
+FROM kafka_events
+WHERE session_id = $1 AND message_id = $2::uuid
+`
+	var (
+		kafkaEvent dto.KafkaEvent
+		payload    []byte
+	)
+
+	err := r.pool.QueryRow(ctx, query, dto.SessionFrom(ctx), messageID).
+		Scan(&kafkaEvent.ID, &kafkaEvent.Topic, &kafkaEvent.MessageID, &kafkaEvent.Partition, &kafkaEvent.Offset, &payload, &kafkaEvent.ReceivedAt, &kafkaEvent.Stale)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, dto.ErrNotFound
+		}
+
+		return nil, fmt.Errorf("row.Scan: %w", err)
+	}
+
+	kafkaEvent.Payload = payload
+
+	return &kafkaEvent, nil
+}
+
+func (r *Repository) ListReceiptsByMessageID(ctx context.Context, messageID uuid.UUID) ([]dto.ProduceReceipt, error) {
+	query := `
+SELECT topic, partition, "offset", coalesce(msg_key, ''), message_id::text, to_char(produced_at, 'YYYY-MM-DD"T"HH24:MI:SSOF')
+FROM kafka_produced
+WHERE session_id = $1 AND message_id = $2::uuid
+ORDER BY id
+`
+	rows, err := r.pool.Query(ctx, query, dto.SessionFrom(ctx), messageID)
+	if err != nil {
+		return nil, fmt.Errorf("pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	var out []dto.ProduceReceipt
+	for rows.Next() {
+		var receipt dto.ProduceReceipt
+
+		err = rows.Scan(&receipt.Topic, &receipt.Partition, &receipt.Offset, &receipt.Key, &receipt.MessageID, &receipt.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		out = append(out, receipt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return out, nil
+}
+
+func (r *Repository) ListDecisionsByMessageID(ctx context.Context, messageID uuid.UUID) ([]dto.ConsumerDecision, error) {
+	query := `
+SELECT id, message_id, topic, coalesce(partition, 0), coalesce("offset", 0), decision, coalesce(reason, ''), to_char(decided_at, 'YYYY-MM-DD"T"HH24:MI:SSOF')
+FROM kafka_decisions
+WHERE session_id = $1 AND message_id = $2::uuid
+ORDER BY id
+`
+	rows, err := r.pool.Query(ctx, query, dto.SessionFrom(ctx), messageID)
+	if err != nil {
+		return nil, fmt.Errorf("pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	var out []dto.ConsumerDecision
+	for rows.Next() {
+		var decision dto.ConsumerDecision
+
+		err = rows.Scan(&decision.ID, &decision.MessageID, &decision.Topic, &decision.Partition, &decision.Offset, &decision.Decision, &decision.Reason, &decision.DecidedAt)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		out = append(out, decision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return out, nil
+}
+
+// ListDecisions возвращает решения консьюмеров одного вида (applied, retry, ...) по всем сообщениям
+func (r *Repository) ListDecisions(ctx context.Context, decision string) ([]dto.ConsumerDecision, error) {
+	query := `
+SELECT id, message_id, topic, coalesce(partition, 0), coalesce("offset", 0), decision, coalesce(reason, ''), to_char(decided_at, 'YYYY-MM-DD"T"HH24:MI:SSOF')
+FROM kafka_decisions
+WHERE session_id = $1 AND decision = $2
+ORDER BY id
+`
+	rows, err := r.pool.Query(ctx, query, dto.SessionFrom(ctx), decision)
+	if err != nil {
+		return nil, fmt.Errorf("pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	var out []dto.ConsumerDecision
+	for rows.Next() {
+		var d dto.ConsumerDecision
+
+		err = rows.Scan(&d.ID, &d.MessageID, &d.Topic, &d.Partition, &d.Offset, &d.Decision, &d.Reason, &d.DecidedAt)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return out, nil
+}
+
+// ListEventsForReplay возвращает журнал в порядке пересборки проекций: топики в порядке
+// topics (профили раньше зависящих от них должностей и истории), внутри — partition, offset.
+func (r *Repository) ListEventsForReplay(ctx context.Context, topics []string) ([]dto.KafkaEvent, error) {
+	query := `
+SELECT id, topic, message_id, partition, "offset", payload, to_char(received_at, 'YYYY-MM-DD"T"HH24:MI:SSOF'), stale
+FROM kafka_events
+WHERE session_id = @session_id
+  AND topic = ANY(@topics)
+ORDER BY array_position(@topics, topic), partition, "offset", id
+`
+	rows, err := r.pool.Query(ctx, query, pgx.NamedArgs{"session_id": dto.SessionFrom(ctx), "topics": topics})
+	if err != nil {
+		return nil, fmt.Errorf("pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	var out []dto.KafkaEvent
+	for rows.Next() {
+		var (
+			kafkaEvent dto.KafkaEvent
+			payload    []byte
+		)
+
+		err = rows.Scan(&kafkaEvent.ID, &kafkaEvent.Topic, &kafkaEvent.MessageID, &kafkaEvent.Partition, &kafkaEvent.Offset, &payload, &kafkaEvent.ReceivedAt, &kafkaEvent.Stale)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		kafkaEvent.Payload = payload
+		out = append(out, kafkaEvent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return out, nil
+}
+
+// TruncateProjectionsTx очищает бизнес-таблицы сессии, построенные из журнала, и снимает
+// пометки stale: при пересборке они вычисляются заново. Журнал не трогается.
+func (r *Repository) TruncateProjectionsTx(ctx context.Context, tx pgx.Tx) error {
+	query := `
+WITH history AS (
+    DELETE FROM employment_history WHERE session_id = @session_id
+), profiles AS (
+    DELETE FROM employee_profile WHERE session_id = @session_id
+), assignments AS (
+    DELETE FROM position_assignment WHERE session_id = @session_id
+)
+UPDATE kafka_events SET stale = false WHERE session_id = @session_id AND stale;
+`
+	if _, err := tx.Exec(ctx, query, pgx.NamedArgs{"session_id": dto.SessionFrom(ctx)}); err != nil {
+		return fmt.Errorf("tx.Exec: %w", err)
+	}
+
+	return nil
+}
+
+// ResetAll очищает таблицы всех сессий; список сессий (trainee_session) сохраняется
 func (r *Repository) ResetAll(ctx context.Context) error {
 	query := `
 TRUNCATE kafka_events RESTART IDENTITY CASCADE;
 TRUNCATE kafka_dlq RESTART IDENTITY CASCADE;
+TRUNCATE kafka_produced RESTART IDENTITY CASCADE;
+TRUNCATE kafka_decisions RESTART IDENTITY CASCADE;
 TRUNCATE employment_history RESTART IDENTITY CASCADE;
 TRUNCATE employee_profile RESTART IDENTITY CASCADE;
+TRUNCATE position_assignment RESTART IDENTITY CASCADE;
 `
 	if _, err := r.pool.Exec(ctx, query); err != nil {
 		return fmt.Errorf("pool.Exec: %w", err)
@@ -159,3 +536,29 @@ TRUNCATE employee_profile RESTART IDENTITY CASCADE;
 
 	return nil
 }
+
+// ResetSession удаляет строки сессии контекста из журнала, DLQ, квитанций, решений и
+// бизнес-таблиц; данные остальных сессий не затрагиваются.
+func (r *Repository) ResetSession(ctx context.Context) error {
+	query := `
+WITH events AS (
+    DELETE FROM kafka_events WHERE session_id = @session_id
+), dlq AS (
+    DELETE FROM kafka_dlq WHERE session_id = @session_id
+), produced AS (
+    DELETE FROM kafka_produced WHERE session_id = @session_id
+), decisions AS (
+    DELETE FROM kafka_decisions WHERE session_id = @session_id
+), history AS (
+    DELETE FROM employment_history WHERE session_id = @session_id
+), assignments AS (
+    DELETE FROM position_assignment WHERE session_id = @session_id
+)
+DELETE FROM employee_profile WHERE session_id = @session_id;
+`
+	if _, err := r.pool.Exec(ctx, query, pgx.NamedArgs{"session_id": dto.SessionFrom(ctx)}); err != nil {
+		return fmt.Errorf("pool.Exec: %w", err)
+	}
+
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
@@ -26,13 +27,23 @@ func NewRepository(pool PgxPoolIface) *Repository {
 }
 
 func (r *Repository) Insert(ctx context.Context, history dto.EmploymentHistory) error {
+	return insert(ctx, r.pool, history)
+}
+
+func (r *Repository) InsertTx(ctx context.Context, tx pgx.Tx, history dto.EmploymentHistory) error {
+	return insert(ctx, tx, history)
+}
+
+func insert(ctx context.Context, db PgxPoolIface, history dto.EmploymentHistory) error {
 	query := `
 insert into employment_history
-  (employee_id, company, position, period_from, period_to, stack, created_at)
+  (session_id, employee_id, company, position, period_from, period_to, stack, message_id, created_at)
 values
-  (@employee_id, @company, @position, @period_from::date, @period_to::date, @stack, now());
+  (@session_id, @employee_id, @company, @position, @period_from::date, @period_to::date, @stack, @message_id::uuid, now());
 `
 	args := pgx.NamedArgs{
+		"session_id":  dto.SessionFrom(ctx),
+		"message_id":  history.MessageID,
 		"employee_id": history.EmployeeID,
 		"company":     history.Company,
 		"position":    history.Position,
@@ -41,7 +52,7 @@ values
 		"stack":       history.Stack,
 	}
 
-	_, err := r.pool.Exec(ctx, query, args)
+	_, err := db.Exec(ctx, query, args)
 	if err != nil {
 		return fmt.Errorf("pool.Exec: %w", err)
 	}
@@ -58,9 +69,10 @@ update employment_history set
   period_from = @period_from::date,
   period_to   = @period_to::date,
   stack       = @stack
-where id = @id;
+where session_id = @session_id and id = @id;
 `
 	args := pgx.NamedArgs{
+		"session_id":  dto.SessionFrom(ctx),
 		"id":          history.ID,
 		"employee_id": history.EmployeeID,
 		"company":     history.Company,
@@ -82,9 +94,9 @@ where id = @id;
 }
 
 func (r *Repository) Delete(ctx context.Context, id int64) error {
-	query := `delete from employment_history where id = $1`
+	query := `delete from employment_history where session_id = $1 and id = $2`
 
-	tag, err := r.pool.Exec(ctx, query, id)
+	tag, err := r.pool.Exec(ctx, query, dto.SessionFrom(ctx), id)
 	if err != nil {
 		return fmt.Errorf("pool.Exec: %w", err)
 	}
@@ -103,22 +115,73 @@ select id,
 	   position,
 	   to_char(period_from,'YYYY-MM-DD'),
 	   to_char(period_to,'YYYY-MM-DD'),
-	   stack
+	   stack,
+	   message_id
 from employment_history
-where employee_id = $1
+where session_id = $1 and employee_id = $2
 order by id desc
 `
-	rows, err := r.pool.Query(ctx, query, employeeID)
+	rows, err := r.pool.Query(ctx, query, dto.SessionFrom(ctx), employeeID)
+	if err != nil {
+		return nil, fmt.Errorf("pool.Query: %w", err)
+	}
+
+	return scanHistory(rows)
+}
+
+func (r *Repository) ListByMessageID(ctx context.Context, messageID uuid.UUID) ([]dto.EmploymentHistory, error) {
+	query := `
+select id,
+	   employee_id,
+	   company,
+	   position,
+	   to_char(period_from,'YYYY-MM-DD'),
+	   to_char(period_to,'YYYY-MM-DD'),
+	   stack,
+	   message_id
+from employment_history
+where session_id = $1 and message_id = $2::uuid
+order by id
+`
+	rows, err := r.pool.Query(ctx, query, dto.SessionFrom(ctx), messageID)
 	if err != nil {
 		return nil, fmt.Errorf("pool.Query: %w", err)
 	}
+
+	return scanHistory(rows)
+}
+
+// ListAllTx — вся история сессии внутри транзакции (снимок до и после пересборки проекций)
+func (r *Repository) ListAllTx(ctx context.Context, tx pgx.Tx) ([]dto.EmploymentHistory, error) {
+	query := `
+select id,
+	   employee_id,
+	   company,
+	   position,
+	   to_char(period_from,'YYYY-MM-DD'),
+	   to_char(period_to,'YYYY-MM-DD'),
+	   stack,
+	   message_id
+from employment_history
+where session_id = $1
+order by id
+`
+	rows, err := tx.Query(ctx, query, dto.SessionFrom(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("tx.Query: %w", err)
+	}
+
+	return scanHistory(rows)
+}
+
+func scanHistory(rows pgx.Rows) ([]dto.EmploymentHistory, error) {
 	defer rows.Close()
 
 	var out []dto.EmploymentHistory
 	for rows.Next() {
 		var history dto.EmploymentHistory
 
-		err = rows.Scan(&history.ID, &history.EmployeeID, &history.Company, &history.Position, &history.PeriodFrom, &history.PeriodTo, &history.Stack)
+		err := rows.Scan(&history.ID, &history.EmployeeID, &history.Company, &history.Position, &history.PeriodFrom, &history.PeriodTo, &history.Stack, &history.MessageID)
 		if err != nil {
 			return nil, fmt.Errorf("rows.Scan: %w", err)
 		}
@@ -141,14 +204,15 @@ select id,
 	   position,
 	   to_char(period_from,'YYYY-MM-DD'),
 	   to_char(period_to,'YYYY-MM-DD'),
-	   stack
+	   stack,
+	   message_id
 from employment_history
-where id = $1;
+where session_id = $1 and id = $2;
 `
-	row := r.pool.QueryRow(ctx, query, id)
+	row := r.pool.QueryRow(ctx, query, dto.SessionFrom(ctx), id)
 
 	var history dto.EmploymentHistory
-	err := row.Scan(&history.ID, &history.EmployeeID, &history.Company, &history.Position, &history.PeriodFrom, &history.PeriodTo, &history.Stack)
+	err := row.Scan(&history.ID, &history.EmployeeID, &history.Company, &history.Position, &history.PeriodFrom, &history.PeriodTo, &history.Stack, &history.MessageID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, dto.ErrNotFound
@@ -0,0 +1,688 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/api"
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/Artexxx/HR-Kafka-QA/internal/exchange/consumer"
+	"github.com/Artexxx/HR-Kafka-QA/internal/repository/events"
+	"github.com/Artexxx/HR-Kafka-QA/internal/repository/history"
+	"github.com/Artexxx/HR-Kafka-QA/internal/repository/memory"
+	"github.com/Artexxx/HR-Kafka-QA/internal/repository/profile"
+	"github.com/Artexxx/HR-Kafka-QA/internal/repository/session"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Обе реализации должны удовлетворять интерфейсам API и консьюмеров
+var (
+	_ eventsRepository  = (*memory.EventsRepository)(nil)
+	_ eventsRepository  = (*events.Repository)(nil)
+	_ profileRepository = (*memory.ProfileRepository)(nil)
+	_ profileRepository = (*profile.Repository)(nil)
+	_ historyRepository = (*memory.HistoryRepository)(nil)
+	_ historyRepository = (*history.Repository)(nil)
+
+	_ api.SessionRepository = (*memory.SessionRepository)(nil)
+	_ api.SessionRepository = (*session.Repository)(nil)
+)
+
+type eventsRepository interface {
+	api.EventsRepository
+	consumer.ProjectionEventsRepository
+	consumer.CheckEventsRepository
+}
+
+type profileRepository interface {
+	api.ProfileRepository
+	consumer.ProjectionProfileRepository
+	consumer.CheckProfileRepository
+}
+
+type historyRepository interface {
+	api.HistoryRepository
+	consumer.ProjectionHistoryRepository
+	consumer.CheckHistoryRepository
+}
+
+// contractRepos — репозитории одной реализации над общим хранилищем
+type contractRepos struct {
+	events   eventsRepository
+	profiles profileRepository
+	history  historyRepository
+	sessions api.SessionRepository
+}
+
+func TestContractMemory(t *testing.T) {
+	runContract(t, func() contractRepos {
+		store := memory.NewStore()
+
+		return contractRepos{
+			events:   memory.NewEventsRepository(store),
+			profiles: memory.NewProfileRepository(store),
+			history:  memory.NewHistoryRepository(store),
+			sessions: memory.NewSessionRepository(store),
+		}
+	})
+}
+
+// TestContractPostgres гоняет тот же контракт на Postgres из TEST_POSTGRES_DSN
+// (схема накатана миграциями); каждый тест работает в своей сессии и удаляет её данные.
+func TestContractPostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("pgxpool.New: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	runContract(t, func() contractRepos {
+		return contractRepos{
+			events:   events.NewRepository(pool),
+			profiles: profile.NewRepository(pool),
+			history:  history.NewRepository(pool),
+			sessions: session.NewRepository(pool),
+		}
+	})
+}
+
+func runContract(t *testing.T, newRepos func() contractRepos) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, ctx context.Context, r contractRepos)
+	}{
+		{name: "profile create and get", run: testProfileCreateGet},
+		{name: "profile partial update and delete", run: testProfileUpdateDelete},
+		{name: "profile list order", run: testProfileListOrder},
+		{name: "profile positions", run: testProfilePositions},
+		{name: "profile superseded positions", run: testProfileSupersede},
+		{name: "history", run: testHistory},
+		{name: "events journal", run: testEventsJournal},
+		{name: "events dlq", run: testEventsDLQ},
+		{name: "sessions isolate data", run: testSessionsIsolate},
+		{name: "sessions registry", run: testSessionsRegistry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRepos()
+			ctx := newSession(t, r)
+
+			tt.run(t, ctx, r)
+		})
+	}
+}
+
+// newSession — контекст отдельной сессии: данные тестов не пересекаются, в том числе в общей базе
+func newSession(t *testing.T, r contractRepos) context.Context {
+	t.Helper()
+
+	ctx := dto.WithSession(context.Background(), "contract-"+uuid.NewString())
+	t.Cleanup(func() {
+		if err := r.events.ResetSession(ctx); err != nil {
+			t.Errorf("ResetSession: %v", err)
+		}
+	})
+
+	return ctx
+}
+
+func testProfileCreateGet(t *testing.T, ctx context.Context, r contractRepos) {
+	if _, err := r.profiles.GetProfile(ctx, "e-1"); !errors.Is(err, dto.ErrNotFound) {
+		t.Fatalf("GetProfile missing: err = %v, want %v", err, dto.ErrNotFound)
+	}
+
+	want := newProfile("e-1", "Иванова")
+	want.Title, want.Department, want.Grade, want.EffectiveFrom = ptr("QA"), ptr("Отдел качества"), ptr("Middle"), ptr("2024-01-01")
+	mustDo(t, "Create", r.profiles.Create(ctx, want))
+
+	if err := r.profiles.Create(ctx, want); !errors.Is(err, dto.ErrAlreadyExists) {
+		t.Fatalf("Create duplicate: err = %v, want %v", err, dto.ErrAlreadyExists)
+	}
+
+	got, err := r.profiles.GetProfile(ctx, "e-1")
+	if err != nil {
+		t.Fatalf("GetProfile: %v", err)
+	}
+	assertProfile(t, *got, want)
+}
+
+func testProfileUpdateDelete(t *testing.T, ctx context.Context, r contractRepos) {
+	if err := r.profiles.Update(ctx, newProfile("e-1", "Иванова")); !errors.Is(err, dto.ErrNotFound) {
+		t.Fatalf("Update missing: err = %v, want %v", err, dto.ErrNotFound)
+	}
+
+	created := newProfile("e-1", "Иванова")
+	created.Title, created.Department = ptr("QA"), ptr("Отдел качества")
+	mustDo(t, "Create", r.profiles.Create(ctx, created))
+
+	// опциональные поля меняются, только если присланы
+	update := newProfile("e-1", "Петрова")
+	update.Title = ptr("Senior QA")
+	mustDo(t, "Update", r.profiles.Update(ctx, update))
+
+	got, err := r.profiles.GetProfile(ctx, "e-1")
+	if err != nil {
+		t.Fatalf("GetProfile: %v", err)
+	}
+	want := update
+	want.Department = created.Department
+	assertProfile(t, *got, want)
+
+	mustDo(t, "Delete", r.profiles.Delete(ctx, "e-1"))
+	if err := r.profiles.Delete(ctx, "e-1"); !errors.Is(err, dto.ErrNotFound) {
+		t.Fatalf("Delete missing: err = %v, want %v", err, dto.ErrNotFound)
+	}
+	if _, err := r.profiles.GetProfile(ctx, "e-1"); !errors.Is(err, dto.ErrNotFound) {
+		t.Fatalf("GetProfile deleted: err = %v, want %v", err, dto.ErrNotFound)
+	}
+}
+
+func testProfileListOrder(t *testing.T, ctx context.Context, r contractRepos) {
+	for _, id := range []string{"e-1", "e-2", "e-3"} {
+		mustDo(t, "Create", r.profiles.Create(ctx, newProfile(id, "Иванова")))
+	}
+	mustDo(t, "Update", r.profiles.Update(ctx, newProfile("e-1", "Петрова")))
+
+	// сначала недавно изменённые
+	profiles, err := r.profiles.ListProfiles(ctx)
+	if err != nil {
+		t.Fatalf("ListProfiles: %v", err)
+	}
+	if got := profileIDs(profiles); !slices.Equal(got, []string{"e-1", "e-3", "e-2"}) {
+		t.Errorf("ListProfiles = %v, want [e-1 e-3 e-2]", got)
+	}
+}
+
+func testProfilePositions(t *testing.T, ctx context.Context, r contractRepos) {
+	created := newProfile("e-1", "Иванова")
+	created.Title = ptr("QA")
+	mustDo(t, "Create", r.profiles.Create(ctx, created))
+
+	positions, err := r.profiles.ListPositions(ctx, "e-1")
+	if err != nil {
+		t.Fatalf("ListPositions: %v", err)
+	}
+	if len(positions) != 0 {
+		t.Fatalf("ListPositions without assignments = %+v, want none", positions)
+	}
+
+	tx, err := r.events.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	for _, a := range []struct{ title, from string }{{"Lead", "2999-01-01"}, {"Junior", "2020-01-01"}, {"Middle", "2021-01-01"}} {
+		assignment := dto.PositionAssignment{EmployeeID: "e-1", Title: ptr(a.title), Department: ptr("QA"), Grade: ptr(a.title), EffectiveFrom: a.from}
+		mustDo(t, "InsertAssignmentTx", r.profiles.InsertAssignmentTx(ctx, tx, assignment))
+	}
+	mustDo(t, "Commit", tx.Commit(ctx))
+
+	positions, err = r.profiles.ListPositions(ctx, "e-1")
+	if err != nil {
+		t.Fatalf("ListPositions: %v", err)
+	}
+	var statuses []string
+	for _, p := range positions {
+		statuses = append(statuses, p.EffectiveFrom+" "+p.Status)
+	}
+	if want := []string{"2020-01-01 past", "2021-01-01 current", "2999-01-01 future"}; !slices.Equal(statuses, want) {
+		t.Errorf("ListPositions = %v, want %v", statuses, want)
+	}
+
+	// должность из последнего вступившего в силу назначения, а не из колонок профиля
+	for asOf, title := range map[string]string{"": "Middle", "2020-06-01": "Junior", "3000-01-01": "Lead"} {
+		got, err := r.profiles.GetProfileAsOf(ctx, "e-1", asOf)
+		if err != nil {
+			t.Fatalf("GetProfileAsOf %q: %v", asOf, err)
+		}
+		if got.Title == nil || *got.Title != title {
+			t.Errorf("GetProfileAsOf %q title = %v, want %s", asOf, got.Title, title)
+		}
+	}
+
+	// при назначениях должность через CRUD не меняется, остальные поля — да
+	update := newProfile("e-1", "Петрова")
+	update.Title = ptr("CTO")
+	if err := r.profiles.Update(ctx, update); !errors.Is(err, dto.ErrInvalidState) {
+		t.Fatalf("Update position with assignments: err = %v, want %v", err, dto.ErrInvalidState)
+	}
+	mustDo(t, "Update", r.profiles.Update(ctx, newProfile("e-1", "Петрова")))
+
+	got, err := r.profiles.GetProfile(ctx, "e-1")
+	if err != nil {
+		t.Fatalf("GetProfile: %v", err)
+	}
+	if got.LastName != "Петрова" || got.Title == nil || *got.Title != "Middle" {
+		t.Errorf("GetProfile = last_name %q title %v, want Петрова Middle", got.LastName, got.Title)
+	}
+}
+
+// testProfileSupersede — отменённые назначения (политика arrival) остаются в истории,
+// но не определяют ни должность профиля, ни текущий effective_from для проверки stale
+func testProfileSupersede(t *testing.T, ctx context.Context, r contractRepos) {
+	created := newProfile("e-1", "Иванова")
+	created.Title, created.EffectiveFrom = ptr("Intern"), ptr("2019-01-01")
+	mustDo(t, "Create", r.profiles.Create(ctx, created))
+
+	assertCurrent := func(want string) {
+		t.Helper()
+
+		tx, err := r.events.Begin(ctx)
+		if err != nil {
+			t.Fatalf("Begin: %v", err)
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+
+		got, err := r.profiles.LockEffectiveFromTx(ctx, tx, "e-1")
+		if err != nil {
+			t.Fatalf("LockEffectiveFromTx: %v", err)
+		}
+		if got != want {
+			t.Errorf("LockEffectiveFromTx = %q, want %q", got, want)
+		}
+	}
+
+	// без назначений текущая дата — из профиля
+	assertCurrent("2019-01-01")
+
+	insert := func(tx pgx.Tx, title, from string) {
+		t.Helper()
+
+		assignment := dto.PositionAssignment{EmployeeID: "e-1", Title: ptr(title), Department: ptr("QA"), Grade: ptr("Middle"), EffectiveFrom: from}
+		mustDo(t, "InsertAssignmentTx", r.profiles.InsertAssignmentTx(ctx, tx, assignment))
+	}
+
+	tx, err := r.events.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	insert(tx, "Junior", "2020-01-01")
+	insert(tx, "Middle", "2021-01-01")
+	insert(tx, "Senior", "2022-01-01")
+	mustDo(t, "Commit", tx.Commit(ctx))
+	assertCurrent("2022-01-01")
+
+	// событие 2020-06-01 пришло последним и вытесняет более поздние назначения
+	tx, err = r.events.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	mustDo(t, "SupersedeAssignmentsTx", r.profiles.SupersedeAssignmentsTx(ctx, tx, "e-1", "2020-06-01"))
+	insert(tx, "Lead", "2020-06-01")
+	mustDo(t, "Commit", tx.Commit(ctx))
+	assertCurrent("2020-06-01")
+
+	assertTimeline := func(want []string) {
+		t.Helper()
+
+		positions, err := r.profiles.ListPositions(ctx, "e-1")
+		if err != nil {
+			t.Fatalf("ListPositions: %v", err)
+		}
+		var got []string
+		for _, p := range positions {
+			got = append(got, p.EffectiveFrom+" "+p.Status)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("ListPositions = %v, want %v", got, want)
+		}
+	}
+
+	assertTimeline([]string{"2020-01-01 past", "2020-06-01 current", "2021-01-01 superseded", "2022-01-01 superseded"})
+
+	for asOf, title := range map[string]string{"": "Lead", "2020-03-01": "Junior", "2021-06-01": "Lead", "2023-01-01": "Lead"} {
+		got, err := r.profiles.GetProfileAsOf(ctx, "e-1", asOf)
+		if err != nil {
+			t.Fatalf("GetProfileAsOf %q: %v", asOf, err)
+		}
+		if deref(got.Title) != title {
+			t.Errorf("GetProfileAsOf %q title = %q, want %s", asOf, deref(got.Title), title)
+		}
+	}
+
+	// новое событие на дату отменённого назначения заменяет его и снова действует
+	tx, err = r.events.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	insert(tx, "Principal", "2022-01-01")
+	mustDo(t, "Commit", tx.Commit(ctx))
+	assertCurrent("2022-01-01")
+	assertTimeline([]string{"2020-01-01 past", "2020-06-01 past", "2021-01-01 superseded", "2022-01-01 current"})
+}
+
+func testHistory(t *testing.T, ctx context.Context, r contractRepos) {
+	messageID := uuid.New()
+	for i, company := range []string{"Альфа", "Бета"} {
+		h := dto.EmploymentHistory{EmployeeID: "e-1", Company: company, Position: "QA", PeriodFrom: "2020-01-01", PeriodTo: "2021-01-01", Stack: []string{"Go"}}
+		if i == 1 {
+			h.MessageID = &messageID
+		}
+		mustDo(t, "Insert", r.history.Insert(ctx, h))
+	}
+
+	// сначала новые
+	rows, err := r.history.ListByEmployee(ctx, "e-1")
+	if err != nil {
+		t.Fatalf("ListByEmployee: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Company != "Бета" || rows[1].Company != "Альфа" {
+		t.Fatalf("ListByEmployee = %+v, want Бета, Альфа", rows)
+	}
+
+	byMessage, err := r.history.ListByMessageID(ctx, messageID)
+	if err != nil {
+		t.Fatalf("ListByMessageID: %v", err)
+	}
+	if len(byMessage) != 1 || byMessage[0].ID != rows[0].ID {
+		t.Errorf("ListByMessageID = %+v, want record %d", byMessage, rows[0].ID)
+	}
+
+	update := rows[1]
+	update.Company, update.Stack = "Гамма", []string{"Go", "Kafka"}
+	mustDo(t, "Update", r.history.Update(ctx, update))
+
+	got, err := r.history.GetByID(ctx, update.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Company != "Гамма" || !slices.Equal(got.Stack, update.Stack) || got.PeriodFrom != "2020-01-01" {
+		t.Errorf("GetByID = %+v, want updated %+v", got, update)
+	}
+
+	mustDo(t, "Delete", r.history.Delete(ctx, update.ID))
+
+	missing := update.ID
+	if _, err := r.history.GetByID(ctx, missing); !errors.Is(err, dto.ErrNotFound) {
+		t.Errorf("GetByID deleted: err = %v, want %v", err, dto.ErrNotFound)
+	}
+	if err := r.history.Update(ctx, update); !errors.Is(err, dto.ErrNotFound) {
+		t.Errorf("Update deleted: err = %v, want %v", err, dto.ErrNotFound)
+	}
+	if err := r.history.Delete(ctx, missing); !errors.Is(err, dto.ErrNotFound) {
+		t.Errorf("Delete deleted: err = %v, want %v", err, dto.ErrNotFound)
+	}
+}
+
+func testEventsJournal(t *testing.T, ctx context.Context, r contractRepos) {
+	first, second := uuid.New(), uuid.New()
+	if _, err := r.events.GetEventByMessageID(ctx, first); !errors.Is(err, dto.ErrNotFound) {
+		t.Fatalf("GetEventByMessageID missing: err = %v, want %v", err, dto.ErrNotFound)
+	}
+
+	claim := func(event dto.KafkaEvent) bool {
+		t.Helper()
+
+		tx, err := r.events.Begin(ctx)
+		if err != nil {
+			t.Fatalf("Begin: %v", err)
+		}
+		claimed, err := r.events.ClaimMessageTx(ctx, tx, event)
+		if err != nil {
+			t.Fatalf("ClaimMessageTx: %v", err)
+		}
+		mustDo(t, "Commit", tx.Commit(ctx))
+
+		return claimed
+	}
+
+	if !claim(journalEvent(first, "hr.positions", 1, "e-1")) {
+		t.Fatal("ClaimMessageTx: first delivery not claimed")
+	}
+	if !claim(journalEvent(second, "hr.personal", 0, "e-2")) {
+		t.Fatal("ClaimMessageTx: second message not claimed")
+	}
+	if claim(journalEvent(first, "hr.positions", 2, "e-1")) {
+		t.Fatal("ClaimMessageTx: duplicate message_id claimed")
+	}
+
+	// сначала новые
+	journal, err := r.events.ListEvents(ctx)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if len(journal) != 2 || journal[0].MessageID != second || journal[1].MessageID != first {
+		t.Fatalf("ListEvents = %+v, want %s, %s", journal, second, first)
+	}
+
+	ordering, err := r.events.ListEventsForOrdering(ctx, "", "e-1")
+	if err != nil {
+		t.Fatalf("ListEventsForOrdering: %v", err)
+	}
+	if len(ordering) != 1 || ordering[0].MessageID != first || ordering[0].Offset != 1 {
+		t.Errorf("ListEventsForOrdering e-1 = %+v, want %s at offset 1", ordering, first)
+	}
+
+	replay, err := r.events.ListEventsForReplay(ctx, []string{"hr.personal", "hr.positions"})
+	if err != nil {
+		t.Fatalf("ListEventsForReplay: %v", err)
+	}
+	if len(replay) != 2 || replay[0].MessageID != second || replay[1].MessageID != first {
+		t.Errorf("ListEventsForReplay = %+v, want %s, %s", replay, second, first)
+	}
+
+	tx, err := r.events.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	mustDo(t, "MarkStaleTx", r.events.MarkStaleTx(ctx, tx, first))
+	mustDo(t, "Commit", tx.Commit(ctx))
+
+	event, err := r.events.GetEventByMessageID(ctx, first)
+	if err != nil {
+		t.Fatalf("GetEventByMessageID: %v", err)
+	}
+	if !event.Stale || event.Topic != "hr.positions" {
+		t.Errorf("GetEventByMessageID = %+v, want stale hr.positions", event)
+	}
+
+	for _, decision := range []string{dto.DecisionApplied, dto.DecisionDuplicate} {
+		mustDo(t, "InsertDecision", r.events.InsertDecision(ctx, dto.ConsumerDecision{MessageID: &first, Topic: "hr.positions", Decision: decision}))
+	}
+	decisions, err := r.events.ListDecisionsByMessageID(ctx, first)
+	if err != nil {
+		t.Fatalf("ListDecisionsByMessageID: %v", err)
+	}
+	if len(decisions) != 2 || decisions[0].Decision != dto.DecisionApplied || decisions[1].Decision != dto.DecisionDuplicate {
+		t.Errorf("ListDecisionsByMessageID = %+v, want applied, duplicate", decisions)
+	}
+
+	duplicates, err := r.events.ListDecisions(ctx, dto.DecisionDuplicate)
+	if err != nil {
+		t.Fatalf("ListDecisions: %v", err)
+	}
+	if len(duplicates) != 1 {
+		t.Errorf("ListDecisions duplicate = %+v, want one", duplicates)
+	}
+}
+
+func testEventsDLQ(t *testing.T, ctx context.Context, r contractRepos) {
+	if _, err := r.events.GetDLQ(ctx, 1<<40); !errors.Is(err, dto.ErrNotFound) {
+		t.Fatalf("GetDLQ missing: err = %v, want %v", err, dto.ErrNotFound)
+	}
+	if err := r.events.MarkDLQReplay(ctx, 1<<40, dto.ReplayStatusReplayed, ""); !errors.Is(err, dto.ErrNotFound) {
+		t.Fatalf("MarkDLQReplay missing: err = %v, want %v", err, dto.ErrNotFound)
+	}
+
+	messageID := uuid.New()
+	mustDo(t, "InsertDLQ", r.events.InsertDLQ(ctx, dto.KafkaDLQ{MessageID: &messageID, Topic: "hr.personal", Payload: []byte(`{"employee_id":"e-1"}`), Error: "required field 'first_name'"}))
+	mustDo(t, "InsertDLQ", r.events.InsertDLQ(ctx, dto.KafkaDLQ{Topic: "hr.history", Payload: []byte("not json"), Error: "invalid JSON"}))
+
+	// сначала новые
+	all, err := r.events.ListDLQ(ctx, dto.DLQFilter{})
+	if err != nil {
+		t.Fatalf("ListDLQ: %v", err)
+	}
+	if len(all) != 2 || all[0].Topic != "hr.history" || all[1].Topic != "hr.personal" {
+		t.Fatalf("ListDLQ = %+v, want hr.history, hr.personal", all)
+	}
+	if string(all[0].RawPayload) != "not json" {
+		t.Errorf("RawPayload = %q, want the original body", all[0].RawPayload)
+	}
+
+	filtered, err := r.events.ListDLQ(ctx, dto.DLQFilter{ErrorContains: "FIRST_NAME"})
+	if err != nil {
+		t.Fatalf("ListDLQ filtered: %v", err)
+	}
+	if len(filtered) != 1 || filtered[0].ID != all[1].ID {
+		t.Errorf("ListDLQ error_contains = %+v, want record %d", filtered, all[1].ID)
+	}
+
+	mustDo(t, "MarkDLQReplay", r.events.MarkDLQReplay(ctx, all[1].ID, dto.ReplayStatusFailed, "boom"))
+	mustDo(t, "MarkDLQReplay", r.events.MarkDLQReplay(ctx, all[1].ID, dto.ReplayStatusReplayed, ""))
+
+	replayed, err := r.events.GetDLQ(ctx, all[1].ID)
+	if err != nil {
+		t.Fatalf("GetDLQ: %v", err)
+	}
+	if replayed.ReplayStatus != dto.ReplayStatusReplayed || replayed.ReplayAttempts != 2 || replayed.LastReplayedAt == nil {
+		t.Errorf("GetDLQ = %+v, want replayed after 2 attempts", replayed)
+	}
+
+	pending, err := r.events.ListDLQ(ctx, dto.DLQFilter{OnlyPending: true})
+	if err != nil {
+		t.Fatalf("ListDLQ pending: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != all[0].ID {
+		t.Errorf("ListDLQ only_pending = %+v, want record %d", pending, all[0].ID)
+	}
+
+	byMessage, err := r.events.ListDLQByMessageID(ctx, messageID)
+	if err != nil {
+		t.Fatalf("ListDLQByMessageID: %v", err)
+	}
+	if len(byMessage) != 1 || byMessage[0].ID != all[1].ID {
+		t.Errorf("ListDLQByMessageID = %+v, want record %d", byMessage, all[1].ID)
+	}
+}
+
+func testSessionsIsolate(t *testing.T, ctx context.Context, r contractRepos) {
+	other := newSession(t, r)
+
+	mustDo(t, "Create", r.profiles.Create(ctx, newProfile("e-1", "Иванова")))
+	// тот же employee_id в другой сессии — другой сотрудник
+	mustDo(t, "Create other", r.profiles.Create(other, newProfile("e-1", "Петрова")))
+
+	got, err := r.profiles.GetProfile(other, "e-1")
+	if err != nil {
+		t.Fatalf("GetProfile other: %v", err)
+	}
+	if got.LastName != "Петрова" {
+		t.Errorf("GetProfile other = %q, want Петрова", got.LastName)
+	}
+
+	mustDo(t, "ResetSession", r.events.ResetSession(other))
+
+	if _, err := r.profiles.GetProfile(other, "e-1"); !errors.Is(err, dto.ErrNotFound) {
+		t.Errorf("GetProfile after reset: err = %v, want %v", err, dto.ErrNotFound)
+	}
+	if _, err := r.profiles.GetProfile(ctx, "e-1"); err != nil {
+		t.Errorf("GetProfile in untouched session: %v", err)
+	}
+}
+
+func testSessionsRegistry(t *testing.T, ctx context.Context, r contractRepos) {
+	id := "contract-" + uuid.NewString()
+	if _, err := r.sessions.Get(ctx, id); !errors.Is(err, dto.ErrNotFound) {
+		t.Fatalf("Get missing: err = %v, want %v", err, dto.ErrNotFound)
+	}
+
+	created, err := r.sessions.Create(ctx, dto.Session{ID: id, Name: "Анна"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	t.Cleanup(func() { _ = r.sessions.Delete(context.Background(), id) })
+
+	if created.CreatedAt == "" {
+		t.Error("Create: created_at is empty")
+	}
+	if _, err := r.sessions.Create(ctx, dto.Session{ID: id, Name: "Анна"}); !errors.Is(err, dto.ErrAlreadyExists) {
+		t.Fatalf("Create duplicate: err = %v, want %v", err, dto.ErrAlreadyExists)
+	}
+
+	got, err := r.sessions.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Name != "Анна" {
+		t.Errorf("Get = %+v, want name Анна", got)
+	}
+
+	mustDo(t, "Delete", r.sessions.Delete(ctx, id))
+	if err := r.sessions.Delete(ctx, id); !errors.Is(err, dto.ErrNotFound) {
+		t.Errorf("Delete missing: err = %v, want %v", err, dto.ErrNotFound)
+	}
+}
+
+func newProfile(employeeID, lastName string) dto.EmployeeProfile {
+	return dto.EmployeeProfile{
+		EmployeeID: employeeID,
+		FirstName:  "Анна",
+		LastName:   lastName,
+		BirthDate:  "1994-06-12",
+		Email:      "anna@mail.ru",
+		Phone:      "+79160000000",
+	}
+}
+
+func journalEvent(messageID uuid.UUID, topic string, offset int64, employeeID string) dto.KafkaEvent {
+	return dto.KafkaEvent{
+		MessageID: messageID,
+		Topic:     topic,
+		Offset:    offset,
+		Payload:   []byte(`{"employee_id":"` + employeeID + `"}`),
+	}
+}
+
+func assertProfile(t *testing.T, got, want dto.EmployeeProfile) {
+	t.Helper()
+
+	if got.EmployeeID != want.EmployeeID || got.FirstName != want.FirstName || got.LastName != want.LastName ||
+		got.BirthDate != want.BirthDate || got.Email != want.Email || got.Phone != want.Phone ||
+		deref(got.Title) != deref(want.Title) || deref(got.Department) != deref(want.Department) ||
+		deref(got.Grade) != deref(want.Grade) || deref(got.EffectiveFrom) != deref(want.EffectiveFrom) {
+		t.Errorf("profile = %s, want %s", describe(got), describe(want))
+	}
+}
+
+func describe(p dto.EmployeeProfile) string {
+	return p.EmployeeID + " " + p.FirstName + " " + p.LastName + " " + p.BirthDate + " " + p.Email + " " + p.Phone + " " +
+		deref(p.Title) + "/" + deref(p.Department) + "/" + deref(p.Grade) + "/" + deref(p.EffectiveFrom)
+}
+
+func profileIDs(profiles []dto.EmployeeProfile) []string {
+	var out []string
+	for _, p := range profiles {
+		out = append(out, p.EmployeeID)
+	}
+
+	return out
+}
+
+func mustDo(t *testing.T, what string, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+}
+
+func ptr(s string) *string { return &s }
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
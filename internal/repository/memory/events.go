@@ -0,0 +1,393 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// EventsRepository — kafka_events, kafka_dlq, kafka_produced и kafka_decisions в памяти
+type EventsRepository struct {
+	store *Store
+}
+
+func NewEventsRepository(store *Store) *EventsRepository {
+	return &EventsRepository{store: store}
+}
+
+// Begin открывает транзакцию для атомарного применения сообщения (см. *Tx-методы репозиториев)
+func (r *EventsRepository) Begin(ctx context.Context) (pgx.Tx, error) {
+	return r.store.Begin(ctx)
+}
+
+func (d *tables) eventIndex(messageID uuid.UUID) int {
+	return slices.IndexFunc(d.events, func(e dto.KafkaEvent) bool { return e.MessageID == messageID })
+}
+
+// ClaimMessageTx атомарно «захватывает» message_id: вставляет событие в журнал,
+// а если message_id уже есть, ничего не делает и возвращает false.
+func (r *EventsRepository) ClaimMessageTx(ctx context.Context, tx pgx.Tx, event dto.KafkaEvent) (bool, error) {
+	var claimed bool
+	err := r.store.write(ctx, tx, func(d *tables, now time.Time) error {
+		claimed = d.eventIndex(event.MessageID) < 0
+		if claimed {
+			d.insertEvent(event, now)
+		}
+
+		return nil
+	})
+
+	return claimed, err
+}
+
+func (d *tables) insertEvent(event dto.KafkaEvent, now time.Time) {
+	d.eventSeq++
+	event.ID = d.eventSeq
+	event.Payload = slices.Clone(event.Payload)
+	event.ReceivedAt = now.Format(timeLayout)
+	event.Stale = false
+	d.events = append(d.events, event)
+}
+
+// MarkStaleTx помечает событие журнала устаревшим
+func (r *EventsRepository) MarkStaleTx(ctx context.Context, tx pgx.Tx, messageID uuid.UUID) error {
+	return r.store.write(ctx, tx, func(d *tables, _ time.Time) error {
+		if i := d.eventIndex(messageID); i >= 0 {
+			d.events[i].Stale = true
+		}
+
+		return nil
+	})
+}
+
+func (r *EventsRepository) InsertDLQ(ctx context.Context, dlq dto.KafkaDLQ) error {
+	return r.store.write(ctx, nil, func(d *tables, now time.Time) error {
+		d.dlqSeq++
+		d.dlq = append(d.dlq, dto.KafkaDLQ{
+			ID:         d.dlqSeq,
+			MessageID:  dlq.MessageID,
+			Topic:      dlq.Topic,
+			Partition:  dlq.Partition,
+			Offset:     dlq.Offset,
+			Key:        dlq.Key,
+			Payload:    dlqPayload(dlq.Payload),
+			RawPayload: slices.Clone([]byte(dlq.Payload)),
+			Error:      dlq.Error,
+			ReceivedAt: now.Format(timeLayout),
+		})
+
+		return nil
+	})
+}
+
+// dlqPayload — payload не обязан быть JSON (битое сообщение); как и в колонке jsonb,
+// такие тела хранятся JSON-строкой
+func dlqPayload(payload []byte) []byte {
+	if json.Valid(payload) {
+		return slices.Clone(payload)
+	}
+
+	wrapped, _ := json.Marshal(string(payload))
+
+	return wrapped
+}
+
+func (r *EventsRepository) InsertReceipt(ctx context.Context, receipt dto.ProduceReceipt) error {
+	producedAt, err := time.Parse(time.RFC3339, receipt.Timestamp)
+	if err != nil {
+		return fmt.Errorf("kafka_produced: produced_at: %w", err)
+	}
+	if receipt.MessageID != "" {
+		if _, err := uuid.Parse(receipt.MessageID); err != nil {
+			return fmt.Errorf("kafka_produced: message_id: %w", err)
+		}
+	}
+
+	receipt.Timestamp = producedAt.Format(timeLayout)
+
+	return r.store.write(ctx, nil, func(d *tables, _ time.Time) error {
+		d.receipts = append(d.receipts, receipt)
+
+		return nil
+	})
+}
+
+func (r *EventsRepository) InsertDecision(ctx context.Context, decision dto.ConsumerDecision) error {
+	return r.store.write(ctx, nil, func(d *tables, now time.Time) error {
+		d.decisionSeq++
+		decision.ID = d.decisionSeq
+		decision.DecidedAt = now.Format(timeLayout)
+		d.decisions = append(d.decisions, decision)
+
+		return nil
+	})
+}
+
+func (r *EventsRepository) ListEvents(ctx context.Context) ([]dto.KafkaEvent, error) {
+	var out []dto.KafkaEvent
+	err := r.store.read(ctx, nil, func(d *tables) {
+		for i := len(d.events) - 1; i >= 0; i-- {
+			out = append(out, d.events[i])
+		}
+	})
+
+	return out, err
+}
+
+// ListEventsForOrdering возвращает события в порядке применения (по id) внутри топика;
+// пустые topic/employeeID — без фильтра.
+func (r *EventsRepository) ListEventsForOrdering(ctx context.Context, topic, employeeID string) ([]dto.KafkaEvent, error) {
+	var out []dto.KafkaEvent
+	err := r.store.read(ctx, nil, func(d *tables) {
+		for _, e := range d.events {
+			if topic != "" && e.Topic != topic {
+				continue
+			}
+			if employeeID != "" && payloadEmployeeID(e.Payload) != employeeID {
+				continue
+			}
+
+			out = append(out, e)
+		}
+	})
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Topic < out[j].Topic })
+
+	return out, err
+}
+
+// payloadEmployeeID — payload->>'employee_id'
+func payloadEmployeeID(payload []byte) string {
+	var p struct {
+		EmployeeID string `json:"employee_id"`
+	}
+	_ = json.Unmarshal(payload, &p)
+
+	return p.EmployeeID
+}
+
+// ListEventsForReplay возвращает журнал в порядке пересборки проекций: топики в порядке
+// topics, внутри — partition, offset.
+func (r *EventsRepository) ListEventsForReplay(ctx context.Context, topics []string) ([]dto.KafkaEvent, error) {
+	var out []dto.KafkaEvent
+	err := r.store.read(ctx, nil, func(d *tables) {
+		for _, e := range d.events {
+			if slices.Contains(topics, e.Topic) {
+				out = append(out, e)
+			}
+		}
+	})
+
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if ta, tb := slices.Index(topics, a.Topic), slices.Index(topics, b.Topic); ta != tb {
+			return ta < tb
+		}
+		if a.Partition != b.Partition {
+			return a.Partition < b.Partition
+		}
+
+		return a.Offset < b.Offset
+	})
+
+	return out, err
+}
+
+func (r *EventsRepository) ListDLQ(ctx context.Context, filter dto.DLQFilter) ([]dto.KafkaDLQ, error) {
+	var from, to time.Time
+	if filter.From != "" {
+		var err error
+		if from, err = time.Parse(time.RFC3339, filter.From); err != nil {
+			return nil, fmt.Errorf("filter.From: %w", err)
+		}
+	}
+	if filter.To != "" {
+		var err error
+		if to, err = time.Parse(time.RFC3339, filter.To); err != nil {
+			return nil, fmt.Errorf("filter.To: %w", err)
+		}
+	}
+
+	var out []dto.KafkaDLQ
+	err := r.store.read(ctx, nil, func(d *tables) {
+		for i := len(d.dlq) - 1; i >= 0; i-- {
+			dlq := d.dlq[i]
+
+			if filter.Topic != "" && dlq.Topic != filter.Topic {
+				continue
+			}
+			if filter.ErrorContains != "" && !strings.Contains(strings.ToLower(dlq.Error), strings.ToLower(filter.ErrorContains)) {
+				continue
+			}
+			receivedAt, _ := time.Parse(timeLayout, dlq.ReceivedAt)
+			if !from.IsZero() && receivedAt.Before(from) {
+				continue
+			}
+			if !to.IsZero() && receivedAt.After(to) {
+				continue
+			}
+			if filter.OnlyPending && dlq.ReplayStatus == dto.ReplayStatusReplayed {
+				continue
+			}
+
+			out = append(out, dlq)
+			if filter.Limit > 0 && len(out) == filter.Limit {
+				return
+			}
+		}
+	})
+
+	return out, err
+}
+
+func (r *EventsRepository) GetDLQ(ctx context.Context, id int64) (*dto.KafkaDLQ, error) {
+	var out *dto.KafkaDLQ
+	err := r.store.read(ctx, nil, func(d *tables) {
+		if i := d.dlqIndex(id); i >= 0 {
+			dlq := d.dlq[i]
+			out = &dlq
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out == nil {
+		return nil, dto.ErrNotFound
+	}
+
+	return out, nil
+}
+
+func (d *tables) dlqIndex(id int64) int {
+	return slices.IndexFunc(d.dlq, func(dlq dto.KafkaDLQ) bool { return dlq.ID == id })
+}
+
+func (r *EventsRepository) ListDLQByMessageID(ctx context.Context, messageID uuid.UUID) ([]dto.KafkaDLQ, error) {
+	var out []dto.KafkaDLQ
+	err := r.store.read(ctx, nil, func(d *tables) {
+		for _, dlq := range d.dlq {
+			if dlq.MessageID != nil && *dlq.MessageID == messageID {
+				out = append(out, dlq)
+			}
+		}
+	})
+
+	return out, err
+}
+
+// MarkDLQReplay фиксирует попытку replay: статус, счётчик попыток и ошибку.
+func (r *EventsRepository) MarkDLQReplay(ctx context.Context, id int64, status, replayErr string) error {
+	return r.store.write(ctx, nil, func(d *tables, now time.Time) error {
+		i := d.dlqIndex(id)
+		if i < 0 {
+			return dto.ErrNotFound
+		}
+
+		replayedAt := now.Format(timeLayout)
+		d.dlq[i].ReplayStatus = status
+		d.dlq[i].ReplayAttempts++
+		d.dlq[i].LastReplayedAt = &replayedAt
+		d.dlq[i].LastReplayError = replayErr
+
+		return nil
+	})
+}
+
+func (r *EventsRepository) GetEventByMessageID(ctx context.Context, messageID uuid.UUID) (*dto.KafkaEvent, error) {
+	var out *dto.KafkaEvent
+	err := r.store.read(ctx, nil, func(d *tables) {
+		if i := d.eventIndex(messageID); i >= 0 {
+			e := d.events[i]
+			out = &e
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out == nil {
+		return nil, dto.ErrNotFound
+	}
+
+	return out, nil
+}
+
+func (r *EventsRepository) ListReceiptsByMessageID(ctx context.Context, messageID uuid.UUID) ([]dto.ProduceReceipt, error) {
+	var out []dto.ProduceReceipt
+	err := r.store.read(ctx, nil, func(d *tables) {
+		for _, receipt := range d.receipts {
+			if id, err := uuid.Parse(receipt.MessageID); err == nil && id == messageID {
+				out = append(out, receipt)
+			}
+		}
+	})
+
+	return out, err
+}
+
+func (r *EventsRepository) ListDecisionsByMessageID(ctx context.Context, messageID uuid.UUID) ([]dto.ConsumerDecision, error) {
+	var out []dto.ConsumerDecision
+	err := r.store.read(ctx, nil, func(d *tables) {
+		for _, decision := range d.decisions {
+			if decision.MessageID != nil && *decision.MessageID == messageID {
+				out = append(out, decision)
+			}
+		}
+	})
+
+	return out, err
+}
+
+// ListDecisions возвращает решения консьюмеров одного вида (applied, retry, ...) по всем сообщениям
+func (r *EventsRepository) ListDecisions(ctx context.Context, decision string) ([]dto.ConsumerDecision, error) {
+	var out []dto.ConsumerDecision
+	err := r.store.read(ctx, nil, func(d *tables) {
+		for _, dd := range d.decisions {
+			if dd.Decision == decision {
+				out = append(out, dd)
+			}
+		}
+	})
+
+	return out, err
+}
+
+// TruncateProjectionsTx очищает бизнес-таблицы, построенные из журнала, и снимает
+// пометки stale. Журнал не трогается.
+func (r *EventsRepository) TruncateProjectionsTx(ctx context.Context, tx pgx.Tx) error {
+	return r.store.write(ctx, tx, func(d *tables, _ time.Time) error {
+		d.history, d.historySeq = nil, 0
+		d.profiles = make(map[string]profileRow)
+		d.assignments, d.assignmentSeq = nil, 0
+
+		events := make([]dto.KafkaEvent, len(d.events))
+		for i, e := range d.events {
+			e.Stale = false
+			events[i] = e
+		}
+		d.events = events
+
+		return nil
+	})
+}
+
+// ResetAll очищает таблицы всех сессий; список сессий сохраняется
+func (r *EventsRepository) ResetAll(_ context.Context) error {
+	r.store.resetAll()
+
+	return nil
+}
+
+// ResetSession удаляет данные сессии контекста; остальные сессии не затрагиваются
+func (r *EventsRepository) ResetSession(ctx context.Context) error {
+	r.store.resetSession(dto.SessionFrom(ctx))
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// HistoryRepository — employment_history в памяти
+type HistoryRepository struct {
+	store *Store
+}
+
+func NewHistoryRepository(store *Store) *HistoryRepository {
+	return &HistoryRepository{store: store}
+}
+
+func (r *HistoryRepository) Insert(ctx context.Context, history dto.EmploymentHistory) error {
+	return r.store.write(ctx, nil, insertHistory(history))
+}
+
+func (r *HistoryRepository) InsertTx(ctx context.Context, tx pgx.Tx, history dto.EmploymentHistory) error {
+	return r.store.write(ctx, tx, insertHistory(history))
+}
+
+func insertHistory(history dto.EmploymentHistory) mutation {
+	history.Stack = cloneStack(history.Stack)
+
+	return func(d *tables, _ time.Time) error {
+		d.historySeq++
+		history.ID = d.historySeq
+		d.history = append(d.history, history)
+
+		return nil
+	}
+}
+
+// cloneStack — stack хранится как text[] NOT NULL DEFAULT '{}'
+func cloneStack(stack []string) []string {
+	if stack == nil {
+		return []string{}
+	}
+
+	return slices.Clone(stack)
+}
+
+func (r *HistoryRepository) Update(ctx context.Context, history dto.EmploymentHistory) error {
+	stack := cloneStack(history.Stack)
+
+	return r.store.write(ctx, nil, func(d *tables, _ time.Time) error {
+		i := d.historyIndex(history.ID)
+		if i < 0 {
+			return dto.ErrNotFound
+		}
+
+		row := &d.history[i]
+		row.EmployeeID = history.EmployeeID
+		row.Company = history.Company
+		row.Position = history.Position
+		row.PeriodFrom = history.PeriodFrom
+		row.PeriodTo = history.PeriodTo
+		row.Stack = stack
+
+		return nil
+	})
+}
+
+func (d *tables) historyIndex(id int64) int {
+	return slices.IndexFunc(d.history, func(h dto.EmploymentHistory) bool { return h.ID == id })
+}
+
+func (r *HistoryRepository) Delete(ctx context.Context, id int64) error {
+	return r.store.write(ctx, nil, func(d *tables, _ time.Time) error {
+		i := d.historyIndex(id)
+		if i < 0 {
+			return dto.ErrNotFound
+		}
+
+		d.history = slices.Delete(d.history, i, i+1)
+
+		return nil
+	})
+}
+
+func (r *HistoryRepository) ListByEmployee(ctx context.Context, employeeID string) ([]dto.EmploymentHistory, error) {
+	var out []dto.EmploymentHistory
+	err := r.store.read(ctx, nil, func(d *tables) {
+		for i := len(d.history) - 1; i >= 0; i-- {
+			if d.history[i].EmployeeID == employeeID {
+				out = append(out, copyHistory(d.history[i]))
+			}
+		}
+	})
+
+	return out, err
+}
+
+func (r *HistoryRepository) ListByMessageID(ctx context.Context, messageID uuid.UUID) ([]dto.EmploymentHistory, error) {
+	var out []dto.EmploymentHistory
+	err := r.store.read(ctx, nil, func(d *tables) {
+		for _, h := range d.history {
+			if h.MessageID != nil && *h.MessageID == messageID {
+				out = append(out, copyHistory(h))
+			}
+		}
+	})
+
+	return out, err
+}
+
+// ListAllTx — вся история внутри транзакции (снимок до и после пересборки проекций)
+func (r *HistoryRepository) ListAllTx(ctx context.Context, tx pgx.Tx) ([]dto.EmploymentHistory, error) {
+	var out []dto.EmploymentHistory
+	err := r.store.read(ctx, tx, func(d *tables) {
+		for _, h := range d.history {
+			out = append(out, copyHistory(h))
+		}
+	})
+
+	return out, err
+}
+
+func (r *HistoryRepository) GetByID(ctx context.Context, id int64) (*dto.EmploymentHistory, error) {
+	var out *dto.EmploymentHistory
+	err := r.store.read(ctx, nil, func(d *tables) {
+		if i := d.historyIndex(id); i >= 0 {
+			h := copyHistory(d.history[i])
+			out = &h
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out == nil {
+		return nil, dto.ErrNotFound
+	}
+
+	return out, nil
+}
+
+// copyHistory — запись с собственной копией stack, чтобы вызывающий не менял таблицу
+func copyHistory(h dto.EmploymentHistory) dto.EmploymentHistory {
+	h.Stack = slices.Clone(h.Stack)
+
+	return h
+}
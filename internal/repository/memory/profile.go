@@ -0,0 +1,354 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/jackc/pgx/v5"
+)
+
+// ProfileRepository — employee_profile и position_assignment в памяти
+type ProfileRepository struct {
+	store *Store
+}
+
+func NewProfileRepository(store *Store) *ProfileRepository {
+	return &ProfileRepository{store: store}
+}
+
+func (r *ProfileRepository) Create(ctx context.Context, p dto.EmployeeProfile) error {
+	return r.store.write(ctx, nil, func(d *tables, now time.Time) error {
+		if _, ok := d.profiles[p.EmployeeID]; ok {
+			return dto.ErrAlreadyExists
+		}
+
+		d.profiles[p.EmployeeID] = profileRow{profile: p, updatedAt: now}
+
+		return nil
+	})
+}
+
+// Update заменяет обязательные поля, а опциональные (должность) — только если присланы.
+// Если у сотрудника есть назначения из hr.positions, должность определяет история
+// назначений, и её правка через CRUD отклоняется с dto.ErrInvalidState.
+func (r *ProfileRepository) Update(ctx context.Context, p dto.EmployeeProfile) error {
+	return r.store.write(ctx, nil, func(d *tables, now time.Time) error {
+		row, ok := d.profiles[p.EmployeeID]
+		if !ok {
+			return dto.ErrNotFound
+		}
+
+		hasAssignments := slices.ContainsFunc(d.assignments, func(a assignmentRow) bool { return a.assignment.EmployeeID == p.EmployeeID })
+		if hasAssignments && (p.Title != nil || p.Department != nil || p.Grade != nil || p.EffectiveFrom != nil) {
+			return fmt.Errorf("position of %s is set by hr.positions: %w", p.EmployeeID, dto.ErrInvalidState)
+		}
+
+		row.profile.FirstName = p.FirstName
+		row.profile.LastName = p.LastName
+		row.profile.BirthDate = p.BirthDate
+		row.profile.Email = p.Email
+		row.profile.Phone = p.Phone
+
+		if p.Title != nil {
+			row.profile.Title = p.Title
+		}
+		if p.Department != nil {
+			row.profile.Department = p.Department
+		}
+		if p.Grade != nil {
+			row.profile.Grade = p.Grade
+		}
+		if p.EffectiveFrom != nil {
+			row.profile.EffectiveFrom = p.EffectiveFrom
+		}
+
+		row.updatedAt = now
+		d.profiles[p.EmployeeID] = row
+
+		return nil
+	})
+}
+
+// Delete удаляет профиль вместе с историей должностей
+func (r *ProfileRepository) Delete(ctx context.Context, employeeID string) error {
+	return r.store.write(ctx, nil, func(d *tables, _ time.Time) error {
+		d.assignments = slices.DeleteFunc(d.assignments, func(a assignmentRow) bool { return a.assignment.EmployeeID == employeeID })
+
+		if _, ok := d.profiles[employeeID]; !ok {
+			return dto.ErrNotFound
+		}
+		delete(d.profiles, employeeID)
+
+		return nil
+	})
+}
+
+func (r *ProfileRepository) GetProfile(ctx context.Context, employeeID string) (*dto.EmployeeProfile, error) {
+	return r.GetProfileAsOf(ctx, employeeID, "")
+}
+
+// GetProfileAsOf возвращает профиль с должностью, действовавшей на дату asOf (YYYY-MM-DD)
+func (r *ProfileRepository) GetProfileAsOf(ctx context.Context, employeeID, asOf string) (*dto.EmployeeProfile, error) {
+	if asOf == "" {
+		asOf = r.store.today()
+	}
+
+	var out *dto.EmployeeProfile
+	err := r.store.read(ctx, nil, func(d *tables) {
+		if row, ok := d.profiles[employeeID]; ok {
+			p := d.profileAsOf(row.profile, asOf)
+			out = &p
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out == nil {
+		return nil, dto.ErrNotFound
+	}
+
+	return out, nil
+}
+
+// profileAsOf — если у сотрудника есть назначения, должность берётся из последнего
+// вступившего в силу к asOf и не отменённого (superseded), иначе — из профиля (создан через CRUD)
+func (d *tables) profileAsOf(p dto.EmployeeProfile, asOf string) dto.EmployeeProfile {
+	var (
+		has    bool
+		latest *dto.PositionAssignment
+	)
+	for i, row := range d.assignments {
+		if row.assignment.EmployeeID != p.EmployeeID {
+			continue
+		}
+
+		has = true
+		a := &d.assignments[i].assignment
+		if !row.superseded && a.EffectiveFrom <= asOf && (latest == nil || a.EffectiveFrom > latest.EffectiveFrom) {
+			latest = a
+		}
+	}
+
+	if !has {
+		return p
+	}
+
+	p.Title, p.Department, p.Grade, p.EffectiveFrom = nil, nil, nil, nil
+	if latest != nil {
+		effectiveFrom := latest.EffectiveFrom
+		p.Title, p.Department, p.Grade, p.EffectiveFrom = latest.Title, latest.Department, latest.Grade, &effectiveFrom
+	}
+
+	return p
+}
+
+func (r *ProfileRepository) ListProfiles(ctx context.Context) ([]dto.EmployeeProfile, error) {
+	return r.listProfiles(ctx, nil)
+}
+
+// ListProfilesTx — список профилей внутри транзакции (снимок до и после пересборки проекций)
+func (r *ProfileRepository) ListProfilesTx(ctx context.Context, tx pgx.Tx) ([]dto.EmployeeProfile, error) {
+	return r.listProfiles(ctx, tx)
+}
+
+func (r *ProfileRepository) listProfiles(ctx context.Context, tx pgx.Tx) ([]dto.EmployeeProfile, error) {
+	today := r.store.today()
+
+	var rows []profileRow
+	err := r.store.read(ctx, tx, func(d *tables) {
+		for _, row := range d.profiles {
+			row.profile = d.profileAsOf(row.profile, today)
+			rows = append(rows, row)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].updatedAt.Equal(rows[j].updatedAt) {
+			return rows[i].updatedAt.After(rows[j].updatedAt)
+		}
+
+		return rows[i].profile.EmployeeID < rows[j].profile.EmployeeID
+	})
+
+	var out []dto.EmployeeProfile
+	for _, row := range rows {
+		out = append(out, row.profile)
+	}
+
+	return out, nil
+}
+
+func (r *ProfileRepository) UpsertPersonal(ctx context.Context, p dto.EmployeeProfile) error {
+	return r.upsertPersonal(ctx, nil, p)
+}
+
+func (r *ProfileRepository) UpsertPersonalTx(ctx context.Context, tx pgx.Tx, p dto.EmployeeProfile) error {
+	return r.upsertPersonal(ctx, tx, p)
+}
+
+func (r *ProfileRepository) upsertPersonal(ctx context.Context, tx pgx.Tx, p dto.EmployeeProfile) error {
+	return r.store.write(ctx, tx, func(d *tables, now time.Time) error {
+		row := d.profiles[p.EmployeeID]
+		row.profile.EmployeeID = p.EmployeeID
+		row.profile.FirstName = p.FirstName
+		row.profile.LastName = p.LastName
+		row.profile.BirthDate = p.BirthDate
+		row.profile.Email = p.Email
+		row.profile.Phone = p.Phone
+		row.updatedAt = now
+		d.profiles[p.EmployeeID] = row
+
+		return nil
+	})
+}
+
+// LockEffectiveFromTx возвращает текущий effective_from (YYYY-MM-DD, пусто — должность
+// ещё не назначена): последний среди неотменённых назначений, без назначений — из профиля.
+// Транзакции Store выполняются по одной, отдельная блокировка не нужна.
+func (r *ProfileRepository) LockEffectiveFromTx(ctx context.Context, tx pgx.Tx, employeeID string) (string, error) {
+	var effectiveFrom string
+	err := r.store.read(ctx, tx, func(d *tables) {
+		has := false
+		for _, row := range d.assignments {
+			if row.assignment.EmployeeID != employeeID || row.superseded {
+				continue
+			}
+
+			has = true
+			effectiveFrom = max(effectiveFrom, row.assignment.EffectiveFrom)
+		}
+
+		if row, ok := d.profiles[employeeID]; ok && !has && row.profile.EffectiveFrom != nil {
+			effectiveFrom = *row.profile.EffectiveFrom
+		}
+	})
+
+	return effectiveFrom, err
+}
+
+func (r *ProfileRepository) UpsertPosition(ctx context.Context, p dto.EmployeeProfile) error {
+	return r.upsertPosition(ctx, nil, p)
+}
+
+func (r *ProfileRepository) UpsertPositionTx(ctx context.Context, tx pgx.Tx, p dto.EmployeeProfile) error {
+	return r.upsertPosition(ctx, tx, p)
+}
+
+func (r *ProfileRepository) upsertPosition(ctx context.Context, tx pgx.Tx, p dto.EmployeeProfile) error {
+	effectiveFrom := p.EffectiveFrom
+	if effectiveFrom != nil && *effectiveFrom == "" {
+		effectiveFrom = nil
+	}
+
+	return r.store.write(ctx, tx, func(d *tables, now time.Time) error {
+		row := d.profiles[p.EmployeeID]
+		row.profile.EmployeeID = p.EmployeeID
+		row.profile.Title = p.Title
+		row.profile.Department = p.Department
+		row.profile.Grade = p.Grade
+		row.profile.EffectiveFrom = effectiveFrom
+		row.updatedAt = now
+		d.profiles[p.EmployeeID] = row
+
+		return nil
+	})
+}
+
+// InsertAssignmentTx добавляет назначение в историю должностей; повтор на ту же
+// дату effective_from заменяет назначение (последнее по приходу побеждает).
+func (r *ProfileRepository) InsertAssignmentTx(ctx context.Context, tx pgx.Tx, a dto.PositionAssignment) error {
+	return r.store.write(ctx, tx, func(d *tables, now time.Time) error {
+		row := assignmentRow{assignment: dto.PositionAssignment{
+			EmployeeID:    a.EmployeeID,
+			Title:         a.Title,
+			Department:    a.Department,
+			Grade:         a.Grade,
+			EffectiveFrom: a.EffectiveFrom,
+			MessageID:     a.MessageID,
+			CreatedAt:     now.Format(timeLayout),
+		}}
+
+		i := slices.IndexFunc(d.assignments, func(x assignmentRow) bool {
+			return x.assignment.EmployeeID == a.EmployeeID && x.assignment.EffectiveFrom == a.EffectiveFrom
+		})
+		if i >= 0 {
+			row.assignment.ID = d.assignments[i].assignment.ID
+			d.assignments[i] = row
+
+			return nil
+		}
+
+		d.assignmentSeq++
+		row.assignment.ID = d.assignmentSeq
+		d.assignments = append(d.assignments, row)
+
+		return nil
+	})
+}
+
+// SupersedeAssignmentsTx отменяет назначения сотрудника с effective_from позже
+// заданного: их вытесняет пришедшее позже событие (политика arrival).
+func (r *ProfileRepository) SupersedeAssignmentsTx(ctx context.Context, tx pgx.Tx, employeeID, effectiveFrom string) error {
+	return r.store.write(ctx, tx, func(d *tables, _ time.Time) error {
+		for i, row := range d.assignments {
+			if row.assignment.EmployeeID == employeeID && row.assignment.EffectiveFrom > effectiveFrom {
+				d.assignments[i].superseded = true
+			}
+		}
+
+		return nil
+	})
+}
+
+// ListPositions возвращает историю должностей сотрудника по effective_from
+func (r *ProfileRepository) ListPositions(ctx context.Context, employeeID string) ([]dto.PositionAssignment, error) {
+	today := r.store.today()
+
+	var rows []assignmentRow
+	err := r.store.read(ctx, nil, func(d *tables) {
+		for _, row := range d.assignments {
+			if row.assignment.EmployeeID == employeeID {
+				rows = append(rows, row)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(rows, func(a, b assignmentRow) int {
+		return cmp.Or(cmp.Compare(a.assignment.EffectiveFrom, b.assignment.EffectiveFrom), cmp.Compare(a.assignment.ID, b.assignment.ID))
+	})
+
+	var current string
+	for _, row := range rows {
+		if !row.superseded && row.assignment.EffectiveFrom <= today {
+			current = row.assignment.EffectiveFrom
+		}
+	}
+
+	out := make([]dto.PositionAssignment, 0, len(rows))
+	for i, row := range rows {
+		out = append(out, row.assignment)
+		switch {
+		case row.superseded:
+			out[i].Status = dto.AssignmentSuperseded
+		case out[i].EffectiveFrom > today:
+			out[i].Status = dto.AssignmentFuture
+		case out[i].EffectiveFrom == current:
+			out[i].Status = dto.AssignmentCurrent
+		default:
+			out[i].Status = dto.AssignmentPast
+		}
+	}
+
+	return out, nil
+}
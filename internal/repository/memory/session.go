@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+)
+
+// SessionRepository — trainee_session в памяти
+type SessionRepository struct {
+	store *Store
+}
+
+func NewSessionRepository(store *Store) *SessionRepository {
+	return &SessionRepository{store: store}
+}
+
+func (r *SessionRepository) Create(_ context.Context, s dto.Session) (dto.Session, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.sessionIndex(s.ID) >= 0 {
+		return dto.Session{}, dto.ErrAlreadyExists
+	}
+
+	s.CreatedAt = r.store.now().Format(timeLayout)
+	r.store.sessions = append(r.store.sessions, s)
+
+	return s, nil
+}
+
+func (r *SessionRepository) sessionIndex(id string) int {
+	return slices.IndexFunc(r.store.sessions, func(s dto.Session) bool { return s.ID == id })
+}
+
+func (r *SessionRepository) Get(_ context.Context, id string) (*dto.Session, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	i := r.sessionIndex(id)
+	if i < 0 {
+		return nil, dto.ErrNotFound
+	}
+
+	s := r.store.sessions[i]
+
+	return &s, nil
+}
+
+func (r *SessionRepository) List(_ context.Context) ([]dto.Session, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return append(make([]dto.Session, 0, len(r.store.sessions)), r.store.sessions...), nil
+}
+
+// Delete удаляет сессию из списка; её данные удаляет EventsRepository.ResetSession
+func (r *SessionRepository) Delete(_ context.Context, id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	i := r.sessionIndex(id)
+	if i < 0 {
+		return dto.ErrNotFound
+	}
+
+	r.store.sessions = slices.Delete(r.store.sessions, i, i+1)
+
+	return nil
+}
@@ -0,0 +1,199 @@
+// Package memory — репозитории events, profile, history и session в памяти процесса с той же
+// семантикой, что и Postgres-реализации: dto.ErrNotFound / dto.ErrAlreadyExists, порядок
+// выборок, частичное обновление профиля, транзакции (*Tx-методы) и savepoint-ы.
+// Нужны, чтобы гонять обработчики API и консьюмеры без базы данных.
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/jackc/pgx/v5"
+)
+
+// timeLayout повторяет to_char(..., 'YYYY-MM-DD"T"HH24:MI:SSOF') Postgres-репозиториев
+const timeLayout = "2006-01-02T15:04:05-07"
+
+// dateLayout — формат колонок date (to_char(..., 'YYYY-MM-DD'))
+const dateLayout = "2006-01-02"
+
+// Store — общие таблицы репозиториев, свои у каждой сессии (dto.SessionFrom).
+// Транзакции выполняются по одной (как при блокировках строк в Postgres конкурентная
+// транзакция ждёт первую), запросы вне транзакции видят только закоммиченные данные.
+type Store struct {
+	mu       sync.Mutex
+	data     map[string]*tables // по сессиям; "" — общая сессия
+	sessions []dto.Session      // trainee_session
+	txs      chan struct{}      // семафор: одна транзакция верхнего уровня за раз
+	now      func() time.Time
+}
+
+func NewStore() *Store {
+	return &Store{
+		data: make(map[string]*tables),
+		txs:  make(chan struct{}, 1),
+		now:  time.Now,
+	}
+}
+
+// tables — таблицы сессии; создаются при первом обращении. Вызывается под s.mu.
+func (s *Store) tables(session string) *tables {
+	d, ok := s.data[session]
+	if !ok {
+		d = newTables()
+		s.data[session] = d
+	}
+
+	return d
+}
+
+type tables struct {
+	events      []dto.KafkaEvent
+	dlq         []dto.KafkaDLQ
+	receipts    []dto.ProduceReceipt
+	decisions   []dto.ConsumerDecision
+	profiles    map[string]profileRow
+	assignments []assignmentRow
+	history     []dto.EmploymentHistory
+
+	// последовательности BIGSERIAL
+	eventSeq      int64
+	dlqSeq        int64
+	decisionSeq   int64
+	assignmentSeq int64
+	historySeq    int64
+}
+
+type profileRow struct {
+	profile   dto.EmployeeProfile
+	updatedAt time.Time
+}
+
+// assignmentRow — строка position_assignment; superseded — назначение отменено
+// событием с более ранним effective_from, пришедшим позже (политика arrival)
+type assignmentRow struct {
+	assignment dto.PositionAssignment
+	superseded bool
+}
+
+func newTables() *tables {
+	return &tables{profiles: make(map[string]profileRow)}
+}
+
+// clone — копия таблиц для транзакции. Строки копируются по значению; срезы и указатели
+// внутри строк не изменяются на месте (только заменяются), поэтому их можно разделять.
+func (d *tables) clone() *tables {
+	out := *d
+	out.events = append([]dto.KafkaEvent(nil), d.events...)
+	out.dlq = append([]dto.KafkaDLQ(nil), d.dlq...)
+	out.receipts = append([]dto.ProduceReceipt(nil), d.receipts...)
+	out.decisions = append([]dto.ConsumerDecision(nil), d.decisions...)
+	out.assignments = append([]assignmentRow(nil), d.assignments...)
+	out.history = append([]dto.EmploymentHistory(nil), d.history...)
+	out.profiles = make(map[string]profileRow, len(d.profiles))
+	for k, v := range d.profiles {
+		out.profiles[k] = v
+	}
+
+	return &out
+}
+
+// mutation — изменение таблиц; в транзакции применяется к её копии сразу
+// и повторяется на закоммиченных данных при Commit. Ошибка означает, что
+// изменение не применено (например, dto.ErrNotFound).
+type mutation func(d *tables, now time.Time) error
+
+// read выполняет чтение закоммиченных данных сессии ctx (tx == nil) или данных транзакции
+func (s *Store) read(ctx context.Context, tx pgx.Tx, fn func(d *tables)) error {
+	if tx != nil {
+		t, err := s.own(tx)
+		if err != nil {
+			return err
+		}
+
+		fn(t.data)
+
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn(s.tables(dto.SessionFrom(ctx)))
+
+	return nil
+}
+
+// write применяет изменение: вне транзакции — сразу к данным сессии ctx, в транзакции —
+// к её копии с записью в журнал
+func (s *Store) write(ctx context.Context, tx pgx.Tx, m mutation) error {
+	if tx != nil {
+		t, err := s.own(tx)
+		if err != nil {
+			return err
+		}
+
+		if err := m(t.data, s.now()); err != nil {
+			return err
+		}
+		t.log = append(t.log, m)
+
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return m(s.tables(dto.SessionFrom(ctx)), s.now())
+}
+
+// Begin открывает транзакцию над данными сессии ctx; ждёт завершения текущей или отмены ctx
+func (s *Store) Begin(ctx context.Context) (pgx.Tx, error) {
+	select {
+	case s.txs <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session := dto.SessionFrom(ctx)
+
+	return &Tx{store: s, session: session, data: s.tables(session).clone()}, nil
+}
+
+func (s *Store) commit(session string, log []mutation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// между началом транзакции и Commit могли пройти запросы вне транзакции;
+	// изменение, ставшее неприменимым, пропускается, как и на копии
+	d := s.tables(session)
+	for _, m := range log {
+		_ = m(d, s.now())
+	}
+}
+
+// resetAll очищает таблицы всех сессий; список сессий сохраняется
+func (s *Store) resetAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data = make(map[string]*tables)
+}
+
+// resetSession удаляет таблицы сессии
+func (s *Store) resetSession(session string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.data, session)
+}
+
+// today — текущая дата в формате колонок date
+func (s *Store) today() string {
+	return s.now().Format(dateLayout)
+}
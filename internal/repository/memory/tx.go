@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// errSQL — Tx реализует pgx.Tx ради *Tx-методов репозиториев; произвольный SQL не выполняется
+var errSQL = errors.New("memory: SQL is not supported")
+
+// Tx — транзакция Store. Работает на копии таблиц; Commit повторяет её изменения
+// на закоммиченных данных, Rollback отбрасывает копию. Begin внутри транзакции
+// открывает savepoint: его Commit переносит изменения в родительскую транзакцию.
+type Tx struct {
+	store   *Store
+	session string
+	parent  *Tx
+	data    *tables
+	log     []mutation
+	closed  bool
+}
+
+// own проверяет, что tx открыта этим Store
+func (s *Store) own(tx pgx.Tx) (*Tx, error) {
+	t, ok := tx.(*Tx)
+	if !ok || t.store != s {
+		return nil, fmt.Errorf("memory: foreign transaction %T", tx)
+	}
+	if t.closed {
+		return nil, pgx.ErrTxClosed
+	}
+
+	return t, nil
+}
+
+func (t *Tx) Begin(_ context.Context) (pgx.Tx, error) {
+	if t.closed {
+		return nil, pgx.ErrTxClosed
+	}
+
+	return &Tx{store: t.store, session: t.session, parent: t, data: t.data.clone()}, nil
+}
+
+func (t *Tx) Commit(_ context.Context) error {
+	if t.closed {
+		return pgx.ErrTxClosed
+	}
+	t.closed = true
+
+	if t.parent != nil {
+		t.parent.data = t.data
+		t.parent.log = append(t.parent.log, t.log...)
+
+		return nil
+	}
+
+	t.store.commit(t.session, t.log)
+	<-t.store.txs
+
+	return nil
+}
+
+func (t *Tx) Rollback(_ context.Context) error {
+	if t.closed {
+		return pgx.ErrTxClosed
+	}
+	t.closed = true
+
+	if t.parent == nil {
+		<-t.store.txs
+	}
+
+	return nil
+}
+
+func (t *Tx) CopyFrom(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) (int64, error) {
+	return 0, errSQL
+}
+
+func (t *Tx) SendBatch(context.Context, *pgx.Batch) pgx.BatchResults {
+	return errBatch{}
+}
+
+func (t *Tx) LargeObjects() pgx.LargeObjects {
+	return pgx.LargeObjects{}
+}
+
+func (t *Tx) Prepare(context.Context, string, string) (*pgconn.StatementDescription, error) {
+	return nil, errSQL
+}
+
+func (t *Tx) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errSQL
+}
+
+func (t *Tx) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errSQL
+}
+
+func (t *Tx) QueryRow(context.Context, string, ...any) pgx.Row {
+	return errRow{}
+}
+
+func (t *Tx) Conn() *pgx.Conn {
+	return nil
+}
+
+type errRow struct{}
+
+func (errRow) Scan(...any) error { return errSQL }
+
+type errBatch struct{}
+
+func (errBatch) Exec() (pgconn.CommandTag, error) { return pgconn.CommandTag{}, errSQL }
+func (errBatch) Query() (pgx.Rows, error)         { return nil, errSQL }
+func (errBatch) QueryRow() pgx.Row                { return errRow{} }
+func (errBatch) Close() error                     { return nil }
+
+var _ pgx.Tx = (*Tx)(nil)
@@ -30,11 +30,12 @@ func NewRepository(pool PgxPoolIface) *Repository {
 func (r *Repository) Create(ctx context.Context, p dto.EmployeeProfile) error {
 	query := `
 insert into employee_profile
-  (employee_id, first_name, last_name, birth_date, email, phone, title, department, grade, effective_from)
+  (session_id, employee_id, first_name, last_name, birth_date, email, phone, title, department, grade, effective_from)
 values
-  (@employee_id, @first_name, @last_name, @birth_date::date, @email, @phone, @title, @department, @grade, @effective_from::date);
+  (@session_id, @employee_id, @first_name, @last_name, @birth_date::date, @email, @phone, @title, @department, @grade, @effective_from::date);
 `
 	args := pgx.NamedArgs{
+		"session_id":     dto.SessionFrom(ctx),
 		"employee_id":    p.EmployeeID,
 		"first_name":     p.FirstName,
 		"last_name":      p.LastName,
@@ -60,9 +61,43 @@ values
 	return nil
 }
 
+// Update заменяет обязательные поля, а опциональные (должность) — только если присланы.
+// Если у сотрудника есть назначения из hr.positions, должность определяет история
+// назначений, и её правка через CRUD отклоняется с dto.ErrInvalidState.
 func (r *Repository) Update(ctx context.Context, p dto.EmployeeProfile) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("pool.Begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(context.WithoutCancel(ctx)) }()
+
+	// блокировка строки профиля, как в LockEffectiveFromTx: консьюмер не добавит
+	// назначение между проверкой и обновлением
+	lockQuery := `
+select exists (
+    select 1 from position_assignment a
+    where a.session_id = p.session_id and a.employee_id = p.employee_id
+)
+from employee_profile p
+where p.session_id = $1 and p.employee_id = $2
+for update of p;
+`
+	var hasAssignments bool
+	if err := tx.QueryRow(ctx, lockQuery, dto.SessionFrom(ctx), p.EmployeeID).Scan(&hasAssignments); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return dto.ErrNotFound
+		}
+
+		return fmt.Errorf("row.Scan: %w", err)
+	}
+
+	if hasAssignments && (p.Title != nil || p.Department != nil || p.Grade != nil || p.EffectiveFrom != nil) {
+		return fmt.Errorf("position of %s is set by hr.positions: %w", p.EmployeeID, dto.ErrInvalidState)
+	}
+
 	set := make([]string, 0, 10)
 	args := pgx.NamedArgs{
+		"session_id":  dto.SessionFrom(ctx),
 		"employee_id": p.EmployeeID,
 	}
 
@@ -105,25 +140,29 @@ func (r *Repository) Update(ctx context.Context, p dto.EmployeeProfile) error {
 	query := fmt.Sprintf(`
 UPDATE employee_profile
 SET %s
-WHERE employee_id = @employee_id;
+WHERE session_id = @session_id AND employee_id = @employee_id;
 `, strings.Join(set, ", "))
 
-	tag, err := r.pool.Exec(ctx, query, args)
-	if err != nil {
-		return fmt.Errorf("pool.Exec: %w", err)
+	if _, err := tx.Exec(ctx, query, args); err != nil {
+		return fmt.Errorf("tx.Exec: %w", err)
 	}
 
-	if tag.RowsAffected() == 0 {
-		return dto.ErrNotFound
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("tx.Commit: %w", err)
 	}
 
 	return nil
 }
 
 func (r *Repository) Delete(ctx context.Context, employeeID string) error {
-	query := `delete from employee_profile where employee_id = $1`
+	query := `
+with assignments as (
+    delete from position_assignment where session_id = $1 and employee_id = $2
+)
+delete from employee_profile where session_id = $1 and employee_id = $2;
+`
 
-	tag, err := r.pool.Exec(ctx, query, employeeID)
+	tag, err := r.pool.Exec(ctx, query, dto.SessionFrom(ctx), employeeID)
 	if err != nil {
 		return fmt.Errorf("pool.Exec: %w", err)
 	}
@@ -134,22 +173,52 @@ func (r *Repository) Delete(ctx context.Context, employeeID string) error {
 	return nil
 }
 
+// profileSelect — профиль с должностью на дату @as_of (пусто — сегодня). Если у сотрудника
+// есть назначения в position_assignment, должность берётся из последнего вступившего
+// в силу к этой дате и не отменённого (superseded), иначе — из колонок employee_profile
+// (профиль создан через CRUD).
+const profileSelect = `
+select p.employee_id,
+       p.first_name,
+       p.last_name,
+       to_char(p.birth_date,'YYYY-MM-DD'),
+       p.email,
+       p.phone,
+       case when h.has then a.title else p.title end,
+       case when h.has then a.department else p.department end,
+       case when h.has then a.grade else p.grade end,
+       to_char(case when h.has then a.effective_from else p.effective_from end,'YYYY-MM-DD')
+from employee_profile p
+left join lateral (
+    select title, department, grade, effective_from
+    from position_assignment
+    where session_id = p.session_id
+      and employee_id = p.employee_id
+      and not superseded
+      and effective_from <= coalesce(nullif(@as_of,'')::date, current_date)
+    order by effective_from desc
+    limit 1
+) a on true
+left join lateral (
+    select true as has
+    from position_assignment
+    where session_id = p.session_id
+      and employee_id = p.employee_id
+    limit 1
+) h on true
+`
+
 func (r *Repository) GetProfile(ctx context.Context, employeeID string) (*dto.EmployeeProfile, error) {
-	query := `
-select employee_id,
-	   first_name,
-	   last_name,
-	   to_char(birth_date,'YYYY-MM-DD'),
-	   email,
-	   phone,
-	   title,
-	   department,
-	   grade,
-	   to_char(effective_from,'YYYY-MM-DD')
-from employee_profile
-where employee_id = $1;
+	return r.GetProfileAsOf(ctx, employeeID, "")
+}
+
+// GetProfileAsOf возвращает профиль с должностью, действовавшей на дату asOf (YYYY-MM-DD)
+func (r *Repository) GetProfileAsOf(ctx context.Context, employeeID, asOf string) (*dto.EmployeeProfile, error) {
+	query := profileSelect + `
+where p.session_id = @session_id
+  and p.employee_id = @employee_id;
 `
-	row := r.pool.QueryRow(ctx, query, employeeID)
+	row := r.pool.QueryRow(ctx, query, pgx.NamedArgs{"session_id": dto.SessionFrom(ctx), "employee_id": employeeID, "as_of": asOf})
 
 	var (
 		out           dto.EmployeeProfile
@@ -178,25 +247,29 @@ where employee_id = $1;
 		return nil, fmt.Errorf("row.Scan: %w", err)
 	}
 
+	out.Title = title
+	out.Department = department
+	out.Grade = grade
+	out.EffectiveFrom = effectiveFrom
+
 	return &out, nil
 }
 
 func (r *Repository) ListProfiles(ctx context.Context) ([]dto.EmployeeProfile, error) {
-	query := `
-select employee_id,
-       first_name,
-       last_name,
-       to_char(birth_date,'YYYY-MM-DD'),
-       email,
-       phone,
-       title,
-       department,
-       grade,
-       to_char(effective_from,'YYYY-MM-DD')
-from employee_profile
-order by updated_at desc, employee_id
+	return listProfiles(ctx, r.pool)
+}
+
+// ListProfilesTx — список профилей внутри транзакции (снимок до и после пересборки проекций)
+func (r *Repository) ListProfilesTx(ctx context.Context, tx pgx.Tx) ([]dto.EmployeeProfile, error) {
+	return listProfiles(ctx, tx)
+}
+
+func listProfiles(ctx context.Context, db PgxPoolIface) ([]dto.EmployeeProfile, error) {
+	query := profileSelect + `
+where p.session_id = @session_id
+order by p.updated_at desc, p.employee_id
 `
-	rows, err := r.pool.Query(ctx, query)
+	rows, err := db.Query(ctx, query, pgx.NamedArgs{"session_id": dto.SessionFrom(ctx), "as_of": ""})
 	if err != nil {
 		return nil, fmt.Errorf("pool.Query: %w", err)
 	}
@@ -237,10 +310,18 @@ order by updated_at desc, employee_id
 }
 
 func (r *Repository) UpsertPersonal(ctx context.Context, p dto.EmployeeProfile) error {
+	return upsertPersonal(ctx, r.pool, p)
+}
+
+func (r *Repository) UpsertPersonalTx(ctx context.Context, tx pgx.Tx, p dto.EmployeeProfile) error {
+	return upsertPersonal(ctx, tx, p)
+}
+
+func upsertPersonal(ctx context.Context, db PgxPoolIface, p dto.EmployeeProfile) error {
 	query := `
-insert into employee_profile (employee_id, first_name, last_name, birth_date, email, phone, updated_at)
-values (@employee_id, @first_name, @last_name, nullif(@birth_date,'')::date, @email, @phone, now())
-on conflict (employee_id) do update set
+insert into employee_profile (session_id, employee_id, first_name, last_name, birth_date, email, phone, updated_at)
+values (@session_id, @employee_id, @first_name, @last_name, nullif(@birth_date,'')::date, @email, @phone, now())
+on conflict (session_id, employee_id) do update set
   first_name = excluded.first_name,
   last_name  = excluded.last_name,
   birth_date = excluded.birth_date,
@@ -249,6 +330,7 @@ on conflict (employee_id) do update set
   updated_at = now();
 `
 	args := pgx.NamedArgs{
+		"session_id":  dto.SessionFrom(ctx),
 		"employee_id": p.EmployeeID,
 		"first_name":  p.FirstName,
 		"last_name":   p.LastName,
@@ -257,18 +339,55 @@ on conflict (employee_id) do update set
 		"phone":       p.Phone,
 	}
 
-	if _, err := r.pool.Exec(ctx, query, args); err != nil {
+	if _, err := db.Exec(ctx, query, args); err != nil {
 		return fmt.Errorf("pool.Exec: %w", err)
 	}
 
 	return nil
 }
 
+// LockEffectiveFromTx блокирует строку профиля до конца транзакции и возвращает
+// текущий effective_from (YYYY-MM-DD, пусто — должность ещё не назначена): последний
+// среди неотменённых назначений, без назначений — из профиля. Блокировка упорядочивает
+// конкурентные события должности одного сотрудника.
+func (r *Repository) LockEffectiveFromTx(ctx context.Context, tx pgx.Tx, employeeID string) (string, error) {
+	query := `
+select coalesce(to_char(coalesce((
+           select max(a.effective_from)
+           from position_assignment a
+           where a.session_id = p.session_id
+             and a.employee_id = p.employee_id
+             and not a.superseded
+       ), p.effective_from), 'YYYY-MM-DD'), '')
+from employee_profile p
+where p.session_id = $1 and p.employee_id = $2
+for update of p;
+`
+	var effectiveFrom string
+	if err := tx.QueryRow(ctx, query, dto.SessionFrom(ctx), employeeID).Scan(&effectiveFrom); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("row.Scan: %w", err)
+	}
+
+	return effectiveFrom, nil
+}
+
 func (r *Repository) UpsertPosition(ctx context.Context, p dto.EmployeeProfile) error {
+	return upsertPosition(ctx, r.pool, p)
+}
+
+func (r *Repository) UpsertPositionTx(ctx context.Context, tx pgx.Tx, p dto.EmployeeProfile) error {
+	return upsertPosition(ctx, tx, p)
+}
+
+func upsertPosition(ctx context.Context, db PgxPoolIface, p dto.EmployeeProfile) error {
 	query := `
-insert into employee_profile (employee_id, title, department, grade, effective_from, updated_at)
-values (@employee_id, @title, @department, @grade, nullif(@effective_from,'')::date, now())
-on conflict (employee_id) do update set
+insert into employee_profile (session_id, employee_id, title, department, grade, effective_from, updated_at)
+values (@session_id, @employee_id, @title, @department, @grade, nullif(@effective_from,'')::date, now())
+on conflict (session_id, employee_id) do update set
   title          = excluded.title,
   department     = excluded.department,
   grade          = excluded.grade,
@@ -276,6 +395,7 @@ on conflict (employee_id) do update set
   updated_at     = now();
 `
 	args := pgx.NamedArgs{
+		"session_id":     dto.SessionFrom(ctx),
 		"employee_id":    p.EmployeeID,
 		"title":          p.Title,
 		"department":     p.Department,
@@ -283,9 +403,112 @@ on conflict (employee_id) do update set
 		"effective_from": p.EffectiveFrom,
 	}
 
-	if _, err := r.pool.Exec(ctx, query, args); err != nil {
+	if _, err := db.Exec(ctx, query, args); err != nil {
 		return fmt.Errorf("pool.Exec: %w", err)
 	}
 
 	return nil
 }
+
+// InsertAssignmentTx добавляет назначение в историю должностей; повтор на ту же
+// дату effective_from заменяет назначение (последнее по приходу побеждает).
+func (r *Repository) InsertAssignmentTx(ctx context.Context, tx pgx.Tx, a dto.PositionAssignment) error {
+	query := `
+insert into position_assignment (session_id, employee_id, title, department, grade, effective_from, message_id, created_at)
+values (@session_id, @employee_id, @title, @department, @grade, @effective_from::date, @message_id::uuid, now())
+on conflict (session_id, employee_id, effective_from) do update set
+  title      = excluded.title,
+  department = excluded.department,
+  grade      = excluded.grade,
+  message_id = excluded.message_id,
+  superseded = false,
+  created_at = now();
+`
+	args := pgx.NamedArgs{
+		"session_id":     dto.SessionFrom(ctx),
+		"employee_id":    a.EmployeeID,
+		"title":          a.Title,
+		"department":     a.Department,
+		"grade":          a.Grade,
+		"effective_from": a.EffectiveFrom,
+		"message_id":     a.MessageID,
+	}
+
+	if _, err := tx.Exec(ctx, query, args); err != nil {
+		return fmt.Errorf("tx.Exec: %w", err)
+	}
+
+	return nil
+}
+
+// SupersedeAssignmentsTx отменяет назначения сотрудника с effective_from позже
+// заданного: их вытесняет пришедшее позже событие (политика arrival).
+func (r *Repository) SupersedeAssignmentsTx(ctx context.Context, tx pgx.Tx, employeeID, effectiveFrom string) error {
+	query := `
+update position_assignment
+set superseded = true
+where session_id = $1
+  and employee_id = $2
+  and effective_from > $3::date
+  and not superseded;
+`
+	if _, err := tx.Exec(ctx, query, dto.SessionFrom(ctx), employeeID, effectiveFrom); err != nil {
+		return fmt.Errorf("tx.Exec: %w", err)
+	}
+
+	return nil
+}
+
+// ListPositions возвращает историю должностей сотрудника по effective_from
+func (r *Repository) ListPositions(ctx context.Context, employeeID string) ([]dto.PositionAssignment, error) {
+	query := `
+select id,
+       employee_id,
+       title,
+       department,
+       grade,
+       to_char(effective_from,'YYYY-MM-DD'),
+       message_id,
+       case
+           when superseded then 'superseded'
+           when effective_from > current_date then 'future'
+           when effective_from = max(effective_from) filter (where effective_from <= current_date and not superseded) over () then 'current'
+           else 'past'
+       end,
+       to_char(created_at, 'YYYY-MM-DD"T"HH24:MI:SSOF')
+from position_assignment
+where session_id = $1 and employee_id = $2
+order by effective_from, id
+`
+	rows, err := r.pool.Query(ctx, query, dto.SessionFrom(ctx), employeeID)
+	if err != nil {
+		return nil, fmt.Errorf("pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]dto.PositionAssignment, 0)
+	for rows.Next() {
+		var a dto.PositionAssignment
+
+		if err := rows.Scan(
+			&a.ID,
+			&a.EmployeeID,
+			&a.Title,
+			&a.Department,
+			&a.Grade,
+			&a.EffectiveFrom,
+			&a.MessageID,
+			&a.Status,
+			&a.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		out = append(out, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,105 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type PgxPoolIface interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type Repository struct {
+	pool PgxPoolIface
+}
+
+func NewRepository(pool PgxPoolIface) *Repository {
+	return &Repository{pool: pool}
+}
+
+// Create регистрирует сессию; возвращает её со временем создания
+func (r *Repository) Create(ctx context.Context, s dto.Session) (dto.Session, error) {
+	query := `
+insert into trainee_session (id, name, created_at)
+values ($1, $2, now())
+returning to_char(created_at, 'YYYY-MM-DD"T"HH24:MI:SSOF');
+`
+	if err := r.pool.QueryRow(ctx, query, s.ID, s.Name).Scan(&s.CreatedAt); err != nil {
+		var pgerr *pgconn.PgError
+		if errors.As(err, &pgerr) && pgerr.Code == "23505" {
+			return dto.Session{}, dto.ErrAlreadyExists
+		}
+
+		return dto.Session{}, fmt.Errorf("row.Scan: %w", err)
+	}
+
+	return s, nil
+}
+
+func (r *Repository) Get(ctx context.Context, id string) (*dto.Session, error) {
+	query := `
+select id, name, to_char(created_at, 'YYYY-MM-DD"T"HH24:MI:SSOF')
+from trainee_session
+where id = $1;
+`
+	var s dto.Session
+	if err := r.pool.QueryRow(ctx, query, id).Scan(&s.ID, &s.Name, &s.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, dto.ErrNotFound
+		}
+
+		return nil, fmt.Errorf("row.Scan: %w", err)
+	}
+
+	return &s, nil
+}
+
+func (r *Repository) List(ctx context.Context) ([]dto.Session, error) {
+	query := `
+select id, name, to_char(created_at, 'YYYY-MM-DD"T"HH24:MI:SSOF')
+from trainee_session
+order by created_at, id
+`
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("pool.Query: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]dto.Session, 0)
+	for rows.Next() {
+		var s dto.Session
+
+		if err := rows.Scan(&s.ID, &s.Name, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return out, nil
+}
+
+// Delete удаляет сессию из списка; её данные удаляет events.ResetSession
+func (r *Repository) Delete(ctx context.Context, id string) error {
+	tag, err := r.pool.Exec(ctx, `delete from trainee_session where id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("pool.Exec: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return dto.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+)
+
+// check выполняет проверку один раз
+func (r *Runner) check(ctx context.Context, a Assert) error {
+	switch {
+	case a.Profile != nil:
+		return r.checkProfile(ctx, *a.Profile)
+	case a.DLQReason != nil:
+		return r.checkDLQReason(ctx, *a.DLQReason)
+	case a.EventCount != nil:
+		return r.checkEventCount(ctx, *a.EventCount)
+	default:
+		return r.checkDecision(ctx, *a.Decision)
+	}
+}
+
+func (r *Runner) checkProfile(ctx context.Context, a AssertProfile) error {
+	profile, err := r.profiles.GetProfile(ctx, a.EmployeeID)
+	if errors.Is(err, dto.ErrNotFound) {
+		if a.Absent {
+			return nil
+		}
+
+		return fmt.Errorf("profile %s not found", a.EmployeeID)
+	}
+	if err != nil {
+		return fmt.Errorf("profiles.GetProfile: %w", err)
+	}
+
+	if a.Absent {
+		return fmt.Errorf("profile %s exists, want absent", a.EmployeeID)
+	}
+
+	fields := []struct {
+		name      string
+		got, want *string
+	}{
+		{"first_name", &profile.FirstName, a.FirstName},
+		{"last_name", &profile.LastName, a.LastName},
+		{"birth_date", &profile.BirthDate, a.BirthDate},
+		{"email", &profile.Email, a.Email},
+		{"phone", &profile.Phone, a.Phone},
+		{"title", profile.Title, a.Title},
+		{"department", profile.Department, a.Department},
+		{"grade", profile.Grade, a.Grade},
+		{"effective_from", profile.EffectiveFrom, a.EffectiveFrom},
+	}
+
+	var diffs []string
+	for _, f := range fields {
+		if f.want == nil {
+			continue
+		}
+
+		var got string
+		if f.got != nil {
+			got = *f.got
+		}
+
+		if got != *f.want {
+			diffs = append(diffs, fmt.Sprintf("%s='%s', want '%s'", f.name, got, *f.want))
+		}
+	}
+
+	if len(diffs) > 0 {
+		return fmt.Errorf("profile %s: %s", a.EmployeeID, strings.Join(diffs, ", "))
+	}
+
+	return nil
+}
+
+func (r *Runner) checkDLQReason(ctx context.Context, a AssertDLQReason) error {
+	messageID, err := parseMessageID(a.MessageID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.events.ListDLQByMessageID(ctx, messageID)
+	if err != nil {
+		return fmt.Errorf("events.ListDLQByMessageID: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return fmt.Errorf("message %s not in DLQ", messageID)
+	}
+
+	var reasons []string
+	for _, row := range rows {
+		if strings.Contains(row.Error, a.Contains) {
+			return nil
+		}
+
+		reasons = append(reasons, row.Error)
+	}
+
+	return fmt.Errorf("message %s in DLQ with reason %q, want containing %q", messageID, reasons, a.Contains)
+}
+
+func (r *Runner) checkEventCount(ctx context.Context, a AssertEventCount) error {
+	topic := r.topic(a.Topic)
+
+	events, err := r.events.ListEventsForOrdering(ctx, topic, a.EmployeeID)
+	if err != nil {
+		return fmt.Errorf("events.ListEventsForOrdering: %w", err)
+	}
+
+	if len(events) != a.Count {
+		return fmt.Errorf("events of %s in '%s': %d, want %d", a.EmployeeID, topic, len(events), a.Count)
+	}
+
+	if !a.Ordered {
+		return nil
+	}
+
+	// события отсортированы по топику и порядку применения
+	partitions := make(map[string][]int)
+	last := make(map[string]dto.KafkaEvent)
+	for _, ev := range events {
+		if !slices.Contains(partitions[ev.Topic], ev.Partition) {
+			partitions[ev.Topic] = append(partitions[ev.Topic], ev.Partition)
+		}
+
+		if prev, ok := last[ev.Topic]; ok && prev.Partition == ev.Partition && ev.Offset < prev.Offset {
+			return fmt.Errorf("events of %s in '%s': offset %d applied after %d", a.EmployeeID, ev.Topic, ev.Offset, prev.Offset)
+		}
+		last[ev.Topic] = ev
+	}
+
+	for t, ps := range partitions {
+		if len(ps) > 1 {
+			slices.Sort(ps)
+			return fmt.Errorf("events of %s in '%s' split across partitions %v", a.EmployeeID, t, ps)
+		}
+	}
+
+	return nil
+}
+
+func (r *Runner) checkDecision(ctx context.Context, a AssertDecision) error {
+	messageID, err := parseMessageID(a.MessageID)
+	if err != nil {
+		return err
+	}
+
+	decisions, err := r.events.ListDecisionsByMessageID(ctx, messageID)
+	if err != nil {
+		return fmt.Errorf("events.ListDecisionsByMessageID: %w", err)
+	}
+
+	var got []string
+	for _, d := range decisions {
+		if d.Decision == a.Decision {
+			return nil
+		}
+
+		got = append(got, d.Decision)
+	}
+
+	return fmt.Errorf("message %s decisions %v, want '%s'", messageID, got, a.Decision)
+}
@@ -0,0 +1,46 @@
+package scenario
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+)
+
+// builtinFS — сценарии QA-чек-листа из README; порядок — по имени файла
+//
+//go:embed builtin/*.yaml
+var builtinFS embed.FS
+
+type builtin struct {
+	scenario Scenario
+	source   []byte
+}
+
+var builtins = mustLoadBuiltins()
+
+func mustLoadBuiltins() []builtin {
+	entries, err := builtinFS.ReadDir("builtin")
+	if err != nil {
+		panic(fmt.Sprintf("scenario: read builtin: %v", err))
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	out := make([]builtin, 0, len(entries))
+	for _, e := range entries {
+		source, err := builtinFS.ReadFile(path.Join("builtin", e.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("scenario: read %s: %v", e.Name(), err))
+		}
+
+		sc, err := Parse(source)
+		if err != nil {
+			panic(fmt.Sprintf("scenario: parse %s: %v", e.Name(), err))
+		}
+
+		out = append(out, builtin{scenario: sc, source: source})
+	}
+
+	return out
+}
@@ -0,0 +1,407 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// maxRunDuration — ограничение прогона одного сценария
+	maxRunDuration = 5 * time.Minute
+	// pollInterval — период повтора проверки в wait_until
+	pollInterval = 200 * time.Millisecond
+)
+
+type Producer interface {
+	ProducePersonal(ctx context.Context, messageID uuid.UUID, in dto.EmployeeProfile, chaos *dto.ProducerChaos) (dto.ProduceReceipt, error)
+	ProducePosition(ctx context.Context, messageID uuid.UUID, in dto.EmployeeProfile, chaos *dto.ProducerChaos) (dto.ProduceReceipt, error)
+	ProduceHistory(ctx context.Context, messageID uuid.UUID, in dto.EmploymentHistory, chaos *dto.ProducerChaos) (dto.ProduceReceipt, error)
+	ProduceRaw(ctx context.Context, raw dto.RawMessage) (dto.ProduceReceipt, error)
+}
+
+type Consumers interface {
+	ListConsumers() []dto.ConsumerState
+	PauseConsumer(name string) (dto.ConsumerState, error)
+	ResumeConsumer(name string) (dto.ConsumerState, error)
+	StopConsumer(ctx context.Context, name string) (dto.ConsumerState, error)
+	StartConsumer(name string) (dto.ConsumerState, error)
+}
+
+// Sessions — сессии стажёров: пока они есть, сценарий не управляет общими консьюмерами
+type Sessions interface {
+	List(ctx context.Context) ([]dto.Session, error)
+}
+
+type EventsRepository interface {
+	InsertReceipt(ctx context.Context, receipt dto.ProduceReceipt) error
+	ListEventsForOrdering(ctx context.Context, topic, employeeID string) ([]dto.KafkaEvent, error)
+	ListDLQByMessageID(ctx context.Context, messageID uuid.UUID) ([]dto.KafkaDLQ, error)
+	ListDecisionsByMessageID(ctx context.Context, messageID uuid.UUID) ([]dto.ConsumerDecision, error)
+}
+
+type ProfileRepository interface {
+	GetProfile(ctx context.Context, employeeID string) (*dto.EmployeeProfile, error)
+}
+
+// Topics — топики стенда; в сценарии на них ссылаются как personal, positions, history
+type Topics struct {
+	Personal  string
+	Positions string
+	History   string
+}
+
+// Runner выполняет сценарии по одному: шаги управляют общими консьюмерами стенда.
+// После прогона консьюмеры возвращаются в состояние, в котором были до него.
+// Пока есть сессии стажёров, сценарии с такими шагами отклоняются: остановка
+// консьюмера остановила бы обработку у всех.
+type Runner struct {
+	producer  Producer
+	consumers Consumers
+	sessions  Sessions
+	events    EventsRepository
+	profiles  ProfileRepository
+	topics    Topics
+	log       zerolog.Logger
+
+	mu sync.Mutex
+}
+
+func NewRunner(
+	producer Producer,
+	consumers Consumers,
+	sessions Sessions,
+	events EventsRepository,
+	profiles ProfileRepository,
+	topics Topics,
+	log zerolog.Logger,
+) *Runner {
+	return &Runner{
+		producer:  producer,
+		consumers: consumers,
+		sessions:  sessions,
+		events:    events,
+		profiles:  profiles,
+		topics:    topics,
+		log:       log.With().Str("component", "scenario").Logger(),
+	}
+}
+
+// List — встроенные сценарии
+func (r *Runner) List() []dto.ScenarioInfo {
+	out := make([]dto.ScenarioInfo, 0, len(builtins))
+	for _, b := range builtins {
+		out = append(out, dto.ScenarioInfo{
+			Name:        b.scenario.Name,
+			Description: b.scenario.Description,
+			Steps:       len(b.scenario.Steps),
+			Source:      string(b.source),
+		})
+	}
+
+	return out
+}
+
+// RunBuiltin выполняет встроенный сценарий по имени
+func (r *Runner) RunBuiltin(ctx context.Context, name string) (dto.ScenarioReport, error) {
+	for _, b := range builtins {
+		if b.scenario.Name == name {
+			return r.RunYAML(ctx, b.source)
+		}
+	}
+
+	return dto.ScenarioReport{}, fmt.Errorf("scenario '%s': %w", name, dto.ErrNotFound)
+}
+
+// RunYAML выполняет сценарий из YAML. Ошибка — только если сценарий не разобран,
+// уже идёт другой прогон или сценарий управляет консьюмерами при живых сессиях
+// стажёров; упавшие шаги отражаются в отчёте.
+func (r *Runner) RunYAML(ctx context.Context, source []byte) (dto.ScenarioReport, error) {
+	if !r.mu.TryLock() {
+		return dto.ScenarioReport{}, fmt.Errorf("another scenario is running: %w", dto.ErrInvalidState)
+	}
+	defer r.mu.Unlock()
+
+	runID := uuid.NewString()[:8]
+
+	sc, err := Parse(expand(source, runID))
+	if err != nil {
+		return dto.ScenarioReport{}, err
+	}
+
+	if sc.controlsConsumers() {
+		if err := r.checkNoSessions(ctx, sc.Name); err != nil {
+			return dto.ScenarioReport{}, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, maxRunDuration)
+	defer cancel()
+
+	prev := r.consumers.ListConsumers()
+	defer r.restoreConsumers(context.WithoutCancel(ctx), prev)
+
+	started := time.Now()
+	report := dto.ScenarioReport{
+		Scenario:    sc.Name,
+		Description: sc.Description,
+		RunID:       runID,
+		Passed:      true,
+		StartedAt:   started.UTC().Format(time.RFC3339),
+		Steps:       make([]dto.ScenarioStepResult, 0, len(sc.Steps)),
+	}
+
+	for i, step := range sc.Steps {
+		result := dto.ScenarioStepResult{Index: i + 1, Name: step.Name, Action: step.action()}
+
+		if !report.Passed {
+			result.Status = dto.ScenarioStepSkipped
+			report.Steps = append(report.Steps, result)
+			continue
+		}
+
+		stepStarted := time.Now()
+		err := r.exec(ctx, step)
+		result.Duration = time.Since(stepStarted).Round(time.Millisecond).String()
+
+		result.Status = dto.ScenarioStepPassed
+		if err != nil {
+			result.Status = dto.ScenarioStepFailed
+			result.Error = err.Error()
+			report.Passed = false
+		}
+
+		report.Steps = append(report.Steps, result)
+	}
+
+	report.Duration = time.Since(started).Round(time.Millisecond).String()
+
+	r.log.Info().
+		Str("scenario", report.Scenario).
+		Str("run_id", runID).
+		Bool("passed", report.Passed).
+		Str("duration", report.Duration).
+		Msg("scenario finished")
+
+	return report, nil
+}
+
+// checkNoSessions отклоняет управление консьюмерами, пока на стенде есть сессии стажёров
+func (r *Runner) checkNoSessions(ctx context.Context, scenario string) error {
+	sessions, err := r.sessions.List(ctx)
+	if err != nil {
+		return fmt.Errorf("sessions.List: %w", err)
+	}
+
+	if len(sessions) > 0 {
+		return fmt.Errorf("scenario '%s' pauses or stops consumers shared by all sessions, not available while %d trainee sessions exist: %w",
+			scenario, len(sessions), dto.ErrInvalidState)
+	}
+
+	return nil
+}
+
+func (r *Runner) exec(ctx context.Context, step Step) error {
+	switch {
+	case step.Produce != nil:
+		return r.produce(ctx, *step.Produce)
+	case step.Sleep != "":
+		d, _ := time.ParseDuration(step.Sleep)
+		return sleep(ctx, d)
+	case step.PauseConsumer != "":
+		_, err := r.consumers.PauseConsumer(step.PauseConsumer)
+		return err
+	case step.ResumeConsumer != "":
+		_, err := r.consumers.ResumeConsumer(step.ResumeConsumer)
+		return err
+	case step.StopConsumer != "":
+		_, err := r.consumers.StopConsumer(ctx, step.StopConsumer)
+		return err
+	case step.StartConsumer != "":
+		_, err := r.consumers.StartConsumer(step.StartConsumer)
+		return err
+	case step.WaitUntil != nil:
+		return r.waitUntil(ctx, *step.WaitUntil)
+	default:
+		return r.check(ctx, step.Assert)
+	}
+}
+
+// waitUntil повторяет проверку каждые pollInterval; по таймауту возвращает последнюю ошибку
+func (r *Runner) waitUntil(ctx context.Context, w WaitUntil) error {
+	timeout := defaultWaitTimeout
+	if w.Timeout != "" {
+		timeout, _ = time.ParseDuration(w.Timeout)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := r.check(ctx, w.Assert)
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout %s: %w", timeout, err)
+		}
+
+		if serr := sleep(ctx, pollInterval); serr != nil {
+			return fmt.Errorf("%w: %w", serr, err)
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Runner) produce(ctx context.Context, p Produce) error {
+	var (
+		receipt dto.ProduceReceipt
+		err     error
+	)
+
+	switch {
+	case p.Personal != nil:
+		e := p.Personal
+		messageID, perr := parseMessageID(e.MessageID)
+		if perr != nil {
+			return perr
+		}
+		receipt, err = r.producer.ProducePersonal(ctx, messageID, dto.EmployeeProfile{
+			EmployeeID: e.EmployeeID,
+			FirstName:  e.FirstName,
+			LastName:   e.LastName,
+			BirthDate:  e.BirthDate,
+			Email:      e.Email,
+			Phone:      e.Phone,
+		}, nil)
+	case p.Position != nil:
+		e := p.Position
+		messageID, perr := parseMessageID(e.MessageID)
+		if perr != nil {
+			return perr
+		}
+		receipt, err = r.producer.ProducePosition(ctx, messageID, dto.EmployeeProfile{
+			EmployeeID:    e.EmployeeID,
+			Title:         e.Title,
+			Department:    e.Department,
+			Grade:         e.Grade,
+			EffectiveFrom: e.EffectiveFrom,
+		}, nil)
+	case p.History != nil:
+		e := p.History
+		messageID, perr := parseMessageID(e.MessageID)
+		if perr != nil {
+			return perr
+		}
+		stack := e.Stack
+		if stack == nil {
+			stack = []string{}
+		}
+		receipt, err = r.producer.ProduceHistory(ctx, messageID, dto.EmploymentHistory{
+			EmployeeID: e.EmployeeID,
+			Company:    e.Company,
+			Position:   e.Position,
+			PeriodFrom: e.PeriodFrom,
+			PeriodTo:   e.PeriodTo,
+			Stack:      stack,
+		}, nil)
+	default:
+		receipt, err = r.producer.ProduceRaw(ctx, dto.RawMessage{
+			Topic:   r.topic(p.Raw.Topic),
+			Key:     p.Raw.Key,
+			Headers: p.Raw.Headers,
+			Body:    []byte(p.Raw.Body),
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	// квитанции сохраняются, как при отправке через API: прогон виден в GET /messages/{message_id}
+	for _, rc := range append([]dto.ProduceReceipt{receipt}, receipt.Copies...) {
+		if err := r.events.InsertReceipt(ctx, rc); err != nil {
+			r.log.Warn().Err(err).Str("topic", rc.Topic).Int64("offset", rc.Offset).Msg("failed to save produce receipt")
+		}
+	}
+
+	return nil
+}
+
+func parseMessageID(s string) (uuid.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid value in field 'message_id'=%s", s)
+	}
+
+	return id, nil
+}
+
+// topic — имя топика по ссылке personal/positions/history; иное значение — имя как есть
+func (r *Runner) topic(name string) string {
+	switch name {
+	case "personal":
+		return r.topics.Personal
+	case "positions":
+		return r.topics.Positions
+	case "history":
+		return r.topics.History
+	default:
+		return name
+	}
+}
+
+// restoreConsumers возвращает консьюмеры, изменённые сценарием, в прежнее состояние
+func (r *Runner) restoreConsumers(ctx context.Context, prev []dto.ConsumerState) {
+	current := make(map[string]string)
+	for _, c := range r.consumers.ListConsumers() {
+		current[c.Name] = c.State
+	}
+
+	for _, p := range prev {
+		state := current[p.Name]
+		if state == p.State {
+			continue
+		}
+
+		var err error
+		switch p.State {
+		case dto.ConsumerStateRunning:
+			if state == dto.ConsumerStateStopped {
+				_, err = r.consumers.StartConsumer(p.Name)
+			} else {
+				_, err = r.consumers.ResumeConsumer(p.Name)
+			}
+		case dto.ConsumerStatePaused:
+			if state == dto.ConsumerStateStopped {
+				_, err = r.consumers.StartConsumer(p.Name)
+			}
+			if err == nil {
+				_, err = r.consumers.PauseConsumer(p.Name)
+			}
+		case dto.ConsumerStateStopped:
+			_, err = r.consumers.StopConsumer(ctx, p.Name)
+		default:
+			err = errors.New("unknown state " + p.State)
+		}
+
+		if err != nil {
+			r.log.Error().Err(err).Str("consumer", p.Name).Str("state", p.State).Msg("failed to restore consumer state")
+		}
+	}
+}
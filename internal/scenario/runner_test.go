@@ -0,0 +1,89 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Artexxx/HR-Kafka-QA/internal/dto"
+	"github.com/rs/zerolog"
+)
+
+// TestRunRefusesConsumerStepsWithSessions — пока есть сессии стажёров, сценарий
+// с управлением консьюмерами не запускается и консьюмеров не трогает
+func TestRunRefusesConsumerStepsWithSessions(t *testing.T) {
+	consumers := &fakeConsumers{}
+	r := NewRunner(nil, consumers, fakeSessions{{ID: "s-1", Name: "anna"}}, nil, nil, Topics{}, zerolog.Nop())
+
+	_, err := r.RunBuiltin(context.Background(), "lag")
+	if !errors.Is(err, dto.ErrInvalidState) {
+		t.Fatalf("RunBuiltin lag: err = %v, want %v", err, dto.ErrInvalidState)
+	}
+
+	if len(consumers.calls) != 0 {
+		t.Errorf("consumer calls = %v, want none", consumers.calls)
+	}
+}
+
+// TestRunConsumerStepsWithoutSessions — без сессий шаги выполняются,
+// а после прогона консьюмеры возвращаются в прежнее состояние
+func TestRunConsumerStepsWithoutSessions(t *testing.T) {
+	consumers := &fakeConsumers{}
+	r := NewRunner(nil, consumers, fakeSessions{}, nil, nil, Topics{}, zerolog.Nop())
+
+	source := []byte("name: pause\nsteps:\n  - name: pause\n    pause_consumer: personal\n")
+	report, err := r.RunYAML(context.Background(), source)
+	if err != nil {
+		t.Fatalf("RunYAML: %v", err)
+	}
+	if !report.Passed {
+		t.Fatalf("report = %+v, want passed", report)
+	}
+
+	if want := []string{"pause personal", "resume personal"}; len(consumers.calls) != 2 || consumers.calls[0] != want[0] || consumers.calls[1] != want[1] {
+		t.Errorf("consumer calls = %v, want %v", consumers.calls, want)
+	}
+}
+
+type fakeSessions []dto.Session
+
+func (s fakeSessions) List(context.Context) ([]dto.Session, error) {
+	return s, nil
+}
+
+// fakeConsumers — один консьюмер personal; вызовы записываются
+type fakeConsumers struct {
+	state string
+	calls []string
+}
+
+func (c *fakeConsumers) set(call, state string) (dto.ConsumerState, error) {
+	c.calls = append(c.calls, call+" personal")
+	c.state = state
+
+	return dto.ConsumerState{Name: "personal", State: state}, nil
+}
+
+func (c *fakeConsumers) ListConsumers() []dto.ConsumerState {
+	if c.state == "" {
+		c.state = dto.ConsumerStateRunning
+	}
+
+	return []dto.ConsumerState{{Name: "personal", State: c.state}}
+}
+
+func (c *fakeConsumers) PauseConsumer(string) (dto.ConsumerState, error) {
+	return c.set("pause", dto.ConsumerStatePaused)
+}
+
+func (c *fakeConsumers) ResumeConsumer(string) (dto.ConsumerState, error) {
+	return c.set("resume", dto.ConsumerStateRunning)
+}
+
+func (c *fakeConsumers) StopConsumer(context.Context, string) (dto.ConsumerState, error) {
+	return c.set("stop", dto.ConsumerStateStopped)
+}
+
+func (c *fakeConsumers) StartConsumer(string) (dto.ConsumerState, error) {
+	return c.set("start", dto.ConsumerStateRunning)
+}
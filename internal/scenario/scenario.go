@@ -0,0 +1,270 @@
+// Package scenario — декларативные QA-сценарии стенда: YAML с шагами produce,
+// управления консьюмерами, ожидания и проверок выполняется против продюсера,
+// консьюмеров и репозиториев запущенного сервиса и даёт отчёт pass/fail с таймингами.
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultWaitTimeout — ожидание wait_until без явного timeout
+const defaultWaitTimeout = 15 * time.Second
+
+// Scenario — сценарий: шаги выполняются по порядку до первой ошибки
+type Scenario struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Steps       []Step `yaml:"steps"`
+}
+
+// Step — шаг сценария: ровно одно действие
+type Step struct {
+	Name           string     `yaml:"name"`
+	Produce        *Produce   `yaml:"produce"`
+	Sleep          string     `yaml:"sleep"`
+	PauseConsumer  string     `yaml:"pause_consumer"`
+	ResumeConsumer string     `yaml:"resume_consumer"`
+	StopConsumer   string     `yaml:"stop_consumer"`
+	StartConsumer  string     `yaml:"start_consumer"`
+	WaitUntil      *WaitUntil `yaml:"wait_until"`
+	Assert         `yaml:",inline"`
+}
+
+// Assert — проверки состояния стенда; в шаге и в wait_until задаётся ровно одна
+type Assert struct {
+	Profile    *AssertProfile    `yaml:"assert_profile"`
+	DLQReason  *AssertDLQReason  `yaml:"assert_dlq_reason"`
+	EventCount *AssertEventCount `yaml:"assert_event_count"`
+	Decision   *AssertDecision   `yaml:"assert_decision"`
+}
+
+// WaitUntil повторяет проверку, пока она не пройдёт или не истечёт timeout
+type WaitUntil struct {
+	Timeout string `yaml:"timeout"` // по умолчанию 15s
+	Assert  `yaml:",inline"`
+}
+
+// Produce — отправка одного события; задаётся ровно одно поле
+type Produce struct {
+	Personal *Personal `yaml:"personal"`
+	Position *Position `yaml:"position"`
+	History  *History  `yaml:"history"`
+	Raw      *Raw      `yaml:"raw"`
+}
+
+type Personal struct {
+	MessageID  string `yaml:"message_id"`
+	EmployeeID string `yaml:"employee_id"`
+	FirstName  string `yaml:"first_name"`
+	LastName   string `yaml:"last_name"`
+	BirthDate  string `yaml:"birth_date"`
+	Email      string `yaml:"email"`
+	Phone      string `yaml:"phone"`
+}
+
+type Position struct {
+	MessageID     string  `yaml:"message_id"`
+	EmployeeID    string  `yaml:"employee_id"`
+	Title         *string `yaml:"title"`
+	Department    *string `yaml:"department"`
+	Grade         *string `yaml:"grade"`
+	EffectiveFrom *string `yaml:"effective_from"`
+}
+
+type History struct {
+	MessageID  string   `yaml:"message_id"`
+	EmployeeID string   `yaml:"employee_id"`
+	Company    string   `yaml:"company"`
+	Position   string   `yaml:"position"`
+	PeriodFrom string   `yaml:"period_from"`
+	PeriodTo   string   `yaml:"period_to"`
+	Stack      []string `yaml:"stack"`
+}
+
+// Raw — сообщение без валидации; topic — personal/positions/history или имя топика
+type Raw struct {
+	Topic   string            `yaml:"topic"`
+	Key     *string           `yaml:"key"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+}
+
+// AssertProfile сверяет заданные поля профиля; absent — профиля быть не должно
+type AssertProfile struct {
+	EmployeeID    string  `yaml:"employee_id"`
+	Absent        bool    `yaml:"absent"`
+	FirstName     *string `yaml:"first_name"`
+	LastName      *string `yaml:"last_name"`
+	BirthDate     *string `yaml:"birth_date"`
+	Email         *string `yaml:"email"`
+	Phone         *string `yaml:"phone"`
+	Title         *string `yaml:"title"`
+	Department    *string `yaml:"department"`
+	Grade         *string `yaml:"grade"`
+	EffectiveFrom *string `yaml:"effective_from"`
+}
+
+// AssertDLQReason — сообщение в kafka_dlq с причиной, содержащей contains
+type AssertDLQReason struct {
+	MessageID string `yaml:"message_id"`
+	Contains  string `yaml:"contains"`
+}
+
+// AssertEventCount — число событий сотрудника в журнале kafka_events; ordered —
+// события топика лежат в одной партиции и применены по возрастанию offset
+type AssertEventCount struct {
+	Topic      string `yaml:"topic"`
+	EmployeeID string `yaml:"employee_id"`
+	Count      int    `yaml:"count"`
+	Ordered    bool   `yaml:"ordered"`
+}
+
+// AssertDecision — решение консьюмера по сообщению (applied, duplicate, dlq, retry, stale)
+type AssertDecision struct {
+	MessageID string `yaml:"message_id"`
+	Decision  string `yaml:"decision"`
+}
+
+// Parse разбирает и проверяет сценарий
+func Parse(data []byte) (Scenario, error) {
+	var sc Scenario
+	if err := yaml.Unmarshal(data, &sc); err != nil {
+		return Scenario{}, fmt.Errorf("yaml.Unmarshal: %w", err)
+	}
+
+	if sc.Name == "" {
+		return Scenario{}, errors.New("required field 'name'")
+	}
+
+	if len(sc.Steps) == 0 {
+		return Scenario{}, errors.New("required field 'steps'")
+	}
+
+	for i, step := range sc.Steps {
+		if err := step.check(); err != nil {
+			return Scenario{}, fmt.Errorf("steps[%d]: %w", i, err)
+		}
+	}
+
+	return sc, nil
+}
+
+// controlsConsumers — есть шаги, меняющие состояние консьюмеров: они общие для всего стенда
+func (sc Scenario) controlsConsumers() bool {
+	for _, s := range sc.Steps {
+		if s.PauseConsumer != "" || s.ResumeConsumer != "" || s.StopConsumer != "" || s.StartConsumer != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// action — имя действия шага
+func (s Step) action() string {
+	actions := s.actions()
+	if len(actions) != 1 {
+		return ""
+	}
+
+	return actions[0]
+}
+
+func (s Step) actions() []string {
+	var out []string
+	add := func(set bool, name string) {
+		if set {
+			out = append(out, name)
+		}
+	}
+
+	add(s.Produce != nil, "produce")
+	add(s.Sleep != "", "sleep")
+	add(s.PauseConsumer != "", "pause_consumer")
+	add(s.ResumeConsumer != "", "resume_consumer")
+	add(s.StopConsumer != "", "stop_consumer")
+	add(s.StartConsumer != "", "start_consumer")
+	add(s.WaitUntil != nil, "wait_until")
+	out = append(out, s.Assert.actions()...)
+
+	return out
+}
+
+func (a Assert) actions() []string {
+	var out []string
+	add := func(set bool, name string) {
+		if set {
+			out = append(out, name)
+		}
+	}
+
+	add(a.Profile != nil, "assert_profile")
+	add(a.DLQReason != nil, "assert_dlq_reason")
+	add(a.EventCount != nil, "assert_event_count")
+	add(a.Decision != nil, "assert_decision")
+
+	return out
+}
+
+func (s Step) check() error {
+	actions := s.actions()
+	if len(actions) != 1 {
+		return fmt.Errorf("expected exactly one action, got %v", actions)
+	}
+
+	switch {
+	case s.Produce != nil:
+		n := 0
+		for _, set := range []bool{s.Produce.Personal != nil, s.Produce.Position != nil, s.Produce.History != nil, s.Produce.Raw != nil} {
+			if set {
+				n++
+			}
+		}
+		if n != 1 {
+			return errors.New("produce: expected exactly one of 'personal', 'position', 'history', 'raw'")
+		}
+	case s.Sleep != "":
+		if _, err := time.ParseDuration(s.Sleep); err != nil {
+			return fmt.Errorf("invalid value in field 'sleep'=%s", s.Sleep)
+		}
+	case s.WaitUntil != nil:
+		if n := len(s.WaitUntil.Assert.actions()); n != 1 {
+			return fmt.Errorf("wait_until: expected exactly one assert_*, got %d", n)
+		}
+		if s.WaitUntil.Timeout != "" {
+			if _, err := time.ParseDuration(s.WaitUntil.Timeout); err != nil {
+				return fmt.Errorf("invalid value in field 'wait_until.timeout'=%s", s.WaitUntil.Timeout)
+			}
+		}
+	}
+
+	return nil
+}
+
+var rePlaceholder = regexp.MustCompile(`\$\{(run|uuid\.[A-Za-z0-9_-]+)}`)
+
+// expand подставляет ${run} — идентификатор прогона и ${uuid.<имя>} — UUID,
+// одинаковый для одного имени в пределах прогона. Так сценарий можно гонять
+// повторно: сотрудники и message_id каждого прогона новые.
+func expand(data []byte, runID string) []byte {
+	ids := make(map[string]string)
+
+	return rePlaceholder.ReplaceAllFunc(data, func(m []byte) []byte {
+		name := string(rePlaceholder.FindSubmatch(m)[1])
+		if name == "run" {
+			return []byte(runID)
+		}
+
+		if _, ok := ids[name]; !ok {
+			ids[name] = uuid.NewString()
+		}
+
+		return []byte(ids[name])
+	})
+}
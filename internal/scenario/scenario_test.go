@@ -0,0 +1,93 @@
+package scenario
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"testing"
+)
+
+// TestBuiltinScenariosParse — встроенные сценарии разбираются и после подстановки
+// ${run} / ${uuid.*} не содержат незаменённых плейсхолдеров
+func TestBuiltinScenariosParse(t *testing.T) {
+	entries, err := builtinFS.ReadDir("builtin")
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+
+	want := []string{"basic-flow", "ordering", "idempotency", "errors", "lag"}
+	if len(entries) != len(want) {
+		t.Fatalf("builtin scenarios = %d files, want %d", len(entries), len(want))
+	}
+
+	for i, e := range entries {
+		t.Run(e.Name(), func(t *testing.T) {
+			source, err := builtinFS.ReadFile(path.Join("builtin", e.Name()))
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+
+			expanded := expand(source, "test")
+			if bytes.Contains(expanded, []byte("${")) {
+				t.Errorf("placeholders left after expand:\n%s", expanded)
+			}
+
+			sc, err := Parse(expanded)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			if sc.Name != want[i] {
+				t.Errorf("name = %q, want %q", sc.Name, want[i])
+			}
+			if sc.Description == "" {
+				t.Error("empty description")
+			}
+			for j, step := range sc.Steps {
+				if step.Name == "" || step.action() == "" {
+					t.Errorf("steps[%d] = %+v: want a name and one action", j, step)
+				}
+			}
+		})
+	}
+
+	if len(builtins) != len(want) {
+		t.Errorf("loaded builtins = %d, want %d", len(builtins), len(want))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{name: "no name", source: "steps:\n  - sleep: 1s\n", wantErr: "required field 'name'"},
+		{name: "no steps", source: "name: x\n", wantErr: "required field 'steps'"},
+		{name: "no action", source: "name: x\nsteps:\n  - name: empty\n", wantErr: "steps[0]: expected exactly one action"},
+		{name: "two actions", source: "name: x\nsteps:\n  - sleep: 1s\n    stop_consumer: personal\n", wantErr: "expected exactly one action"},
+		{name: "bad sleep", source: "name: x\nsteps:\n  - sleep: soon\n", wantErr: "invalid value in field 'sleep'=soon"},
+		{name: "two produce kinds", source: "name: x\nsteps:\n  - produce:\n      personal: {}\n      raw: {}\n", wantErr: "produce: expected exactly one of"},
+		{name: "wait without assert", source: "name: x\nsteps:\n  - wait_until:\n      timeout: 1s\n", wantErr: "wait_until: expected exactly one assert_*"},
+		{name: "bad wait timeout", source: "name: x\nsteps:\n  - wait_until:\n      timeout: later\n      assert_profile:\n        employee_id: e-1\n", wantErr: "invalid value in field 'wait_until.timeout'=later"},
+		{name: "invalid yaml", source: "name: [", wantErr: "yaml.Unmarshal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.source))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Parse: err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	out := string(expand([]byte("${run} ${uuid.a} ${uuid.a} ${uuid.b}"), "r1"))
+
+	parts := strings.Fields(out)
+	if len(parts) != 4 || parts[0] != "r1" || parts[1] != parts[2] || parts[1] == parts[3] || len(parts[1]) != 36 {
+		t.Fatalf("expand = %q, want run id, the same UUID twice and another UUID", out)
+	}
+}
@@ -0,0 +1,33 @@
+-- Квитанции продюсера: что и куда было отправлено через HTTP API
+CREATE TABLE IF NOT EXISTS kafka_produced (
+                                              id          BIGSERIAL PRIMARY KEY,
+                                              message_id  UUID,
+                                              topic       TEXT NOT NULL,
+                                              partition   INT NOT NULL,
+                                              "offset"      BIGINT NOT NULL,
+                                              msg_key     TEXT,
+                                              produced_at TIMESTAMPTZ DEFAULT now()
+);
+
+-- Решения консьюмера по каждому прочитанному сообщению: applied / duplicate / dlq
+CREATE TABLE IF NOT EXISTS kafka_decisions (
+                                               id          BIGSERIAL PRIMARY KEY,
+                                               message_id  UUID,
+                                               topic       TEXT NOT NULL,
+                                               partition   INT,
+                                               "offset"      BIGINT,
+                                               decision    TEXT NOT NULL,
+                                               reason      TEXT,
+                                               decided_at  TIMESTAMPTZ DEFAULT now()
+);
+
+ALTER TABLE kafka_dlq ADD COLUMN IF NOT EXISTS message_id UUID;
+ALTER TABLE kafka_dlq ADD COLUMN IF NOT EXISTS partition INT;
+ALTER TABLE kafka_dlq ADD COLUMN IF NOT EXISTS "offset" BIGINT;
+
+ALTER TABLE employment_history ADD COLUMN IF NOT EXISTS message_id UUID;
+
+CREATE INDEX IF NOT EXISTS idx_kafka_produced_message_id  ON kafka_produced (message_id);
+CREATE INDEX IF NOT EXISTS idx_kafka_decisions_message_id ON kafka_decisions (message_id);
+CREATE INDEX IF NOT EXISTS idx_kafka_dlq_message_id       ON kafka_dlq (message_id);
+CREATE INDEX IF NOT EXISTS idx_history_message_id         ON employment_history (message_id);
@@ -0,0 +1,6 @@
+-- Повторная отправка (replay) сообщений из DLQ
+ALTER TABLE kafka_dlq ADD COLUMN IF NOT EXISTS raw_payload       BYTEA;
+ALTER TABLE kafka_dlq ADD COLUMN IF NOT EXISTS replay_status     TEXT;
+ALTER TABLE kafka_dlq ADD COLUMN IF NOT EXISTS replay_attempts   INT NOT NULL DEFAULT 0;
+ALTER TABLE kafka_dlq ADD COLUMN IF NOT EXISTS last_replayed_at  TIMESTAMPTZ;
+ALTER TABLE kafka_dlq ADD COLUMN IF NOT EXISTS last_replay_error TEXT;
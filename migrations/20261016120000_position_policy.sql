@@ -0,0 +1,2 @@
+-- Устаревшие события должности (effective_from старше текущего)
+ALTER TABLE kafka_events ADD COLUMN IF NOT EXISTS stale BOOLEAN NOT NULL DEFAULT false;
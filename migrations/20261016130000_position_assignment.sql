@@ -0,0 +1,13 @@
+-- Временная история должностей: каждое событие hr.positions — назначение с effective_from
+CREATE TABLE IF NOT EXISTS position_assignment (
+                                                   id             BIGSERIAL PRIMARY KEY,
+                                                   employee_id    TEXT NOT NULL,
+                                                   title          TEXT,
+                                                   department     TEXT,
+                                                   grade          TEXT,
+                                                   effective_from DATE NOT NULL,
+                                                   message_id     UUID,
+                                                   created_at     TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_position_assignment_employee_effective ON position_assignment (employee_id, effective_from);
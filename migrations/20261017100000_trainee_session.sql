@@ -0,0 +1,30 @@
+-- Сессии стажёров: у каждой своё пространство employee_id, журнала, DLQ и сброса.
+-- Строки без сессии (session_id = '') принадлежат общей сессии — запросам без X-Session-ID.
+CREATE TABLE IF NOT EXISTS trainee_session (
+                                               id         TEXT PRIMARY KEY,
+                                               name       TEXT NOT NULL,
+                                               created_at TIMESTAMPTZ DEFAULT now()
+);
+
+ALTER TABLE employee_profile    ADD COLUMN IF NOT EXISTS session_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE employment_history  ADD COLUMN IF NOT EXISTS session_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE position_assignment ADD COLUMN IF NOT EXISTS session_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE kafka_events        ADD COLUMN IF NOT EXISTS session_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE kafka_dlq           ADD COLUMN IF NOT EXISTS session_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE kafka_produced      ADD COLUMN IF NOT EXISTS session_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE kafka_decisions     ADD COLUMN IF NOT EXISTS session_id TEXT NOT NULL DEFAULT '';
+
+-- employee_id и message_id уникальны внутри сессии
+ALTER TABLE employee_profile DROP CONSTRAINT IF EXISTS employee_profile_pkey;
+ALTER TABLE employee_profile ADD PRIMARY KEY (session_id, employee_id);
+
+ALTER TABLE kafka_events DROP CONSTRAINT IF EXISTS kafka_events_message_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS kafka_events_session_message_id_key ON kafka_events (session_id, message_id);
+
+DROP INDEX IF EXISTS idx_position_assignment_employee_effective;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_position_assignment_session_employee_effective ON position_assignment (session_id, employee_id, effective_from);
+
+CREATE INDEX IF NOT EXISTS idx_history_session_employee_id ON employment_history (session_id, employee_id);
+CREATE INDEX IF NOT EXISTS idx_kafka_dlq_session_id        ON kafka_dlq (session_id);
+CREATE INDEX IF NOT EXISTS idx_kafka_produced_session_id   ON kafka_produced (session_id);
+CREATE INDEX IF NOT EXISTS idx_kafka_decisions_session_id  ON kafka_decisions (session_id);
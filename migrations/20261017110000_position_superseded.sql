@@ -0,0 +1,3 @@
+-- Назначения, отменённые событием с более ранним effective_from, пришедшим позже (политика arrival):
+-- остаются в истории, но не определяют должность
+ALTER TABLE position_assignment ADD COLUMN IF NOT EXISTS superseded BOOLEAN NOT NULL DEFAULT false;
@@ -3,21 +3,47 @@ CREATE SCHEMA IF NOT EXISTS "public";
 -- Set comment to schema: "public"
 COMMENT ON SCHEMA "public" IS 'standard public schema';
 -- Create "employee_profile" table
-CREATE TABLE "public"."employee_profile" ("employee_id" text NOT NULL, "first_name" text NULL, "last_name" text NULL, "birth_date" date NULL, "email" text NULL, "phone" text NULL, "title" text NULL, "department" text NULL, "grade" text NULL, "effective_from" date NULL, "updated_at" timestamptz NULL DEFAULT now(), PRIMARY KEY ("employee_id"));
+CREATE TABLE "public"."employee_profile" ("employee_id" text NOT NULL, "first_name" text NULL, "last_name" text NULL, "birth_date" date NULL, "email" text NULL, "phone" text NULL, "title" text NULL, "department" text NULL, "grade" text NULL, "effective_from" date NULL, "updated_at" timestamptz NULL DEFAULT now(), "session_id" text NOT NULL DEFAULT '', PRIMARY KEY ("session_id", "employee_id"));
 -- Create "employment_history" table
-CREATE TABLE "public"."employment_history" ("id" bigserial NOT NULL, "employee_id" text NOT NULL, "company" text NOT NULL, "position" text NULL, "period_from" date NOT NULL, "period_to" date NOT NULL, "stack" text[] NOT NULL DEFAULT '{}', "created_at" timestamptz NULL DEFAULT now(), PRIMARY KEY ("id"));
+CREATE TABLE "public"."employment_history" ("id" bigserial NOT NULL, "employee_id" text NOT NULL, "company" text NOT NULL, "position" text NULL, "period_from" date NOT NULL, "period_to" date NOT NULL, "stack" text[] NOT NULL DEFAULT '{}', "created_at" timestamptz NULL DEFAULT now(), "message_id" uuid NULL, "session_id" text NOT NULL DEFAULT '', PRIMARY KEY ("id"));
 -- Create index "idx_history_employee_id" to table: "employment_history"
 CREATE INDEX "idx_history_employee_id" ON "public"."employment_history" ("employee_id");
+-- Create index "idx_history_message_id" to table: "employment_history"
+CREATE INDEX "idx_history_message_id" ON "public"."employment_history" ("message_id");
+-- Create index "idx_history_session_employee_id" to table: "employment_history"
+CREATE INDEX "idx_history_session_employee_id" ON "public"."employment_history" ("session_id", "employee_id");
+-- Create "kafka_decisions" table
+CREATE TABLE "public"."kafka_decisions" ("id" bigserial NOT NULL, "message_id" uuid NULL, "topic" text NOT NULL, "partition" integer NULL, "offset" bigint NULL, "decision" text NOT NULL, "reason" text NULL, "decided_at" timestamptz NULL DEFAULT now(), "session_id" text NOT NULL DEFAULT '', PRIMARY KEY ("id"));
+-- Create index "idx_kafka_decisions_message_id" to table: "kafka_decisions"
+CREATE INDEX "idx_kafka_decisions_message_id" ON "public"."kafka_decisions" ("message_id");
+-- Create index "idx_kafka_decisions_session_id" to table: "kafka_decisions"
+CREATE INDEX "idx_kafka_decisions_session_id" ON "public"."kafka_decisions" ("session_id");
 -- Create "kafka_dlq" table
-CREATE TABLE "public"."kafka_dlq" ("id" bigserial NOT NULL, "topic" text NOT NULL, "msg_key" text NULL, "payload" jsonb NOT NULL, "error" text NOT NULL, "received_at" timestamptz NULL DEFAULT now(), PRIMARY KEY ("id"));
+CREATE TABLE "public"."kafka_dlq" ("id" bigserial NOT NULL, "topic" text NOT NULL, "msg_key" text NULL, "payload" jsonb NOT NULL, "error" text NOT NULL, "received_at" timestamptz NULL DEFAULT now(), "message_id" uuid NULL, "partition" integer NULL, "offset" bigint NULL, "raw_payload" bytea NULL, "replay_status" text NULL, "replay_attempts" integer NOT NULL DEFAULT 0, "last_replayed_at" timestamptz NULL, "last_replay_error" text NULL, "session_id" text NOT NULL DEFAULT '', PRIMARY KEY ("id"));
+-- Create index "idx_kafka_dlq_message_id" to table: "kafka_dlq"
+CREATE INDEX "idx_kafka_dlq_message_id" ON "public"."kafka_dlq" ("message_id");
+-- Create index "idx_kafka_dlq_session_id" to table: "kafka_dlq"
+CREATE INDEX "idx_kafka_dlq_session_id" ON "public"."kafka_dlq" ("session_id");
 -- Create index "idx_kafka_dlq_topic_received_at" to table: "kafka_dlq"
 CREATE INDEX "idx_kafka_dlq_topic_received_at" ON "public"."kafka_dlq" ("topic", "received_at" DESC);
 -- Create "kafka_events" table
-CREATE TABLE "public"."kafka_events" ("id" bigserial NOT NULL, "message_id" uuid NULL, "topic" text NOT NULL, "partition" integer NULL, "offset" bigint NULL, "payload" jsonb NOT NULL, "received_at" timestamptz NULL DEFAULT now(), PRIMARY KEY ("id"));
+CREATE TABLE "public"."kafka_events" ("id" bigserial NOT NULL, "message_id" uuid NULL, "topic" text NOT NULL, "partition" integer NULL, "offset" bigint NULL, "payload" jsonb NOT NULL, "received_at" timestamptz NULL DEFAULT now(), "stale" boolean NOT NULL DEFAULT false, "session_id" text NOT NULL DEFAULT '', PRIMARY KEY ("id"));
 -- Create index "idx_kafka_events_topic_received_at" to table: "kafka_events"
 CREATE INDEX "idx_kafka_events_topic_received_at" ON "public"."kafka_events" ("topic", "received_at" DESC);
--- Create index "kafka_events_message_id_key" to table: "kafka_events"
-CREATE UNIQUE INDEX "kafka_events_message_id_key" ON "public"."kafka_events" ("message_id");
+-- Create index "kafka_events_session_message_id_key" to table: "kafka_events"
+CREATE UNIQUE INDEX "kafka_events_session_message_id_key" ON "public"."kafka_events" ("session_id", "message_id");
+-- Create "kafka_produced" table
+CREATE TABLE "public"."kafka_produced" ("id" bigserial NOT NULL, "message_id" uuid NULL, "topic" text NOT NULL, "partition" integer NOT NULL, "offset" bigint NOT NULL, "msg_key" text NULL, "produced_at" timestamptz NULL DEFAULT now(), "session_id" text NOT NULL DEFAULT '', PRIMARY KEY ("id"));
+-- Create index "idx_kafka_produced_message_id" to table: "kafka_produced"
+CREATE INDEX "idx_kafka_produced_message_id" ON "public"."kafka_produced" ("message_id");
+-- Create index "idx_kafka_produced_session_id" to table: "kafka_produced"
+CREATE INDEX "idx_kafka_produced_session_id" ON "public"."kafka_produced" ("session_id");
+-- Create "position_assignment" table
+CREATE TABLE "public"."position_assignment" ("id" bigserial NOT NULL, "employee_id" text NOT NULL, "title" text NULL, "department" text NULL, "grade" text NULL, "effective_from" date NOT NULL, "message_id" uuid NULL, "created_at" timestamptz NULL DEFAULT now(), "session_id" text NOT NULL DEFAULT '', "superseded" boolean NOT NULL DEFAULT false, PRIMARY KEY ("id"));
+-- Create index "idx_position_assignment_session_employee_effective" to table: "position_assignment"
+CREATE UNIQUE INDEX "idx_position_assignment_session_employee_effective" ON "public"."position_assignment" ("session_id", "employee_id", "effective_from");
+-- Create "trainee_session" table
+CREATE TABLE "public"."trainee_session" ("id" text NOT NULL, "name" text NOT NULL, "created_at" timestamptz NULL DEFAULT now(), PRIMARY KEY ("id"));
 
 -- Создаём роль "только чтение"
 CREATE ROLE qa_readonly LOGIN PASSWORD 'pg-ro-secret' NOSUPERUSER NOCREATEDB NOCREATEROLE NOINHERIT;